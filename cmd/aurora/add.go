@@ -18,7 +18,16 @@ func handleAdd(opts Options) error {
 			Name:      opts.Package,
 			CloneURL:  opts.CloneURL,
 			Subdir:    opts.Subdir,
+			Ref:       opts.Ref,
 			Priority:  opts.Priority,
+
+			Env:          opts.Env,
+			MakepkgFlags: opts.MakepkgFlags,
+			MakepkgConf:  opts.MakepkgConf,
+
+			Architectures:      opts.Architectures,
+			VerifyReproducible: opts.VerifyReproducible,
+			Review:             opts.Review,
 		},
 		&proto.ResponseAddPackage{},
 	)
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+)
+
+func handleBuilds(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseListBuilds
+	err := client.Call(
+		(*rpc.PackageService).ListBuilds,
+		proto.RequestListBuilds{
+			Signature: signer.sign(),
+			Name:      opts.Package,
+			Limit:     opts.Limit,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	tab := tabwriter.NewWriter(os.Stdout, 1, 2, 3, ' ', 0)
+	fmt.Fprintf(tab, "ID\tSTATUS\tVERSION\tCOMMIT\tSTARTED\tDURATION\tREASON\tREPRODUCIBLE\n")
+
+	for _, build := range response.Builds {
+		duration := "-"
+		if !build.Finished.IsZero() {
+			duration = build.Finished.Sub(build.Started).Round(time.Second).String()
+		}
+
+		fmt.Fprintf(
+			tab,
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			build.ID,
+			build.Status,
+			build.Version,
+			orDash(shortCommit(build.Commit)),
+			build.Started.Format(time.RFC3339),
+			duration,
+			orDash(build.FailureReason),
+			orDash(build.Reproducible),
+		)
+	}
+
+	err = tab.Flush()
+	if err != nil {
+		return err
+	}
+
+	// differences are shown only for the latest checked build, otherwise
+	// output becomes unreadable
+	for _, build := range response.Builds {
+		if build.Reproducible == "" {
+			continue
+		}
+
+		if len(build.ReproducibleDiff) > 0 {
+			fmt.Printf("\nDifferences found by %s:\n", build.ID)
+
+			for _, line := range build.ReproducibleDiff {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+
+		break
+	}
+
+	return nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+
+	return commit
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+)
+
+func handleDiff(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseGetRecipeDiff
+	err := client.Call(
+		(*rpc.PackageService).GetRecipeDiff,
+		proto.RequestGetRecipeDiff{
+			Signature: signer.sign(),
+			Name:      opts.Package,
+			BuildA:    opts.BuildA,
+			BuildB:    opts.BuildB,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Comparing build %s with %s\n", response.BuildA, response.BuildB)
+
+	if response.Diff == "" {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	fmt.Print(response.Diff)
+
+	return nil
+}
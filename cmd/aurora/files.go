@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+)
+
+func handleFiles(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseSearchFiles
+	err := client.Call(
+		(*rpc.PackageService).SearchFiles,
+		proto.RequestSearchFiles{
+			Signature: signer.sign(),
+			Pattern:   opts.Pattern,
+			Regex:     opts.Regex,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(response.Matches) == 0 {
+		return errors.New("no files found")
+	}
+
+	for _, match := range response.Matches {
+		repositories := "-"
+		if len(match.Repositories) > 0 {
+			repositories = strings.Join(match.Repositories, ",")
+		}
+
+		fmt.Printf(
+			"%s/%s %s (%s, package %s)\n",
+			repositories, match.Name, match.Version, match.Arch, match.Package,
+		)
+
+		for _, file := range match.Files {
+			fmt.Printf("    /%s\n", file)
+		}
+	}
+
+	return nil
+}
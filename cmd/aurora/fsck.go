@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+)
+
+func handleFsck(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseFsck
+	err := client.Call(
+		(*rpc.AdminService).Fsck,
+		proto.RequestFsck{
+			Signature: signer.sign(),
+			Repair:    opts.Repair,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	unrepaired := 0
+	for _, issue := range response.Issues {
+		switch {
+		case issue.Repaired:
+			fmt.Printf("%s (repaired)\n", issue)
+		case issue.Error != "":
+			fmt.Printf("%s (unable to repair: %s)\n", issue, issue.Error)
+			unrepaired++
+		default:
+			fmt.Println(issue)
+			unrepaired++
+		}
+	}
+
+	if unrepaired > 0 {
+		return fmt.Errorf("%d issues found", unrepaired)
+	}
+
+	return nil
+}
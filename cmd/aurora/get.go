@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"text/tabwriter"
 	"time"
 
+	"github.com/kovetskiy/aurora/pkg/pkginfo"
 	"github.com/kovetskiy/aurora/pkg/proto"
 	"github.com/kovetskiy/aurora/pkg/rpc"
 	"github.com/kovetskiy/aurora/pkg/signature"
@@ -17,7 +19,7 @@ func handleGet(opts Options) error {
 	signer := NewSigner(opts.Key)
 
 	if opts.Package != "" {
-		return handleGetPackage(client, opts.Package, signer.sign())
+		return handleGetPackage(client, opts.Package, opts.Details, signer.sign())
 	}
 
 	return handleListPackages(client, signer.sign())
@@ -39,13 +41,19 @@ func handleListPackages(client *Client, signature *signature.Signature) error {
 	return printPackages(reply.Packages...)
 }
 
-func handleGetPackage(client *Client, name string, signature *signature.Signature) error {
+func handleGetPackage(
+	client *Client,
+	name string,
+	details bool,
+	signature *signature.Signature,
+) error {
 	var reply proto.ResponseGetPackage
 	err := client.Call(
 		(*rpc.PackageService).GetPackage,
 		proto.RequestGetPackage{
 			Signature: signature,
 			Name:      name,
+			Details:   details,
 		},
 		&reply,
 	)
@@ -57,17 +65,163 @@ func handleGetPackage(client *Client, name string, signature *signature.Signatur
 		return errors.New("package not found")
 	}
 
-	return printPackages(reply.Package)
+	err = printPackages(reply.Package)
+	if err != nil {
+		return err
+	}
+
+	err = printPackageSettings(reply.Package)
+	if err != nil {
+		return err
+	}
+
+	for _, archive := range reply.Archives {
+		fmt.Println()
+
+		err := printArchive(archive)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func printArchive(archive proto.ArchiveMetadata) error {
+	tab := tabwriter.NewWriter(os.Stdout, 1, 2, 3, ' ', 0)
+
+	fmt.Fprintf(tab, "archive\t%s\n", archive.Filename)
+	fmt.Fprintf(tab, "name\t%s\n", archive.Name)
+	fmt.Fprintf(tab, "version\t%s\n", archive.Version)
+	fmt.Fprintf(tab, "arch\t%s\n", archive.Arch)
+	fmt.Fprintf(tab, "size\t%d\n", archive.Size)
+	fmt.Fprintf(tab, "installed size\t%d\n", archive.InstalledSize)
+	fmt.Fprintf(tab, "sha256\t%s\n", archive.SHA256)
+	fmt.Fprintf(tab, "published\t%s\n", archive.Published.Format(time.RFC3339))
+
+	for _, value := range archive.Depends {
+		fmt.Fprintf(tab, "depends\t%s\n", value)
+	}
+
+	for _, value := range archive.Provides {
+		fmt.Fprintf(tab, "provides\t%s\n", value)
+	}
+
+	for _, value := range archive.Conflicts {
+		fmt.Fprintf(tab, "conflicts\t%s\n", value)
+	}
+
+	if archive.PkgbuildSHA256 != "" {
+		fmt.Fprintf(tab, "pkgbuild sha256\t%s\n", archive.PkgbuildSHA256)
+	}
+
+	for _, file := range archive.Files {
+		switch file.Type {
+		case pkginfo.FileTypeDir:
+			fmt.Fprintf(tab, "file\t/%s/\n", file.Path)
+		case pkginfo.FileTypeLink:
+			fmt.Fprintf(tab, "file\t/%s -> %s\n", file.Path, file.Link)
+		default:
+			fmt.Fprintf(tab, "file\t/%s\n", file.Path)
+		}
+	}
+
+	return tab.Flush()
+}
+
+func printPackageSettings(pkg *proto.Package) error {
+	if pkg == nil {
+		return nil
+	}
+
+	tab := tabwriter.NewWriter(os.Stdout, 1, 2, 3, ' ', 0)
+
+	repositories := []string{}
+	for name := range pkg.Repositories {
+		repositories = append(repositories, name)
+	}
+
+	sort.Strings(repositories)
+
+	for _, name := range repositories {
+		published := pkg.Repositories[name]
+		fmt.Fprintf(
+			tab, "repository %s\t%s, published %s\n",
+			name, published.Version, published.Published.Format(time.RFC3339),
+		)
+	}
+
+	if pkg.Promote != "" {
+		fmt.Fprintf(tab, "pending promotion\tto %s\n", pkg.Promote)
+	}
+
+	if pkg.Superseded != "" {
+		fmt.Fprintf(tab, "superseded by\t%s\n", pkg.Superseded)
+	}
+
+	if pkg.Hold != "" {
+		fmt.Fprintf(tab, "hold\t%s, see aurora ack\n", pkg.Hold)
+	}
+
+	if pkg.Maintainer != "" {
+		fmt.Fprintf(tab, "maintainer\t%s\n", pkg.Maintainer)
+	}
+
+	if !pkg.OutOfDate.IsZero() {
+		fmt.Fprintf(tab, "out-of-date\t%s\n", pkg.OutOfDate.Format(time.RFC3339))
+	}
+
+	if pkg.Deleted {
+		fmt.Fprintf(tab, "deleted from AUR\tyes\n")
+	}
+
+	if pkg.Ref != "" {
+		fmt.Fprintf(tab, "ref\t%s\n", pkg.Ref)
+	}
+
+	for _, arch := range pkg.Architectures {
+		fmt.Fprintf(tab, "arch\t%s\n", arch)
+	}
+
+	for _, value := range pkg.Env {
+		fmt.Fprintf(tab, "env\t%s\n", value)
+	}
+
+	for _, value := range pkg.MakepkgFlags {
+		fmt.Fprintf(tab, "makepkg flag\t%s\n", value)
+	}
+
+	for _, value := range pkg.MakepkgConf {
+		fmt.Fprintf(tab, "makepkg.conf\t%s\n", value)
+	}
+
+	if pkg.VerifyReproducible {
+		fmt.Fprintf(tab, "verify reproducible\tyes\n")
+	}
+
+	if pkg.Review {
+		fmt.Fprintf(tab, "review\tyes\n")
+	}
+
+	if pkg.PendingBuild != "" {
+		fmt.Fprintf(
+			tab,
+			"pending review\t%s, see aurora diff %s %s\n",
+			pkg.PendingBuild, pkg.Name, pkg.PendingBuild,
+		)
+	}
+
+	return tab.Flush()
 }
 
 func printPackages(pkgs ...*proto.Package) error {
 	tab := tabwriter.NewWriter(os.Stdout, 1, 2, 3, ' ', 0)
-	fmt.Fprintf(tab, "NAME\tSTATUS\tVERSION\tDATE\tVER TIME\tBUILD TIME\tPRIORITY\tFAILURES\n")
+	fmt.Fprintf(tab, "NAME\tSTATUS\tVERSION\tDATE\tVER TIME\tBUILD TIME\tPRIORITY\tFAILURES\tREASON\n")
 
 	for _, pkg := range pkgs {
 		fmt.Fprintf(
 			tab,
-			"%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\n",
+			"%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
 			pkg.Name,
 			pkg.Status,
 			pkg.Version,
@@ -76,6 +230,7 @@ func printPackages(pkgs ...*proto.Package) error {
 			pkg.BuildTime.String(),
 			pkg.Priority,
 			pkg.Failures,
+			orDash(pkg.FailureReason),
 		)
 	}
 
@@ -17,9 +17,24 @@ var (
 Aurora is a command line client for aurora daemon.
 
 Usage:
-  aurora [options] get [<package>]
-  aurora [options] add <package>
-  aurora [options] rm <package>
+  aurora [options] get [<package>] [--details]
+  aurora [options] add <package> [-e <var>]... [-f <flag>]... [-m <line>]... [--arch <arch>]... [-r] [--review]
+  aurora [options] set <package> [-e <var>]... [-f <flag>]... [-m <line>]... [--arch <arch>]... [--clear] [-r | -R] [--review | --no-review]
+  aurora [options] rm <package> [--keep-files]
+  aurora [options] patch <package> <file> [-n <name>]
+  aurora [options] patches <package>
+  aurora [options] unpatch <package> <name>
+  aurora [options] push <dir> [-n <name>]
+  aurora [options] builds <package> [-l <n>]
+  aurora [options] diff <package> [<build-a>] [<build-b>]
+  aurora [options] approve <package>
+  aurora [options] reject <package>
+  aurora [options] ack <package>
+  aurora [options] promote <package> [--to <repository>]
+  aurora [options] rollback <package> [<version>]
+  aurora [options] snapshots
+  aurora [options] fsck [--repair]
+  aurora [options] files <pattern> [-x]
   aurora [options] log <package>
   aurora [options] watch <package> [-w]
   aurora [options] whoami
@@ -28,11 +43,77 @@ Usage:
 
 Options:
   get                         Query specified package or query a list of packages.
+   --details                  Show metadata of published archives of the
+                               package: dependencies, sizes, checksums and
+                               files.
   add                         Add a package to the queue.
    -c --clone-url <url>       Use custom clone URL of the package.
    -s --subdir <dir>          Use subdir for in a custom clone URL.
+   --ref <ref>                Check out specified branch, tag or commit
+                               after cloning.
    -p --priority <n>          Use specified priority for the package. [default: 0]
-  remove                      Remove a package from the queue.
+   -e --env <var>             Pass NAME=VALUE environment variable to the build.
+   -f --makepkg-flag <flag>   Pass extra flag to makepkg, e.g. --nocheck.
+   -m --makepkg-conf <line>   Append NAME=VALUE line to makepkg.conf.
+   --arch <arch>              Build the package for specified architecture,
+                               e.g. aarch64, the first one is the main one.
+                               By default the first configured architecture
+                               of the server is used.
+   -r --verify-reproducible   Build the package second time and check that
+                               resulting archives are identical.
+   --review                   Stop building when PKGBUILD or install scripts
+                               are changed until the change is approved,
+                               the first seen version is trusted.
+  set                         Change build settings of a package, specified
+                               settings replace existing ones, --ref is
+                               accepted too.
+   --clear                    Clear all build settings before applying new ones.
+   -R --no-verify-reproducible
+                               Disable reproducibility check.
+   --no-review                Disable review of changes.
+  rm                          Remove a package from the queue, the package
+                               and packages split from it are removed from
+                               repositories and its archives are removed.
+   --keep-files               Move archives of the package to removed/
+                               directory of the server instead of removing.
+  patch                       Upload a patch which is applied to PKGBUILD
+                               directory of a package before building.
+                               Uploading a patch with the same name
+                               creates a new version of the patch.
+  patches                     List patches of a package.
+  unpatch                     Remove a patch of a package.
+  push                        Upload PKGBUILD directory and build the package
+                               from it instead of cloning, the package is
+                               added if it doesn't exist. Name of the
+                               directory is used as name of the package.
+   -n --name <name>           Use specified name of the patch or the package
+                               instead of name of the file or the directory.
+  builds                      List latest builds of a package.
+   -l --limit <n>             Number of builds to list. [default: 10]
+  diff                        Show changes of PKGBUILD, .SRCINFO and install
+                               scripts between two builds, by default latest
+                               build is compared with previous successful one.
+  approve                     Approve changes of a package awaiting review.
+  reject                      Reject changes of a package awaiting review.
+  ack                         Acknowledge reason of hold of a package, e.g.
+                               change of AUR maintainer.
+  promote                     Promote published version of a package to the
+                               next repository, e.g. from testing to stable.
+   --to <repository>          Promote to specified repository.
+  rollback                    List retained archives of a package or publish
+                               the archive of specified version again, the
+                               package is put on hold until aurora ack.
+  snapshots                   List dated snapshots of repositories, use
+                               shown server in pacman.conf to roll back.
+  fsck                        Check consistency of repository databases,
+                               archives and packages on the server.
+   --repair                   Repair found issues, packages which archives
+                               are lost are queued for rebuilding.
+  files                       Find published packages which own files matching
+                               glob pattern, like pacman -F. Pattern without
+                               slashes matches names of files, otherwise
+                               whole paths like /usr/bin/foo are matched.
+   -x --regex                 Interpret pattern as a regular expression.
   log                         Retrieve logs of a package.
   watch                       Watch build process.
   whoami                      Retrieves information about current using in the aurora.
@@ -49,19 +130,58 @@ Options:
 type (
 	Options struct {
 		Get           bool
+		Details       bool
 		Add           bool
+		Set           bool
 		Rm            bool
+		KeepFiles     bool
+		Patch         bool
+		Patches       bool
+		Unpatch       bool
+		Push          bool
+		Builds        bool
+		Diff          bool
+		Approve       bool
+		Reject        bool
+		Ack           bool
+		Promote       bool
+		Snapshots     bool
+		Rollback      bool
+		Fsck          bool
+		Repair        bool
+		Files         bool
+		Pattern       string `docopt:"<pattern>"`
+		Regex         bool
 		Log           bool
 		Watch         bool
 		Whoami        bool
 		Address       string
 		Package       string
+		File          string
+		Dir           string
+		Name          string `docopt:"<name>,--name"`
 		Key           string
 		AllowInsecure bool `docopt:"--i-use-insecure-address"`
 		Wait          bool
 		CloneURL      string `docopt:"--clone-url"`
 		Subdir        string
+		Ref           string
 		Priority      int
+		Env           []string
+		MakepkgFlags  []string `docopt:"--makepkg-flag"`
+		MakepkgConf   []string `docopt:"--makepkg-conf"`
+		Architectures []string `docopt:"--arch"`
+		Clear         bool
+		Limit         int
+		BuildA        string `docopt:"<build-a>"`
+		BuildB        string `docopt:"<build-b>"`
+		To            string
+		Version       string `docopt:"<version>"`
+
+		VerifyReproducible   bool
+		NoVerifyReproducible bool
+		Review               bool
+		NoReview             bool
 	}
 )
 
@@ -90,8 +210,38 @@ func main() {
 		err = handleGet(opts)
 	case opts.Add:
 		err = handleAdd(opts)
+	case opts.Set:
+		err = handleSet(opts)
 	case opts.Rm:
 		err = handleRemove(opts)
+	case opts.Patch:
+		err = handlePatch(opts)
+	case opts.Patches:
+		err = handlePatches(opts)
+	case opts.Unpatch:
+		err = handleUnpatch(opts)
+	case opts.Push:
+		err = handlePush(opts)
+	case opts.Builds:
+		err = handleBuilds(opts)
+	case opts.Diff:
+		err = handleDiff(opts)
+	case opts.Approve:
+		err = handleApprove(opts)
+	case opts.Reject:
+		err = handleReject(opts)
+	case opts.Ack:
+		err = handleAck(opts)
+	case opts.Promote:
+		err = handlePromote(opts)
+	case opts.Rollback:
+		err = handleRollback(opts)
+	case opts.Snapshots:
+		err = handleSnapshots(opts)
+	case opts.Fsck:
+		err = handleFsck(opts)
+	case opts.Files:
+		err = handleFiles(opts)
 	case opts.Log:
 		err = handleLog(opts)
 	case opts.Watch:
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/reconquest/karma-go"
+)
+
+func handlePatch(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	contents, err := ioutil.ReadFile(opts.File)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to read patch file: %s", opts.File,
+		)
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = filepath.Base(opts.File)
+	}
+
+	var response proto.ResponseUploadPatch
+	err = client.Call(
+		(*rpc.PackageService).UploadPatch,
+		proto.RequestUploadPatch{
+			Signature: signer.sign(),
+			Name:      opts.Package,
+			Patch:     name,
+			Content:   string(contents),
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Patch %s has been uploaded\n", response.Patch)
+
+	return nil
+}
+
+func handlePatches(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseListPatches
+	err := client.Call(
+		(*rpc.PackageService).ListPatches,
+		proto.RequestListPatches{
+			Signature: signer.sign(),
+			Name:      opts.Package,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	tab := tabwriter.NewWriter(os.Stdout, 1, 2, 3, ' ', 0)
+	fmt.Fprintf(tab, "NAME\tVERSION\tAUTHOR\tDATE\n")
+
+	for _, patch := range response.Patches {
+		fmt.Fprintf(
+			tab,
+			"%s\t%d\t%s\t%s\n",
+			patch.Name,
+			patch.Version,
+			patch.Author,
+			patch.Date.Format(time.RFC3339),
+		)
+	}
+
+	return tab.Flush()
+}
+
+func handleUnpatch(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseRemovePatch
+	err := client.Call(
+		(*rpc.PackageService).RemovePatch,
+		proto.RequestRemovePatch{
+			Signature: signer.sign(),
+			Name:      opts.Package,
+			Patch:     opts.Name,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Patch %s has been removed\n", opts.Name)
+
+	return nil
+}
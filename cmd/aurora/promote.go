@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+)
+
+func handlePromote(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponsePromotePackage
+	err := client.Call(
+		(*rpc.PackageService).PromotePackage,
+		proto.RequestPromotePackage{
+			Signature:  signer.sign(),
+			Name:       opts.Package,
+			Repository: opts.To,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"Package will be promoted from %s to %s shortly\n",
+		response.From, response.To,
+	)
+
+	return nil
+}
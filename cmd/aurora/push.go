@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/reconquest/karma-go"
+)
+
+// pushIgnore lists files in root of PKGBUILD directory which are created by
+// makepkg or VCS and should not be pushed.
+var pushIgnore = map[string]bool{
+	".git": true,
+	"src":  true,
+	"pkg":  true,
+}
+
+func handlePush(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return err
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+
+	content, err := packSource(dir)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to pack directory: %s", dir,
+		)
+	}
+
+	var response proto.ResponsePushSource
+	err = client.Call(
+		(*rpc.PackageService).PushSource,
+		proto.RequestPushSource{
+			Signature: signer.sign(),
+			Name:      name,
+			Content:   content,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Source %s has been pushed, package has been queued\n", response.Source)
+
+	return nil
+}
+
+func packSource(dir string) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	compressor := gzip.NewWriter(buffer)
+	archive := tar.NewWriter(compressor)
+
+	err := filepath.Walk(
+		dir,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			name, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+
+			if name == "." {
+				return nil
+			}
+
+			if pushIgnore[name] || strings.Contains(info.Name(), ".pkg.tar") {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			if !info.IsDir() && !info.Mode().IsRegular() {
+				return fmt.Errorf("only regular files can be pushed: %s", name)
+			}
+
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+
+			header.Name = filepath.ToSlash(name)
+			if info.IsDir() {
+				header.Name += "/"
+			}
+
+			err = archive.WriteHeader(header)
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+
+			defer file.Close()
+
+			_, err = io.Copy(archive, file)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = compressor.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -11,18 +11,36 @@ func handleRemove(opts Options) error {
 	client := NewClient(opts.Address)
 	signer := NewSigner(opts.Key)
 
+	var response proto.ResponseRemovePackage
 	err := client.Call(
 		(*rpc.PackageService).RemovePackage,
 		proto.RequestRemovePackage{
 			Signature: signer.sign(),
 			Name:      opts.Package,
+			KeepFiles: opts.KeepFiles,
 		},
-		&proto.ResponseRemovePackage{},
+		&response,
 	)
 	if err != nil {
 		return err
 	}
 
+	for _, entry := range response.Entries {
+		fmt.Printf(
+			"removed %s %s from %s (%s)\n",
+			entry.Name, entry.Version, entry.Repository, entry.Arch,
+		)
+	}
+
+	action := "removed"
+	if response.Dir != "" {
+		action = "moved to " + response.Dir
+	}
+
+	for _, file := range response.Files {
+		fmt.Printf("%s %s\n", action, file)
+	}
+
 	fmt.Println("Package has been removed from the queue")
 
 	return nil
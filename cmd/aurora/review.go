@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+)
+
+func handleApprove(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseReviewPackage
+	err := client.Call(
+		(*rpc.PackageService).ApprovePackage,
+		proto.RequestReviewPackage{
+			Signature: signer.sign(),
+			Name:      opts.Package,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Changes have been approved, package has been queued")
+
+	return nil
+}
+
+func handleAck(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseAckPackage
+	err := client.Call(
+		(*rpc.PackageService).AckPackage,
+		proto.RequestAckPackage{
+			Signature: signer.sign(),
+			Name:      opts.Package,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Hold has been acknowledged")
+
+	return nil
+}
+
+func handleReject(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseReviewPackage
+	err := client.Call(
+		(*rpc.PackageService).RejectPackage,
+		proto.RequestReviewPackage{
+			Signature: signer.sign(),
+			Name:      opts.Package,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Changes have been rejected")
+
+	return nil
+}
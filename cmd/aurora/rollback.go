@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+)
+
+func handleRollback(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseRollback
+	err := client.Call(
+		(*rpc.PackageService).Rollback,
+		proto.RequestRollback{
+			Signature: signer.sign(),
+			Name:      opts.Package,
+			Version:   opts.Version,
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	if response.Archive != nil {
+		fmt.Printf(
+			"Package has been rolled back to %s and put on hold, "+
+				"use aurora ack to build it again\n",
+			response.Archive.Version,
+		)
+
+		return nil
+	}
+
+	published := map[string]string{}
+	for repository, version := range response.Package.Repositories {
+		published[version.Archive] += " " + repository
+	}
+
+	tab := tabwriter.NewWriter(os.Stdout, 1, 2, 3, ' ', 0)
+	fmt.Fprintf(tab, "VERSION\tBUILT\tPUBLISHED\tARCHIVE\n")
+
+	for _, archive := range response.Archives {
+		fmt.Fprintf(
+			tab,
+			"%s\t%s\t%s\t%s\n",
+			archive.Version,
+			archive.Built.Format(time.RFC3339),
+			orDash(published[archive.Filename]),
+			archive.Filename,
+		)
+	}
+
+	return tab.Flush()
+}
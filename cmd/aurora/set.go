@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+)
+
+func handleSet(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	request := proto.RequestSetPackage{
+		Signature: signer.sign(),
+		Name:      opts.Package,
+	}
+
+	if opts.Clear {
+		request.Ref = new(string)
+		request.Env = &[]string{}
+		request.MakepkgFlags = &[]string{}
+		request.MakepkgConf = &[]string{}
+		request.Architectures = &[]string{}
+	}
+
+	if opts.Ref != "" {
+		request.Ref = &opts.Ref
+	}
+
+	if len(opts.Env) > 0 {
+		request.Env = &opts.Env
+	}
+
+	if len(opts.MakepkgFlags) > 0 {
+		request.MakepkgFlags = &opts.MakepkgFlags
+	}
+
+	if len(opts.MakepkgConf) > 0 {
+		request.MakepkgConf = &opts.MakepkgConf
+	}
+
+	if len(opts.Architectures) > 0 {
+		request.Architectures = &opts.Architectures
+	}
+
+	if opts.VerifyReproducible || opts.NoVerifyReproducible {
+		request.VerifyReproducible = &opts.VerifyReproducible
+	}
+
+	if opts.Review || opts.NoReview {
+		request.Review = &opts.Review
+	}
+
+	var response proto.ResponseSetPackage
+	err := client.Call(
+		(*rpc.PackageService).SetPackage,
+		request,
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Package settings have been changed")
+
+	return printPackageSettings(response.Package)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+)
+
+func handleSnapshots(opts Options) error {
+	client := NewClient(opts.Address)
+	signer := NewSigner(opts.Key)
+
+	var response proto.ResponseListSnapshots
+	err := client.Call(
+		(*rpc.SnapshotService).ListSnapshots,
+		proto.RequestListSnapshots{
+			Signature: signer.sign(),
+		},
+		&response,
+	)
+	if err != nil {
+		return err
+	}
+
+	base, err := url.Parse(opts.Address)
+	if err != nil {
+		return err
+	}
+
+	tab := tabwriter.NewWriter(os.Stdout, 1, 2, 3, ' ', 0)
+	fmt.Fprintf(tab, "NAME\tARCHIVES\tSERVER\n")
+
+	for _, snapshot := range response.Snapshots {
+		server := *base
+		server.Path = "/aurora/snapshots/" + snapshot.Name
+
+		fmt.Fprintf(
+			tab,
+			"%s\t%d\t%s\n",
+			snapshot.Name,
+			snapshot.Archives,
+			server.String(),
+		)
+	}
+
+	return tab.Flush()
+}
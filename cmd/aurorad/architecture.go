@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/reconquest/karma-go"
+)
+
+// defaultArchitecture is the only architecture of configs written before
+// architectures were introduced.
+const defaultArchitecture = "x86_64"
+
+// validateArchitectures checks names of architectures, configs written
+// before architectures were introduced build only x86_64 packages.
+func validateArchitectures(config *Config) error {
+	if len(config.Architectures) == 0 {
+		config.Architectures = []ConfigArchitecture{{Name: defaultArchitecture}}
+	}
+
+	names := map[string]bool{}
+	for i, architecture := range config.Architectures {
+		if !proto.IsValidArchitecture(architecture.Name) {
+			return fmt.Errorf("invalid architecture name: %q", architecture.Name)
+		}
+
+		// databases of architectures are stored in subdirectories of
+		// repo_dir
+		if architecture.Name == snapshotsDir ||
+			architecture.Name == rpc.RemovedDir {
+			return fmt.Errorf("architecture name is reserved: %s", architecture.Name)
+		}
+
+		if names[architecture.Name] {
+			return fmt.Errorf("duplicate architecture: %s", architecture.Name)
+		}
+
+		if i > 0 && architecture.From == "" {
+			return fmt.Errorf(
+				"base image (from) is not specified for architecture %s",
+				architecture.Name,
+			)
+		}
+
+		names[architecture.Name] = true
+	}
+
+	return nil
+}
+
+// architectureNames returns names of architectures, the first one is the
+// default one.
+func (config *Config) architectureNames() []string {
+	names := []string{}
+	for _, architecture := range config.Architectures {
+		names = append(names, architecture.Name)
+	}
+
+	return names
+}
+
+// image returns name of builder image of given architecture.
+func (config *Config) image(arch string) string {
+	return getImage(config.BaseImage, config.architectureNames(), arch)
+}
+
+// getImage returns name of builder image of given architecture.
+func getImage(baseImage string, architectures []string, arch string) string {
+	if len(architectures) == 0 || arch == architectures[0] {
+		return baseImage
+	}
+
+	return baseImage + "-" + arch
+}
+
+// getImageArgs returns build arguments of builder image of given
+// architecture, the first architecture is built using defaults of
+// Dockerfile unless base image is specified.
+func getImageArgs(architecture ConfigArchitecture) map[string]*string {
+	args := map[string]*string{}
+
+	if architecture.From == "" {
+		return args
+	}
+
+	chost := architecture.CHOST
+	switch {
+	case chost != "":
+	case architecture.Name == defaultArchitecture:
+		chost = "x86_64-pc-linux-gnu"
+	default:
+		chost = architecture.Name + "-unknown-linux-gnu"
+	}
+
+	args["BASE"] = &architecture.From
+	args["CARCH"] = &architecture.Name
+	args["CHOST"] = &chost
+
+	return args
+}
+
+// getArchitectures returns architectures which the package is built for,
+// architectures which are not configured anymore are skipped.
+func getArchitectures(pkg proto.Package, architectures []string) []string {
+	configured := map[string]bool{}
+	for _, arch := range architectures {
+		configured[arch] = true
+	}
+
+	targets := []string{}
+	for _, arch := range pkg.Architectures {
+		if configured[arch] {
+			targets = append(targets, arch)
+		}
+	}
+
+	if len(targets) == 0 && len(architectures) > 0 {
+		targets = append(targets, architectures[0])
+	}
+
+	return targets
+}
+
+// buildArchitecture builds the package for another architecture using the
+// builder image of the architecture and publishes built archive. The same
+// commit is built, pkgver is retrieved only to apply pkgver() of PKGBUILD.
+func (build *build) buildArchitecture(arch string) error {
+	name := build.container + "-" + arch
+
+	build.log.Debugf("creating container %s", name)
+
+	build.bus.Publish(
+		build.pkg.Name,
+		fmt.Sprintf("builder: Creating container for %s\n", arch),
+	)
+
+	container, err := build.cloud.CreateContainer(
+		build.image(arch),
+		build.bufferDir,
+		name,
+		build.getPinnedEnv(),
+		build.getBinds(),
+	)
+	if err != nil {
+		return karma.Format(
+			err, "can't create container",
+		)
+	}
+
+	defer func() {
+		err := build.cloud.DestroyContainer(container)
+		if err != nil {
+			build.log.Error(
+				karma.Format(
+					err, "can't destroy container %s", name,
+				),
+			)
+		}
+	}()
+
+	err = build.cloud.StartContainer(container)
+	if err != nil {
+		return karma.Format(
+			err, "can't start container",
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*30)
+	defer cancel()
+
+	for _, script := range []struct {
+		path  string
+		stage string
+	}{
+		{"/app/clone.sh", failureStageClone},
+		{"/app/prepare.sh", failureStageClone},
+		{"/app/pkgver.sh", failureStagePkgver},
+		{"/app/run.sh", failureStageBuild},
+	} {
+		build.setStage(script.stage)
+
+		err = build.cloud.Exec(
+			ctx, build.log, build.publish(arch+": "),
+			container, []string{script.path}, nil,
+		)
+		if err != nil {
+			return karma.Format(err, "%s failed", filepath.Base(script.path))
+		}
+	}
+
+	archive, err := build.findArchive()
+	if err != nil {
+		return err
+	}
+
+	err = build.verify(archive)
+	if err != nil {
+		removeErr := os.Remove(archive)
+		if removeErr != nil {
+			build.log.Error(
+				karma.Format(
+					removeErr, "unable to remove unverified archive",
+				),
+			)
+		}
+
+		return err
+	}
+
+	repoPath, err := build.publishArchive(archive)
+	if err != nil {
+		return err
+	}
+
+	if build.record.Archives == nil {
+		build.record.Archives = map[string]string{}
+	}
+
+	build.record.Archives[arch] = filepath.Base(repoPath)
+
+	build.bus.Publish(
+		build.pkg.Name,
+		fmt.Sprintf("builder: Package for %s has been published\n", arch),
+	)
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateArchitectures(t *testing.T) {
+	test := assert.New(t)
+
+	config := &Config{}
+	test.NoError(validateArchitectures(config))
+	test.Equal([]string{"x86_64"}, config.architectureNames())
+
+	testcases := []struct {
+		architectures []ConfigArchitecture
+		valid         bool
+	}{
+		{[]ConfigArchitecture{{Name: "x86_64"}, {Name: "aarch64", From: "arm"}}, true},
+		{[]ConfigArchitecture{{Name: "aarch64", From: "arm"}}, true},
+		{[]ConfigArchitecture{{Name: "x86_64"}, {Name: "aarch64"}}, false},
+		{[]ConfigArchitecture{{Name: "x86_64"}, {Name: "x86_64", From: "x"}}, false},
+		{[]ConfigArchitecture{{Name: "any"}}, false},
+		{[]ConfigArchitecture{{Name: "snapshots"}}, false},
+		{[]ConfigArchitecture{{Name: "removed"}}, false},
+		{[]ConfigArchitecture{{Name: "../x86_64"}}, false},
+	}
+
+	for _, testcase := range testcases {
+		err := validateArchitectures(&Config{Architectures: testcase.architectures})
+		if testcase.valid {
+			test.NoError(err, "%v", testcase.architectures)
+		} else {
+			test.Error(err, "%v", testcase.architectures)
+		}
+	}
+}
+
+func TestGetArchitectures(t *testing.T) {
+	test := assert.New(t)
+
+	architectures := []string{"x86_64", "aarch64"}
+
+	test.Equal(
+		[]string{"x86_64"},
+		getArchitectures(proto.Package{}, architectures),
+	)
+	test.Equal(
+		[]string{"aarch64", "x86_64"},
+		getArchitectures(
+			proto.Package{Architectures: []string{"aarch64", "x86_64"}},
+			architectures,
+		),
+	)
+	test.Equal(
+		[]string{"x86_64"},
+		getArchitectures(
+			proto.Package{Architectures: []string{"armv7h"}},
+			architectures,
+		),
+	)
+}
+
+func TestGetImage(t *testing.T) {
+	test := assert.New(t)
+
+	architectures := []string{"x86_64", "aarch64"}
+
+	test.Equal("aurora", getImage("aurora", architectures, "x86_64"))
+	test.Equal("aurora-aarch64", getImage("aurora", architectures, "aarch64"))
+	test.Equal("aurora", getImage("aurora", nil, "x86_64"))
+}
+
+func TestGetImageArgs(t *testing.T) {
+	test := assert.New(t)
+
+	test.Empty(getImageArgs(ConfigArchitecture{Name: "x86_64"}))
+
+	args := getImageArgs(ConfigArchitecture{Name: "aarch64", From: "arm"})
+	test.Equal("arm", *args["BASE"])
+	test.Equal("aarch64", *args["CARCH"])
+	test.Equal("aarch64-unknown-linux-gnu", *args["CHOST"])
+
+	args = getImageArgs(
+		ConfigArchitecture{Name: "armv7h", From: "arm", CHOST: "armv7l-unknown-linux-gnueabihf"},
+	)
+	test.Equal("armv7l-unknown-linux-gnueabihf", *args["CHOST"])
+
+	args = getImageArgs(ConfigArchitecture{Name: "x86_64", From: "archlinux"})
+	test.Equal("x86_64-pc-linux-gnu", *args["CHOST"])
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/aur"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// aurTopic is a bus topic of changes of AUR metadata, it's prefixed like
+// imageTopic.
+const aurTopic = ":aur"
+
+// aurChange is a change of AUR metadata of a package worth notifying about.
+type aurChange struct {
+	message    string
+	maintainer bool
+}
+
+func (proc *Processor) loopAUR(done func()) {
+	defer done()
+
+	endpoint := proc.config.AUR.Endpoint
+	if endpoint == "" {
+		endpoint = aur.DefaultEndpoint
+	}
+
+	client := aur.NewClient(endpoint, time.Minute)
+
+	for {
+		err := proc.checkAUR(client)
+		if err != nil {
+			errorh(err, "unable to check AUR metadata of packages")
+		}
+
+		time.Sleep(proc.config.AUR.Interval)
+	}
+}
+
+// checkAUR updates AUR metadata of packages cloned from AUR and publishes
+// changes to the bus.
+func (proc *Processor) checkAUR(client *aur.Client) error {
+	var packages []proto.Package
+	err := proc.storage.Find(
+		bson.M{
+			"clone_url": "",
+			"source":    bson.M{"$in": []interface{}{0, nil}},
+		},
+	).All(&packages)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find packages in database",
+		)
+	}
+
+	if len(packages) == 0 {
+		return nil
+	}
+
+	// packages are tracked by package base, so split packages which base
+	// isn't named after any of them are found as well
+	bases := []string{}
+	for _, pkg := range packages {
+		bases = append(bases, pkg.Name)
+	}
+
+	infos, err := client.Bases(bases)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to query AUR",
+		)
+	}
+
+	for _, pkg := range packages {
+		info, found := infos[pkg.Name]
+
+		set := bson.M{
+			"deleted":     !found,
+			"aur_checked": time.Now(),
+		}
+
+		if found {
+			set["maintainer"] = info.Maintainer
+			set["out_of_date"] = info.OutOfDateSince()
+		}
+
+		changes := getAURChanges(pkg, info, found)
+		for _, change := range changes {
+			infof("aur: %s: %s", pkg.Name, change.message)
+
+			event := fmt.Sprintf("aur: %s: %s\n", pkg.Name, change.message)
+
+			proc.bus.Publish(pkg.Name, event)
+			proc.bus.Publish(aurTopic, event)
+
+			if change.maintainer && proc.config.AUR.Hold {
+				set["hold"] = change.message
+			}
+		}
+
+		err := proc.storage.Update(
+			bson.M{"name": pkg.Name},
+			bson.M{"$set": set},
+		)
+		if err != nil {
+			errorh(err, "unable to update AUR metadata of package %s", pkg.Name)
+		}
+	}
+
+	return nil
+}
+
+// getAURChanges compares AUR metadata with the previously known state of the
+// package. Nothing is reported on the first check.
+func getAURChanges(pkg proto.Package, info aur.Package, found bool) []aurChange {
+	if pkg.AURChecked.IsZero() {
+		return nil
+	}
+
+	changes := []aurChange{}
+
+	if !found {
+		if !pkg.Deleted {
+			changes = append(changes, aurChange{
+				message: "package has been deleted from AUR",
+			})
+		}
+
+		return changes
+	}
+
+	if pkg.Deleted {
+		changes = append(changes, aurChange{
+			message: "package has been restored in AUR",
+		})
+	}
+
+	if info.Maintainer != pkg.Maintainer {
+		var message string
+		switch {
+		case info.Maintainer == "":
+			message = fmt.Sprintf("package has been orphaned by %s", pkg.Maintainer)
+		case pkg.Maintainer == "":
+			message = fmt.Sprintf("package has been adopted by %s", info.Maintainer)
+		default:
+			message = fmt.Sprintf(
+				"maintainer has been changed from %s to %s",
+				pkg.Maintainer, info.Maintainer,
+			)
+		}
+
+		changes = append(changes, aurChange{message: message, maintainer: true})
+	}
+
+	if pkg.OutOfDate.IsZero() && info.OutOfDate != 0 {
+		changes = append(changes, aurChange{
+			message: "package has been flagged out-of-date",
+		})
+	}
+
+	return changes
+}
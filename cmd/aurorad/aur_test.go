@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/aur"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAURChanges(t *testing.T) {
+	test := assert.New(t)
+
+	checked := time.Unix(1600000000, 0)
+
+	testcases := []struct {
+		Name    string
+		Package proto.Package
+		Info    aur.Package
+		Found   bool
+		Changes []aurChange
+	}{
+		{
+			"first check",
+			proto.Package{},
+			aur.Package{Maintainer: "john", OutOfDate: 1},
+			true,
+			nil,
+		},
+		{
+			"same",
+			proto.Package{Maintainer: "john", AURChecked: checked},
+			aur.Package{Maintainer: "john"},
+			true,
+			[]aurChange{},
+		},
+		{
+			"changed",
+			proto.Package{Maintainer: "john", AURChecked: checked},
+			aur.Package{Maintainer: "mallory"},
+			true,
+			[]aurChange{
+				{"maintainer has been changed from john to mallory", true},
+			},
+		},
+		{
+			"orphaned",
+			proto.Package{Maintainer: "john", AURChecked: checked},
+			aur.Package{},
+			true,
+			[]aurChange{{"package has been orphaned by john", true}},
+		},
+		{
+			"adopted",
+			proto.Package{AURChecked: checked},
+			aur.Package{Maintainer: "mallory"},
+			true,
+			[]aurChange{{"package has been adopted by mallory", true}},
+		},
+		{
+			"deleted",
+			proto.Package{Maintainer: "john", AURChecked: checked},
+			aur.Package{},
+			false,
+			[]aurChange{{"package has been deleted from AUR", false}},
+		},
+		{
+			"still deleted",
+			proto.Package{Maintainer: "john", Deleted: true, AURChecked: checked},
+			aur.Package{},
+			false,
+			[]aurChange{},
+		},
+		{
+			"out-of-date",
+			proto.Package{Maintainer: "john", AURChecked: checked},
+			aur.Package{Maintainer: "john", OutOfDate: 1600000000},
+			true,
+			[]aurChange{{"package has been flagged out-of-date", false}},
+		},
+		{
+			"still out-of-date",
+			proto.Package{
+				Maintainer: "john",
+				OutOfDate:  time.Unix(1600000000, 0),
+				AURChecked: checked,
+			},
+			aur.Package{Maintainer: "john", OutOfDate: 1600000000},
+			true,
+			[]aurChange{},
+		},
+	}
+
+	for _, testcase := range testcases {
+		test.Equal(
+			testcase.Changes,
+			getAURChanges(testcase.Package, testcase.Info, testcase.Found),
+			testcase.Name,
+		)
+	}
+}
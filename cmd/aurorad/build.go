@@ -6,9 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -17,10 +15,13 @@ import (
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/kovetskiy/aurora/pkg/srcinfo"
+	"github.com/kovetskiy/aurora/pkg/vercmp"
 	"github.com/kovetskiy/lorg"
 	"github.com/reconquest/faces/execution"
 	"github.com/reconquest/karma-go"
-	"github.com/reconquest/lexec-go"
 	"github.com/reconquest/regexputil-go"
 )
 
@@ -28,7 +29,10 @@ const (
 	FAILURES_TO_REMOVE = 3
 )
 
-var ErrPkgverNotChanged = errors.New("pkgver not changed")
+var (
+	ErrPkgverNotChanged = errors.New("pkgver not changed")
+	ErrPatchConflict    = errors.New("patch-conflict")
+)
 
 type execWriter struct {
 	logger  lorg.Logger
@@ -44,22 +48,12 @@ func (writer *execWriter) Write(buffer []byte) (int, error) {
 }
 
 const (
-	reArchiveTime = `(?P<time>\d+)`
-	reArchiveName = `(?P<name>[a-zA-Z0-9][a-zA-Z0-9@\._+-]+)`
-	reArchiveVer  = `(?P<ver>[a-zA-Z0-9_.:]+-[0-9]+)`
-	reArchiveArch = `(?P<arch>(i686|x86_64|any))`
-	reArchiveExt  = `(?P<ext>tar(.(gz|bz2|xz|zst|lrz|lzo|sz))?)`
-
-	packagesDatabaseFile = "aurora.db.tar"
+	// defaultRepository is the only repository of configs written before
+	// repositories were introduced.
+	defaultRepository = "aurora"
 )
 
-var reArchiveFilename = regexp.MustCompile(
-	`^` + reArchiveTime +
-		`\.` + reArchiveName +
-		`-` + reArchiveVer +
-		`-` + reArchiveArch +
-		`\.pkg\.` + reArchiveExt + `$`,
-)
+var reArchiveFilename = proto.ArchiveFilename
 
 const (
 	connectionMaxRetries = 10
@@ -67,27 +61,53 @@ const (
 )
 
 type build struct {
-	storage *mgo.Collection
-	pkg     proto.Package
+	storage  *mgo.Collection
+	builds   *mgo.Collection
+	patches  *mgo.Collection
+	sources  *mgo.Collection
+	recipes  *mgo.Collection
+	archives *mgo.Collection
+	files    *mgo.Collection
+	pkg      proto.Package
+	record   proto.Build
+	force    bool
+
+	// started is true once makepkg is started, builds which stopped before
+	// are recorded only if they failed or await review, so polling of
+	// packages which pkgver is not changed doesn't flood history of builds
+	started bool
 
 	instance      string
 	repoDir       string
+	repositories  []string
+	architectures []string
+	baseImage     string
 	bufferDir     string
 	logsDir       string
 	configHistory ConfigHistory
+	configVerify  ConfigVerify
+
+	cloud      *Cloud
+	keyring    *Keyring
+	signer     *Signer
+	classifier *FailureClassifier
 
-	cloud *Cloud
+	// stage is the current stage of the build and output keeps output of
+	// the stage, both are used for detecting reason of failure
+	stage  string
+	output failureLog
 
 	log *lorg.Log
 
+	// arch is the architecture which the package is built for first,
+	// pkgver is retrieved and the build is verified using its image
+	arch      string
 	container string
 	ID        string
 	process   *execution.Operation
 	bus       *Bus
 }
 
-var dbLock = &sync.Mutex{}
-
 func (build *build) String() string {
 	return build.pkg.Name
 }
@@ -109,9 +129,36 @@ func (build *build) updateStatus(status proto.BuildStatus) {
 
 	build.bus.Publish(build.pkg.Name, status)
 
+	build.record.Status = status.String()
+	build.record.Version = build.pkg.Version
+	if status != proto.BuildStatusProcessing {
+		build.record.Finished = time.Now()
+	}
+
+	if build.started ||
+		status == proto.BuildStatusFailure ||
+		status == proto.BuildStatusAwaitingReview {
+		build.updateRecord()
+	}
+
+	// only fields owned by the builder are updated, settings of the package
+	// can be changed via RPC while it's being built
 	err := build.storage.Update(
 		bson.M{"name": build.pkg.Name},
-		build.pkg,
+		bson.M{
+			"$set": bson.M{
+				"status":         build.pkg.Status,
+				"instance":       build.pkg.Instance,
+				"date":           build.pkg.Date,
+				"version":        build.pkg.Version,
+				"failures":       build.pkg.Failures,
+				"build_time":     build.pkg.BuildTime,
+				"pkgver_time":    build.pkg.PkgverTime,
+				"image_digest":   build.pkg.ImageDigest,
+				"image_created":  build.pkg.ImageCreated,
+				"failure_reason": build.pkg.FailureReason,
+			},
+		},
 	)
 	if err != nil {
 		build.log.Error(
@@ -125,11 +172,70 @@ func (build *build) updateStatus(status proto.BuildStatus) {
 	build.log.Infof("status: %s", status)
 }
 
+// clearRebuild clears requested rebuild when the build starts, so changes
+// made during the build request one more rebuild.
+func (build *build) clearRebuild() {
+	err := build.storage.Update(
+		bson.M{"name": build.pkg.Name},
+		bson.M{"$set": bson.M{"rebuild": false}},
+	)
+	if err != nil {
+		build.log.Error(
+			karma.Format(
+				err, "can't clear requested rebuild",
+			),
+		)
+	}
+}
+
+func (build *build) updateRecord() {
+	_, err := build.builds.UpsertId(build.record.ID, build.record)
+	if err != nil {
+		build.log.Error(
+			karma.Format(
+				err, "can't update build record",
+			),
+		)
+	}
+}
+
 func (build *build) init() bool {
 	build.log = logger.NewChildWithPrefix(
 		fmt.Sprintf("(%s)", build.pkg.Name),
 	)
 
+	build.container = build.pkg.Name + "-" + fmt.Sprint(time.Now().Unix())
+	build.arch = getArchitectures(build.pkg, build.architectures)[0]
+
+	build.record = proto.Build{
+		ID:       build.container,
+		Package:  build.pkg.Name,
+		Instance: build.instance,
+		Image:    build.image(build.arch),
+		Forced:   build.force,
+		Source:   build.pkg.Source,
+		Started:  time.Now(),
+	}
+
+	// both builds of reproducibility check should use the same timestamp
+	if build.pkg.VerifyReproducible {
+		build.record.SourceDateEpoch = build.record.Started.Unix()
+	}
+
+	build.setStage(failureStageInternal)
+
+	image, err := build.cloud.InspectImage(build.record.Image)
+	if err != nil {
+		build.log.Error(
+			karma.Format(
+				err, "unable to inspect image %s", build.record.Image,
+			),
+		)
+	} else {
+		build.record.ImageDigest = image.Digest
+		build.record.ImageCreated = image.Created
+	}
+
 	return true
 }
 
@@ -140,6 +246,10 @@ func (build *build) Process() {
 
 	build.cleanup()
 
+	if build.pkg.Rebuild {
+		build.clearRebuild()
+	}
+
 	oldstatus := build.pkg.Status
 
 	build.pkg.Date = time.Now()
@@ -153,12 +263,23 @@ func (build *build) Process() {
 			return
 		}
 
-		build.log.Error(err)
+		if err == ErrAwaitingReview {
+			build.log.Infof("recipe has been changed, awaiting review")
+			build.updateStatus(proto.BuildStatusAwaitingReview)
+			return
+		}
+
+		// rejection is a decision of a reviewer, package shouldn't be
+		// removed because of it
+		if karma.Contains(err, ErrReviewRejected) {
+			build.fail(err)
+			return
+		}
 
 		build.pkg.Failures++
-		build.updateStatus(proto.BuildStatusFailure)
+		build.fail(err)
 
-		if build.pkg.Failures >= FAILURES_TO_REMOVE && build.pkg.Priority == 0 {
+		if isRemovable(build.pkg) {
 			build.log.Warningf(
 				"package failed %d times, removing it from the database",
 				build.pkg.Failures,
@@ -171,43 +292,97 @@ func (build *build) Process() {
 
 	build.log.Infof("package is ready in buffer: %s", archive)
 
-	repoPath := filepath.Join(build.repoDir, filepath.Base(archive))
-
-	err = os.Rename(archive, repoPath)
+	err = build.verify(archive)
 	if err != nil {
-		build.log.Error(
-			karma.Format(
-				err,
-				"unable to move file from buffer",
-			),
-		)
+		build.pkg.Failures++
+		build.fail(err)
+
+		err = os.Remove(archive)
+		if err != nil {
+			build.log.Error(
+				karma.Format(
+					err, "unable to remove unverified archive",
+				),
+			)
+		}
 
-		build.updateStatus(proto.BuildStatusFailure)
 		return
 	}
 
-	build.log.Infof("adding archive %s to aurora repository", repoPath)
+	repoPath, err := build.publishArchive(archive)
+	if err != nil {
+		build.fail(err)
+		return
+	}
 
-	err = build.repoAdd(repoPath)
+	build.record.Archive = filepath.Base(repoPath)
+
+	err = build.setPublished(build.record.Archive)
 	if err != nil {
-		build.log.Error(
-			karma.Format(
-				err, "can't update aurora repository",
-			),
-		)
-		build.updateStatus(proto.BuildStatusFailure)
+		build.log.Error(err)
+	}
 
-		return
+	// archives for any architecture are published to repositories of all
+	// architectures of the package
+	if build.getArchiveArch(repoPath) != proto.ArchitectureAny {
+		for _, arch := range getArchitectures(build.pkg, build.architectures)[1:] {
+			err := build.buildArchitecture(arch)
+			if err != nil {
+				build.pkg.Failures++
+				build.fail(
+					karma.Format(
+						err, "can't build package for %s", arch,
+					),
+				)
+				return
+			}
+		}
 	}
 
+	build.pkg.ImageDigest = build.record.ImageDigest
+	build.pkg.ImageCreated = build.record.ImageCreated
 	build.pkg.Failures = 0
+	build.pkg.FailureReason = ""
 	build.updateStatus(proto.BuildStatusSuccess)
+
+	if build.pkg.VerifyReproducible {
+		build.checkReproducible(repoPath)
+	}
+}
+
+func (build *build) fail(err error) {
+	build.log.Error(err)
+
+	reason := build.classifier.Classify(build.stage, err, build.output.String())
+
+	build.log.Infof("failure reason: %s", reason)
+
+	build.record.Error = err.Error()
+	build.record.FailureReason = reason
+	build.pkg.FailureReason = reason
+
+	build.updateStatus(proto.BuildStatusFailure)
+}
+
+func (build *build) setStage(stage string) {
+	build.stage = stage
+	build.output.Reset()
+}
+
+// publish sends output of the current stage to the bus.
+func (build *build) publish(prefix string) func(string) {
+	return func(log string) {
+		build.output.Write(log)
+		build.bus.Publish(build.pkg.Name, prefix+log)
+	}
 }
 
 func (build *build) cleanup() error {
+	pkgname := build.pkg.ArchiveName()
+
 	globbed, err := filepath.Glob(
 		filepath.Join(
-			fmt.Sprintf("%s/*.%s-*-*-*.pkg.*", build.repoDir, build.pkg.Name),
+			fmt.Sprintf("%s/*.%s-*-*-*.pkg.*", build.repoDir, pkgname),
 		),
 	)
 	if err != nil {
@@ -219,6 +394,7 @@ func (build *build) cleanup() error {
 
 	type archive struct {
 		Time     string
+		Arch     string
 		Basename string
 	}
 
@@ -229,15 +405,23 @@ func (build *build) cleanup() error {
 		matches := reArchiveFilename.FindStringSubmatch(basename)
 
 		name := regexputil.Subexp(reArchiveFilename, matches, "name")
-		if name != build.pkg.Name {
+		if name != pkgname {
+			continue
+		}
+
+		// archives promoted to other repositories are kept regardless of
+		// history settings
+		if build.isPromoted(basename) {
 			continue
 		}
 
 		ver := regexputil.Subexp(reArchiveFilename, matches, "ver")
 		time := regexputil.Subexp(reArchiveFilename, matches, "time")
+		arch := regexputil.Subexp(reArchiveFilename, matches, "arch")
 
 		builds[ver] = append(builds[ver], archive{
 			Time:     time,
+			Arch:     arch,
 			Basename: basename,
 		})
 	}
@@ -251,7 +435,8 @@ func (build *build) cleanup() error {
 	if len(versions) > build.configHistory.Versions {
 		max := build.configHistory.Versions
 
-		sort.Sort(sort.StringSlice(versions))
+		// the newest versions are kept
+		vercmp.Sort(versions)
 
 		for _, version := range versions[max:] {
 			for _, archive := range builds[version] {
@@ -262,13 +447,24 @@ func (build *build) cleanup() error {
 		}
 	}
 
-	for _, archives := range builds {
+	// builds of every architecture are counted separately
+	archs := map[string][]archive{}
+	for version, archives := range builds {
+		for _, archive := range archives {
+			key := version + " " + archive.Arch
+			archs[key] = append(archs[key], archive)
+		}
+	}
+
+	for _, archives := range archs {
 		if len(archives) <= build.configHistory.BuildsPerVersion {
 			continue
 		}
 
+		// the latest builds are kept, times are unix timestamps of the
+		// same length
 		sort.Slice(archives, func(i, j int) bool {
-			return archives[i].Time < archives[j].Time
+			return archives[i].Time > archives[j].Time
 		})
 
 		for _, archive := range archives[build.configHistory.BuildsPerVersion:] {
@@ -291,59 +487,193 @@ func (build *build) cleanup() error {
 				),
 			)
 		}
+
+		err = os.Remove(fullpath + ".sig")
+		if err != nil && !os.IsNotExist(err) {
+			build.log.Error(
+				karma.Format(
+					err,
+					"unable to remove signature of old pkg: %s",
+					fullpath,
+				),
+			)
+		}
 	}
 
 	return nil
 }
 
-func (build *build) repoAdd(path string) error {
-	dbLock.Lock()
-	defer dbLock.Unlock()
+// publishArchive moves verified archive from buffer to repository directory,
+// signs it and adds it to the first repository.
+func (build *build) publishArchive(archive string) (string, error) {
+	build.setStage(failureStageInternal)
 
-	cmd := exec.Command(
-		"repo-add",
-		filepath.Join(build.repoDir, packagesDatabaseFile),
-		path,
+	repoPath := filepath.Join(build.repoDir, filepath.Base(archive))
+
+	err := os.Rename(archive, repoPath)
+	if err != nil {
+		return "", karma.Format(
+			err,
+			"unable to move file from buffer",
+		)
+	}
+
+	build.log.Infof(
+		"adding archive %s to repository %s",
+		repoPath, build.repositories[0],
 	)
 
-	err := lexec.NewExec(lexec.Loggerf(build.log.Tracef), cmd).Run()
+	build.setStage(failureStageRepoAdd)
+
+	if build.signer != nil {
+		err = build.signer.SignFile(repoPath)
+		if err != nil {
+			return "", karma.Format(
+				err, "can't sign archive",
+			)
+		}
+	}
+
+	// metadata is stored before the archive is published, so published
+	// archives are always shown in details and found by files
+	_, err = rpc.PutArchive(build.archives, build.files, build.pkg.Name, repoPath)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	err = build.repoAdd(repoPath)
+	if err != nil {
+		removeErr := rpc.RemoveArchive(
+			build.archives, build.files, filepath.Base(repoPath),
+		)
+		if removeErr != nil {
+			build.log.Error(removeErr)
+		}
+
+		return "", karma.Format(
+			err, "can't update aurora repository",
+		)
+	}
+
+	return repoPath, nil
+}
+
+// repoAdd adds archive to databases of the first repository, archives for
+// any architecture are added to databases of all architectures of the
+// package.
+func (build *build) repoAdd(path string) error {
+	entry, err := repodb.ReadPackage(path)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to read package archive",
+		)
+	}
+
+	architectures := []string{build.getArchiveArch(path)}
+	if architectures[0] == proto.ArchitectureAny {
+		architectures = getArchitectures(build.pkg, build.architectures)
+	}
+
+	for _, arch := range architectures {
+		err := repodb.Update(
+			repodb.ArchPath(build.repoDir, build.repositories[0], build.architectures, arch),
+			build.signer.sign(),
+			func(database *repodb.Database) error {
+				database.Add(entry)
+				return nil
+			},
+		)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to update repository for %s", arch,
+			)
+		}
 	}
 
 	return nil
 }
 
-func (build *build) repoRemove(path string) error {
-	dbLock.Lock()
-	defer dbLock.Unlock()
+// getArchiveArch returns architecture of given archive, architecture of the
+// build is returned if the name of archive can't be parsed.
+func (build *build) getArchiveArch(path string) string {
+	archive, ok := proto.ParseArchive(filepath.Base(path))
+	if !ok {
+		return build.arch
+	}
 
-	cmd := exec.Command(
-		"repo-remove",
-		filepath.Join(build.repoDir, packagesDatabaseFile),
-		path,
-	)
+	return archive.Arch
+}
 
-	err := lexec.NewExec(lexec.Loggerf(build.log.Tracef), cmd).Run()
+// image returns builder image of given architecture, archives for any
+// architecture are verified using image of the build.
+func (build *build) image(arch string) string {
+	if arch == proto.ArchitectureAny {
+		arch = build.arch
+	}
+
+	return getImage(build.baseImage, build.architectures, arch)
+}
+
+// setPublished records version of the package published to the first
+// repository.
+func (build *build) setPublished(archive string) error {
+	published := proto.PackageRepository{
+		Version:   build.pkg.Version,
+		Archive:   archive,
+		Published: time.Now(),
+	}
+
+	if build.pkg.Repositories == nil {
+		build.pkg.Repositories = map[string]proto.PackageRepository{}
+	}
+
+	build.pkg.Repositories[build.repositories[0]] = published
+
+	err := build.storage.Update(
+		bson.M{"name": build.pkg.Name},
+		bson.M{"$set": bson.M{
+			"repositories." + build.repositories[0]: published,
+		}},
+	)
 	if err != nil {
-		return err
+		return karma.Format(
+			err,
+			"unable to update published version of package",
+		)
 	}
 
 	return nil
 }
 
+// isPromoted returns true if the archive is published to any repository
+// except the first one.
+func (build *build) isPromoted(archive string) bool {
+	for _, repository := range build.repositories[1:] {
+		if build.pkg.Repositories[repository].Archive == archive {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (build *build) build(oldstatus string) (string, error) {
 	defer build.shutdown()
 
 	var err error
 
-	build.container = build.pkg.Name + "-" + fmt.Sprint(time.Now().Unix())
-
 	build.ID, err = build.start(oldstatus)
 	if err != nil {
 		return "", err
 	}
 
+	return build.findArchive()
+}
+
+// findArchive returns the newest archive built in buffer directory.
+func (build *build) findArchive() (string, error) {
 	archives, err := filepath.Glob(
 		filepath.Join(
 			fmt.Sprintf("%s/%s/*.pkg.*", build.bufferDir, build.pkg.Name),
@@ -403,14 +733,30 @@ func (build *build) shutdown() {
 func (build *build) start(oldstatus string) (string, error) {
 	build.log.Debugf("creating container %s", build.container)
 
+	var err error
+
+	err = build.prepareSource(build.bufferDir)
+	if err != nil {
+		return "", karma.Format(
+			err, "can't prepare source",
+		)
+	}
+
+	build.record.Patches, err = build.preparePatches(build.bufferDir)
+	if err != nil {
+		return "", karma.Format(
+			err, "can't prepare patches",
+		)
+	}
+
 	build.bus.Publish(build.pkg.Name, "builder: Creating container for makepkg\n")
 
 	container, err := build.cloud.CreateContainer(
+		build.image(build.arch),
 		build.bufferDir,
 		build.container,
-		build.pkg.Name,
-		build.pkg.CloneURL,
-		build.pkg.Subdir,
+		build.getEnv(),
+		build.getBinds(),
 	)
 	if err != nil {
 		return "", karma.Format(
@@ -430,6 +776,31 @@ func (build *build) start(oldstatus string) (string, error) {
 		)
 	}
 
+	build.bus.Publish(build.pkg.Name, "builder: Cloning PKGBUILD\n")
+
+	err = build.clone(container)
+	if err != nil {
+		return "", err
+	}
+
+	// nothing from PKGBUILD directory is executed until it's reviewed
+	err = build.review()
+	if err != nil {
+		return "", err
+	}
+
+	build.bus.Publish(build.pkg.Name, "builder: Preparing PKGBUILD\n")
+
+	srcinfo, err := build.prepare(container)
+	if err != nil {
+		return "", err
+	}
+
+	err = build.importKeys(srcinfo)
+	if err != nil {
+		return "", err
+	}
+
 	build.bus.Publish(build.pkg.Name, "builder: Retrieving PKGVER\n")
 
 	pkgverAt := time.Now()
@@ -443,7 +814,9 @@ func (build *build) start(oldstatus string) (string, error) {
 		)
 	}
 
-	if build.pkg.Version == pkgver && oldstatus != proto.BuildStatusFailure.String() {
+	if !build.force &&
+		vercmp.Compare(build.pkg.Version, pkgver) == 0 &&
+		oldstatus != proto.BuildStatusFailure.String() {
 		build.bus.Publish(build.pkg.Name, "Builder: PKGVER is not changed")
 		return "", ErrPkgverNotChanged
 	}
@@ -456,6 +829,9 @@ func (build *build) start(oldstatus string) (string, error) {
 	build.log.Debug("building package")
 	build.bus.Publish(build.pkg.Name, "builder: Starting build\n")
 
+	build.started = true
+	build.updateRecord()
+
 	runAt := time.Now()
 	_, err = build.WaitRun(container)
 	build.pkg.BuildTime = time.Since(runAt)
@@ -485,11 +861,334 @@ func (build *build) start(oldstatus string) (string, error) {
 	return container, err
 }
 
+// prepareSource puts pushed source of the package into the given buffer
+// directory, dir.sh extracts it instead of cloning.
+func (build *build) prepareSource(bufferDir string) error {
+	path := filepath.Join(bufferDir, build.pkg.Name, "source.tar")
+
+	err := os.RemoveAll(path)
+	if err != nil {
+		return err
+	}
+
+	if build.pkg.Source == 0 {
+		return nil
+	}
+
+	source, err := rpc.FindSource(build.sources, build.pkg.Name, build.pkg.Source)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find source %s@%d in database",
+			build.pkg.Name, build.pkg.Source,
+		)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(path, source.Content, 0o644)
+	if err != nil {
+		return err
+	}
+
+	build.log.Debugf("prepared source %s", source)
+
+	return nil
+}
+
+// preparePatches puts latest versions of patches into the given buffer
+// directory, dir.sh applies them right after cloning. Returns list of
+// prepared patches.
+func (build *build) preparePatches(bufferDir string) ([]string, error) {
+	dir := filepath.Join(bufferDir, build.pkg.Name, "patches")
+
+	err := os.RemoveAll(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	patches, err := rpc.FindPatches(build.patches, build.pkg.Name)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find patches in database",
+		)
+	}
+
+	prepared := []string{}
+
+	if len(patches) == 0 {
+		return prepared, nil
+	}
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, patch := range patches {
+		if !proto.IsValidPatchName(patch.Name) {
+			return nil, fmt.Errorf("invalid patch name in database found: %q", patch.Name)
+		}
+
+		// patches are applied in lexical order
+		path := filepath.Join(dir, fmt.Sprintf("%03d-%s", i, patch.Name))
+
+		err := ioutil.WriteFile(path, []byte(patch.Content), 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		prepared = append(prepared, patch.String())
+	}
+
+	build.log.Debugf("prepared %d patches: %v", len(patches), prepared)
+
+	return prepared, nil
+}
+
+// checkPatchConflict returns ErrPatchConflict if dir.sh wasn't able to apply
+// one of patches.
+func (build *build) checkPatchConflict() error {
+	path := filepath.Join(build.bufferDir, build.pkg.Name, "patch-conflict")
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return karma.Format(
+			err,
+			"unable to read patch conflict file: %s", path,
+		)
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to remove patch conflict file",
+		)
+	}
+
+	return karma.
+		Describe("patch", strings.TrimSpace(string(contents))).
+		Format(ErrPatchConflict, "patch does not apply")
+}
+
+func (build *build) getEnv() []string {
+	env := []string{
+		fmt.Sprintf("AURORA_PACKAGE=%s", build.pkg.Name),
+		fmt.Sprintf("AURORA_CLONE_URL=%s", build.pkg.CloneURL),
+		fmt.Sprintf("AURORA_SUBDIR=%s", build.pkg.Subdir),
+		fmt.Sprintf("AURORA_SOURCE=%d", build.pkg.Source),
+		fmt.Sprintf("AURORA_REF=%s", build.getRef()),
+		fmt.Sprintf(
+			"AURORA_MAKEPKG_FLAGS=%s",
+			strings.Join(build.pkg.MakepkgFlags, " "),
+		),
+		fmt.Sprintf(
+			"AURORA_MAKEPKG_CONF=%s",
+			strings.Join(build.pkg.MakepkgConf, "\n"),
+		),
+	}
+
+	// settings are validated before saving, but database is not a source
+	// of trust for what is going to be passed into the container
+	for _, value := range build.pkg.Env {
+		if !proto.IsValidEnv(value) {
+			build.log.Warningf("skipping invalid env variable: %q", value)
+			continue
+		}
+
+		env = append(env, value)
+	}
+
+	if build.record.SourceDateEpoch != 0 {
+		env = append(
+			env,
+			fmt.Sprintf("SOURCE_DATE_EPOCH=%d", build.record.SourceDateEpoch),
+		)
+	}
+
+	return env
+}
+
+// getPinnedEnv returns environment of a build which should build the same
+// commit as the first build, upstream could be changed since then.
+func (build *build) getPinnedEnv() []string {
+	env := build.getEnv()
+
+	if build.record.Commit != "" {
+		for i := range env {
+			if strings.HasPrefix(env[i], "AURORA_REF=") {
+				env[i] = fmt.Sprintf("AURORA_REF=%s", build.record.Commit)
+			}
+		}
+	}
+
+	return env
+}
+
+// getRef returns git ref which is checked out after cloning.
+func (build *build) getRef() string {
+	if build.pkg.Ref != "" && !proto.IsValidRef(build.pkg.Ref) {
+		build.log.Warningf("skipping invalid git ref: %q", build.pkg.Ref)
+		return ""
+	}
+
+	return build.pkg.Ref
+}
+
+func (build *build) getBinds() []string {
+	binds := []string{}
+
+	if build.keyring != nil {
+		binds = append(binds, fmt.Sprintf("%s:/keyring:ro", build.keyring.dir))
+	}
+
+	return binds
+}
+
+// clone clones PKGBUILD directory or extracts pushed source, nothing is
+// executed from the directory yet.
+func (build *build) clone(container string) error {
+	build.setStage(failureStageClone)
+
+	err := build.cloud.Exec(
+		context.Background(), build.log, build.publish("clone: "),
+		container, []string{"/app/clone.sh"}, nil,
+	)
+	if err != nil {
+		return karma.Format(err, "clone.sh failed")
+	}
+
+	build.record.Commit, err = build.getCommit()
+	if err != nil {
+		return err
+	}
+
+	if build.record.Commit != "" {
+		build.bus.Publish(
+			build.pkg.Name,
+			fmt.Sprintf("builder: PKGBUILD commit is %s\n", build.record.Commit),
+		)
+	}
+
+	return nil
+}
+
+// prepare applies patches to cloned PKGBUILD directory and reads .SRCINFO.
+func (build *build) prepare(container string) (*srcinfo.SrcInfo, error) {
+	build.setStage(failureStageClone)
+
+	err := build.cloud.Exec(
+		context.Background(), build.log, build.publish("prepare: "),
+		container, []string{"/app/prepare.sh"}, nil,
+	)
+	if err != nil {
+		return nil, karma.Format(err, "prepare.sh failed")
+	}
+
+	err = build.checkPatchConflict()
+	if err != nil {
+		return nil, err
+	}
+
+	err = build.captureRecipe()
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to capture recipe",
+		)
+	}
+
+	path := filepath.Join(build.bufferDir, build.pkg.Name, ".SRCINFO")
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to open file after prepare: %s", path,
+		)
+	}
+
+	defer file.Close()
+
+	info, err := srcinfo.Parse(file)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to parse .SRCINFO",
+		)
+	}
+
+	return info, nil
+}
+
+// getCommit returns hash of cloned PKGBUILD commit, the hash is empty for
+// pushed sources.
+func (build *build) getCommit() (string, error) {
+	path := filepath.Join(build.bufferDir, build.pkg.Name, "commit")
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", karma.Format(
+			err,
+			"unable to read file after prepare: %s", path,
+		)
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return "", karma.Format(
+			err,
+			"unable to remove commit file",
+		)
+	}
+
+	return strings.TrimSpace(string(contents)), nil
+}
+
+// importKeys puts keys listed in validpgpkeys into the keyring, so pkgver.sh
+// can import them before verifying sources.
+func (build *build) importKeys(info *srcinfo.SrcInfo) error {
+	if build.keyring == nil || len(info.ValidPGPKeys) == 0 {
+		return nil
+	}
+
+	build.setStage(failureStageKeys)
+
+	build.bus.Publish(
+		build.pkg.Name,
+		fmt.Sprintf("builder: Importing PGP keys: %v\n", info.ValidPGPKeys),
+	)
+
+	err := build.keyring.Import(info.ValidPGPKeys)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to import keys listed in validpgpkeys",
+		)
+	}
+
+	return nil
+}
+
 func (build *build) getVersion(container string) (string, error) {
+	build.setStage(failureStagePkgver)
+
 	err := build.cloud.Exec(
-		context.Background(), build.log, func(log string) {
-			build.bus.Publish(build.pkg.Name, "pkgver: "+log)
-		}, container, []string{"/app/pkgver.sh"}, nil,
+		context.Background(), build.log, build.publish("pkgver: "),
+		container, []string{"/app/pkgver.sh"}, nil,
 	)
 	if err != nil {
 		return "", karma.Format(err, "pkgver.sh failed")
@@ -516,10 +1215,10 @@ func (build *build) getVersion(container string) (string, error) {
 }
 
 func (build *build) run(ctx context.Context, container string) error {
+	build.setStage(failureStageBuild)
+
 	err := build.cloud.Exec(
-		ctx, build.log, func(log string) {
-			build.bus.Publish(build.pkg.Name, "makepkg: "+log)
-		},
+		ctx, build.log, build.publish("makepkg: "),
 		container, []string{"/app/run.sh"}, nil,
 	)
 	if err != nil {
@@ -547,6 +1246,10 @@ func (build *build) WaitRun(name string) (bool, error) {
 	select {
 	case err := <-result:
 		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return false, karma.Format(ctx.Err(), "build timed out: %s", err)
+			}
+
 			return false, err
 		}
 		return true, nil
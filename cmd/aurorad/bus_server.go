@@ -1,21 +1,27 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/gorilla/websocket"
 	"github.com/kovetskiy/aurora/pkg/bus"
 	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/kovetskiy/aurora/pkg/signature"
 )
 
 type BusServer struct {
-	bus *Bus
+	bus  *Bus
+	auth *rpc.AuthService
 }
 
-func NewBusServer(bus *Bus) *BusServer {
+func NewBusServer(bus *Bus, auth *rpc.AuthService) *BusServer {
 	return &BusServer{
-		bus: bus,
+		bus:  bus,
+		auth: auth,
 	}
 }
 
@@ -31,6 +37,14 @@ func (server *BusServer) ServeHTTP(
 		return
 	}
 
+	// packages are removed by the rpc server which has no access to the
+	// bus, so it asks to close the topic of the package passing signature
+	// of the client
+	if request.Method == http.MethodDelete {
+		server.closeTopic(response, request, pkgName)
+		return
+	}
+
 	sub, exists := server.bus.Subscribe(pkgName)
 
 	upgrader := &websocket.Upgrader{
@@ -95,3 +109,29 @@ func (server *BusServer) ServeHTTP(
 
 	server.bus.Unsubscribe(pkgName, sub)
 }
+
+func (server *BusServer) closeTopic(
+	response http.ResponseWriter,
+	request *http.Request,
+	pkgName string,
+) {
+	var sign signature.Signature
+	err := json.NewDecoder(io.LimitReader(request.Body, 64*1024)).Decode(&sign)
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	signer := server.auth.Verify(&sign)
+	if signer == nil {
+		http.Error(response, rpc.ErrorUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	infof("closing bus topic of removed package %s by %s", pkgName, signer)
+
+	server.bus.Publish(pkgName, "remove: Package has been removed\n")
+	server.bus.Close(pkgName)
+
+	response.WriteHeader(http.StatusNoContent)
+}
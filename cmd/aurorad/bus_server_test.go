@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/kovetskiy/aurora/pkg/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusServer_CloseTopic(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "aurora-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(
+		filepath.Join(dir, "john"),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}),
+		0o644,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth, err := rpc.NewAuthService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := NewBus()
+	server := httptest.NewServer(NewBusServer(bus, auth))
+	defer server.Close()
+
+	sub, _ := bus.Subscribe("foo")
+
+	closeTopic := func(body []byte) int {
+		request, err := http.NewRequest(
+			http.MethodDelete,
+			server.URL+"/?package=foo",
+			bytes.NewReader(body),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		response.Body.Close()
+
+		return response.StatusCode
+	}
+
+	test.Equal(http.StatusBadRequest, closeTopic(nil))
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unauthorized, err := json.Marshal(signature.New(other))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test.Equal(http.StatusUnauthorized, closeTopic(unauthorized))
+	test.Len(bus.subs["foo"], 1)
+
+	authorized, err := json.Marshal(signature.New(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for range sub {
+		}
+	}()
+
+	test.Equal(http.StatusNoContent, closeTopic(authorized))
+	test.Len(bus.subs["foo"], 0)
+}
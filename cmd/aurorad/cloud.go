@@ -9,11 +9,14 @@ import (
 	"runtime"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/kovetskiy/aurora/docker"
 	"github.com/kovetskiy/lorg"
 	"github.com/reconquest/karma-go"
 )
@@ -23,8 +26,7 @@ const (
 )
 
 type Cloud struct {
-	client    *client.Client
-	BaseImage string
+	client *client.Client
 
 	mutex     sync.Mutex
 	resources ConfigResources
@@ -32,12 +34,27 @@ type Cloud struct {
 	cpuNext   int
 }
 
-func NewCloud(baseImage string, resources ConfigResources, threads int) (*Cloud, error) {
+// ExecError is returned by Exec when command exits with non-zero code.
+type ExecError struct {
+	Command  []string
+	ExitCode int
+}
+
+func (err ExecError) Error() string {
+	return fmt.Sprintf("%v exited with code %d", err.Command, err.ExitCode)
+}
+
+type Image struct {
+	Name    string
+	Digest  string
+	Created time.Time
+}
+
+func NewCloud(resources ConfigResources, threads int) (*Cloud, error) {
 	var err error
 
 	cloud := &Cloud{}
 	cloud.client, err = client.NewEnvClient()
-	cloud.BaseImage = baseImage
 	cloud.resources = resources
 
 	if threads == 0 {
@@ -69,31 +86,28 @@ func (cloud *Cloud) getNextCPU() string {
 }
 
 func (cloud *Cloud) CreateContainer(
+	image string,
 	bufferDir string,
 	containerName string,
-	packageName string,
-	cloneURL string,
-	subdir string,
+	env []string,
+	binds []string,
 ) (string, error) {
 	config := &container.Config{
-		Image: cloud.BaseImage,
+		Image: image,
 		Labels: map[string]string{
 			ImageLabelKey: version,
 		},
-		Tty: true,
-		Env: []string{
-			fmt.Sprintf("AURORA_PACKAGE=%s", packageName),
-			fmt.Sprintf("AURORA_CLONE_URL=%s", cloneURL),
-			fmt.Sprintf("AURORA_SUBDIR=%s", subdir),
-		},
+		Tty:          true,
+		Env:          env,
 		AttachStdout: true,
 		AttachStderr: true,
 	}
 
 	hostConfig := &container.HostConfig{
-		Binds: []string{
-			fmt.Sprintf("%s:/buffer", bufferDir),
-		},
+		Binds: append(
+			[]string{fmt.Sprintf("%s:/buffer", bufferDir)},
+			binds...,
+		),
 	}
 
 	if cloud.resources.CPU > 0 {
@@ -208,9 +222,87 @@ func (cloud *Cloud) Exec(
 		return karma.Format(err, "unable to read stdout of exec/attach")
 	}
 
+	inspect, err := cloud.client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return karma.Format(err, "unable to inspect exec")
+	}
+
+	if inspect.ExitCode != 0 {
+		return ExecError{Command: command, ExitCode: inspect.ExitCode}
+	}
+
 	return nil
 }
 
+// BuildImage builds image from the embedded build context, args are passed
+// to Dockerfile, e.g. base image of another architecture.
+func (cloud *Cloud) BuildImage(
+	ctx context.Context,
+	image string,
+	args map[string]*string,
+	logger lorg.Logger,
+	publish func(string),
+) error {
+	buildContext, err := docker.Context()
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to prepare image build context",
+		)
+	}
+
+	response, err := cloud.client.ImageBuild(
+		ctx, buildContext,
+		types.ImageBuildOptions{
+			Tags:        []string{image},
+			BuildArgs:   args,
+			Remove:      true,
+			ForceRemove: true,
+			PullParent:  true,
+			NoCache:     true,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	writer := &execWriter{logger: logger, publish: publish}
+
+	err = jsonmessage.DisplayJSONMessagesStream(
+		response.Body, writer, 0, false, nil,
+	)
+	if err != nil {
+		return karma.Format(err, "image build failed")
+	}
+
+	return nil
+}
+
+func (cloud *Cloud) InspectImage(image string) (*Image, error) {
+	inspect, _, err := cloud.client.ImageInspectWithRaw(
+		context.Background(), image,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := time.Parse(time.RFC3339Nano, inspect.Created)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to parse image creation time: %q", inspect.Created,
+		)
+	}
+
+	return &Image{
+		Name:    image,
+		Digest:  inspect.ID,
+		Created: created,
+	}, nil
+}
+
 func (cloud *Cloud) WriteLogs(
 	logsDir, container, packageName string,
 ) error {
@@ -9,7 +9,7 @@ import (
 func TestNext_One(t *testing.T) {
 	test := assert.New(t)
 
-	cloud, _ := NewCloud("a", ConfigResources{CPU: 1}, 4)
+	cloud, _ := NewCloud(ConfigResources{CPU: 1}, 4)
 
 	test.Equal("0", cloud.getNextCPU())
 	test.Equal("1", cloud.getNextCPU())
@@ -21,7 +21,7 @@ func TestNext_One(t *testing.T) {
 func TestNext_Two(t *testing.T) {
 	test := assert.New(t)
 
-	cloud, _ := NewCloud("a", ConfigResources{CPU: 2}, 4)
+	cloud, _ := NewCloud(ConfigResources{CPU: 2}, 4)
 
 	test.Equal("0-1", cloud.getNextCPU())
 	test.Equal("2-3", cloud.getNextCPU())
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"time"
 
 	"github.com/go-yaml/yaml"
@@ -13,6 +15,8 @@ import (
 
 const defaultConfigPath = `/etc/aurora/aurora.conf`
 
+var reRepositoryName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 const defaultConfig = `# enable debug messages
 debug: true
 
@@ -25,9 +29,40 @@ listen: ":80"
 # DSN of database to use (mongodb)
 database: "mongodb://localhost/aurora"
 
-# directory with ready-to-install packages
+# directory with ready-to-install packages and repository databases
 repo_dir: "/srv/http/aurora/"
 
+# repositories which packages are published to, every repository is a
+# database <name>.db.tar in repo_dir and is served at /<name>/, so
+# pacman.conf should use Server = https://<host>/$repo. New builds are
+# published to the first repository and then promoted to the next ones using
+# aurora promote, for example:
+#   - name: "testing"
+#   - name: "stable"
+#     # promote automatically once the version has been published to the
+#     # previous repository for specified time without failed builds,
+#     # 0 = promote manually only
+#     promote_after: "72h"
+repositories:
+  - name: "aurora"
+
+# architectures which packages are built for, packages are built for the
+# first architecture unless other architectures are specified using
+# aurora add/set --arch. Databases of the first architecture are stored in
+# repo_dir, databases of other architectures are stored in repo_dir/<arch>/,
+# every repository is served at /<name>/<arch>/ as well, so pacman.conf can
+# use Server = https://<host>/$repo/$arch. Builder image of the first
+# architecture is base_image, images of other architectures are named
+# <base_image>-<arch> and built from specified archlinux image of that
+# architecture, foreign images are run using binfmt_misc emulation
+# (qemu-user-static), for example:
+#   - name: "aarch64"
+#     from: "lopsided/archlinux-arm64v8"
+#     # CHOST of makepkg.conf, default is <name>-unknown-linux-gnu
+#     chost: "aarch64-unknown-linux-gnu"
+architectures:
+  - name: "x86_64"
+
 # directory where logs will be stored
 logs_dir: "/var/log/aurora/packages/"
 
@@ -58,6 +93,103 @@ timeout:
 # image used for building pkgs
 base_image: "aurora"
 
+image:
+  # rebuild base image in background every specified time, 0 = never
+  refresh: "0"
+  # force rebuild of packages which were built using an image older than
+  # specified time when a newer image is available, 0 = never
+  max_age: "0"
+
+keyring:
+  # directory where public keys listed in validpgpkeys are cached,
+  # empty = don't manage keys
+  dir: "/var/aurora/keyring/"
+  # keyserver to fetch missing keys from, empty = use cached keys only
+  keyserver: "https://keyserver.ubuntu.com"
+
+verify:
+  # run namcap on built archives before publishing
+  namcap: false
+  # install built archives using pacman -U in a fresh container before
+  # publishing
+  install: false
+  # don't publish archive if namcap reports an issue of specified severity or
+  # higher: "error", "warning", "info", empty = never
+  block_severity: "error"
+
+aur:
+  # endpoint of AUR RPC interface
+  endpoint: "https://aur.archlinux.org/rpc/"
+  # check maintainers and out-of-date flags of packages cloned from AUR every
+  # specified time, 0 = never
+  interval: "1h"
+  # hold building of a package once its maintainer is changed until the
+  # change is acknowledged using aurora ack
+  hold: false
+
+official:
+  # mirror of official repositories, $repo and $arch are replaced, file://
+  # URLs are supported as well
+  mirror: "https://geo.mirror.pkgbuild.com/$repo/os/$arch"
+  repos: ["core", "extra", "multilib"]
+  arch: "x86_64"
+  # mark packages which are available in official repositories as superseded
+  # every specified time, superseded packages are not built, 0 = never
+  interval: "6h"
+  # remove superseded packages from aurora repository database after
+  # specified time, 0 = never
+  remove_after: "0"
+
+signing:
+  # armored private key used for signing archives and the repository
+  # database, can be generated using aurorad --generate-signing-key,
+  # empty = don't sign
+  key: ""
+  # identity of generated key
+  name: "aurora"
+  email: "aurora@localhost"
+
+snapshots:
+  # create immutable snapshot of repositories made of hardlinks every
+  # specified time, snapshots are served at /aurora/snapshots/<date>/ and
+  # created once a day at most, 0 = never
+  interval: "0"
+  # remove snapshots older than specified time, 0 = never
+  max_age: "720h"
+  # keep only specified number of latest snapshots, 0 = unlimited
+  max_count: 0
+
+retention:
+  # apply retention policies every specified time and at startup, removed
+  # items are reported on the bus of their packages and counted in metrics
+  # served at /debug/vars of the bus server, 0 = at startup only
+  interval: "1h"
+  archives:
+    # remove archives which are not published to any repository after
+    # specified time, they are retained for aurora rollback
+    max_age: "96h"
+  logs:
+    # remove logs of last builds of packages after specified time, 0 = never
+    max_age: "720h"
+    # keep only logs of specified number of recently built packages,
+    # 0 = unlimited
+    max_count: 0
+  builds:
+    # remove records of builds after specified time except the latest build
+    # of every package, 0 = never
+    max_age: "2160h"
+  buffer:
+    # remove leftovers of failed builds in buffer_dir after specified time,
+    # 0 = never
+    max_age: "24h"
+
+failures:
+  # log patterns used for detecting reason of failed builds, checked in order
+  # before the built-in ones, for example:
+  #   - reason: "download"
+  #     regexp: "Connection timed out"
+  patterns: []
+
 # settings for cleaning up disk space in repository
 history:
 	# how many different pkgver-pkgrel combination can exist
@@ -82,6 +214,79 @@ type ConfigHistory struct {
 	BuildsPerVersion int `yaml:"builds_per_version" required:"true"`
 }
 
+type ConfigRetention struct {
+	Interval time.Duration         `yaml:"interval"`
+	Archives ConfigRetentionPolicy `yaml:"archives" default:"max_age: 96h"`
+	Logs     ConfigRetentionPolicy `yaml:"logs"`
+	Builds   ConfigRetentionPolicy `yaml:"builds"`
+	Buffer   ConfigRetentionPolicy `yaml:"buffer"`
+}
+
+// ConfigRetentionPolicy describes when items are removed, MaxCount is used
+// only for logs.
+type ConfigRetentionPolicy struct {
+	MaxAge   time.Duration `yaml:"max_age"`
+	MaxCount int           `yaml:"max_count"`
+}
+
+type ConfigImage struct {
+	Refresh time.Duration `yaml:"refresh"`
+	MaxAge  time.Duration `yaml:"max_age"`
+}
+
+type ConfigKeyring struct {
+	Dir       string `yaml:"dir"`
+	Keyserver string `yaml:"keyserver"`
+}
+
+type ConfigVerify struct {
+	Namcap        bool   `yaml:"namcap"`
+	Install       bool   `yaml:"install"`
+	BlockSeverity string `yaml:"block_severity"`
+}
+
+type ConfigAUR struct {
+	Endpoint string        `yaml:"endpoint"`
+	Interval time.Duration `yaml:"interval"`
+	Hold     bool          `yaml:"hold"`
+}
+
+type ConfigOfficial struct {
+	Mirror      string        `yaml:"mirror"`
+	Repos       []string      `yaml:"repos"`
+	Arch        string        `yaml:"arch"`
+	Interval    time.Duration `yaml:"interval"`
+	RemoveAfter time.Duration `yaml:"remove_after"`
+}
+
+type ConfigSigning struct {
+	Key   string `yaml:"key"`
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+}
+
+type ConfigRepository struct {
+	Name         string        `yaml:"name" required:"true"`
+	PromoteAfter time.Duration `yaml:"promote_after"`
+}
+
+type ConfigArchitecture struct {
+	Name  string `yaml:"name" required:"true"`
+	From  string `yaml:"from"`
+	CHOST string `yaml:"chost"`
+}
+
+type ConfigSnapshots struct {
+	Interval time.Duration `yaml:"interval"`
+	MaxAge   time.Duration `yaml:"max_age"`
+	MaxCount int           `yaml:"max_count"`
+}
+
+type ConfigFailurePattern struct {
+	Reason string `yaml:"reason" required:"true"`
+	Regexp string `yaml:"regexp" required:"true"`
+}
+
 type ConfigResources struct {
 	CPU int `yaml:"cpu"`
 }
@@ -90,15 +295,27 @@ type Config struct {
 	Debug bool
 	Trace bool
 
-	Instance  string        `yaml:"instance" required:"true"`
-	Listen    string        `required:"true"`
-	Database  string        `required:"true"`
-	RepoDir   string        `yaml:"repo_dir" required:"true"`
-	LogsDir   string        `yaml:"logs_dir" required:"true"`
-	BufferDir string        `yaml:"buffer_dir" required:"true"`
-	Threads   int           `yaml:"threads"`
-	BaseImage string        `yaml:"base_image" required:"true"`
-	History   ConfigHistory `yaml:"history" required:"true"`
+	Instance  string          `yaml:"instance" required:"true"`
+	Listen    string          `required:"true"`
+	Database  string          `required:"true"`
+	RepoDir   string          `yaml:"repo_dir" required:"true"`
+	LogsDir   string          `yaml:"logs_dir" required:"true"`
+	BufferDir string          `yaml:"buffer_dir" required:"true"`
+	Threads   int             `yaml:"threads"`
+	BaseImage string          `yaml:"base_image" required:"true"`
+	History   ConfigHistory   `yaml:"history" required:"true"`
+	Image     ConfigImage     `yaml:"image"`
+	Keyring   ConfigKeyring   `yaml:"keyring"`
+	Verify    ConfigVerify    `yaml:"verify"`
+	AUR       ConfigAUR       `yaml:"aur"`
+	Official  ConfigOfficial  `yaml:"official"`
+	Signing   ConfigSigning   `yaml:"signing"`
+	Snapshots ConfigSnapshots `yaml:"snapshots"`
+	Retention ConfigRetention `yaml:"retention"`
+
+	Failures struct {
+		Patterns []ConfigFailurePattern `yaml:"patterns"`
+	} `yaml:"failures"`
 
 	Bus struct {
 		Listen string `yaml:"listen" required:"true"`
@@ -117,6 +334,9 @@ type Config struct {
 		Build string `yaml:"build" required:"true"`
 	} `required:"true"`
 
+	Repositories  []ConfigRepository   `yaml:"repositories"`
+	Architectures []ConfigArchitecture `yaml:"architectures"`
+
 	Resources         ConfigResources
 	AuthorizedKeysDir string `yaml:"authorized_keys" required:"true"`
 }
@@ -152,6 +372,20 @@ func GetConfig(path string) (*Config, error) {
 		err = ko.Load(path, &config, yaml.Unmarshal)
 	}
 
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateRepositories(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateArchitectures(&config)
+	if err != nil {
+		return nil, err
+	}
+
 	if config.Instance == "$HOSTNAME" {
 		instance, err := os.Hostname()
 		if err != nil {
@@ -161,5 +395,49 @@ func GetConfig(path string) (*Config, error) {
 		config.Instance = instance
 	}
 
-	return &config, err
+	return &config, nil
+}
+
+// validateRepositories checks names of repositories, configs written before
+// repositories were introduced have the only repository named aurora.
+func validateRepositories(config *Config) error {
+	if len(config.Repositories) == 0 {
+		config.Repositories = []ConfigRepository{{Name: defaultRepository}}
+	}
+
+	names := map[string]bool{}
+	for i, repository := range config.Repositories {
+		if !reRepositoryName.MatchString(repository.Name) {
+			return fmt.Errorf("invalid repository name: %q", repository.Name)
+		}
+
+		if repository.Name == "rpc" {
+			return fmt.Errorf("repository name is reserved: %s", repository.Name)
+		}
+
+		if names[repository.Name] {
+			return fmt.Errorf("duplicate repository: %s", repository.Name)
+		}
+
+		if i == 0 && repository.PromoteAfter > 0 {
+			return fmt.Errorf(
+				"promote_after can't be specified for the first repository %s",
+				repository.Name,
+			)
+		}
+
+		names[repository.Name] = true
+	}
+
+	return nil
+}
+
+// repositoryNames returns names of repositories in order of promotion.
+func (config *Config) repositoryNames() []string {
+	names := []string{}
+	for _, repository := range config.Repositories {
+		names = append(names, repository.Name)
+	}
+
+	return names
 }
@@ -7,6 +7,19 @@ import (
 	karma "github.com/reconquest/karma-go"
 )
 
+// Collections holds collections of aurora database.
+type Collections struct {
+	Packages *mgo.Collection
+	Builds   *mgo.Collection
+	Patches  *mgo.Collection
+	Sources  *mgo.Collection
+	Recipes  *mgo.Collection
+	Archives *mgo.Collection
+	// ArchiveFiles are files of archives stored apart from their metadata,
+	// since files of large archives don't fit into one document.
+	ArchiveFiles *mgo.Collection
+}
+
 type Database struct {
 	*mgo.Database
 
@@ -27,6 +40,72 @@ func NewDatabase(dsn string) (*Database, error) {
 	return db, nil
 }
 
+func (db *Database) Collections() (*Collections, error) {
+	collections := &Collections{
+		Packages: db.C("packages"),
+		Builds:   db.C("builds"),
+		Patches:  db.C("patches"),
+		Sources:  db.C("sources"),
+		Recipes:  db.C("recipes"),
+		Archives: db.C("archives"),
+
+		ArchiveFiles: db.C("archive_files"),
+	}
+
+	indexes := []struct {
+		collection *mgo.Collection
+		index      mgo.Index
+	}{
+		{
+			collections.Packages,
+			mgo.Index{Key: []string{"name"}, Unique: true},
+		},
+		{
+			collections.Builds,
+			mgo.Index{Key: []string{"package", "-started"}},
+		},
+		{
+			collections.Patches,
+			mgo.Index{
+				Key:    []string{"package", "name", "version"},
+				Unique: true,
+			},
+		},
+		{
+			collections.Sources,
+			mgo.Index{
+				Key:    []string{"package", "version"},
+				Unique: true,
+			},
+		},
+		{
+			collections.Archives,
+			mgo.Index{Key: []string{"package", "-published"}},
+		},
+		{
+			collections.ArchiveFiles,
+			mgo.Index{Key: []string{"archive", "chunk"}, Unique: true},
+		},
+		{
+			collections.ArchiveFiles,
+			mgo.Index{Key: []string{"package"}},
+		},
+	}
+
+	for _, item := range indexes {
+		err := item.collection.EnsureIndex(item.index)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"can't ensure index for collection %s",
+				item.collection.Name,
+			)
+		}
+	}
+
+	return collections, nil
+}
+
 func (db *Database) connect() error {
 	logger.Infof(
 		"connecting to db %q",
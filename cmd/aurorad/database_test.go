@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo"
+)
+
+// newTestCollections returns collections of an empty database in mongodb
+// specified by AURORA_TEST_DATABASE, the database is dropped after the test.
+// Tests which need a database are skipped if it's not specified.
+func newTestCollections(t *testing.T) *Collections {
+	dsn := os.Getenv("AURORA_TEST_DATABASE")
+	if dsn == "" {
+		t.Skip("AURORA_TEST_DATABASE is not specified")
+	}
+
+	session, err := mgo.DialWithTimeout(dsn, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	database := &Database{
+		Database: session.DB(fmt.Sprintf("aurora_test_%d", time.Now().UnixNano())),
+		session:  session,
+	}
+
+	t.Cleanup(func() {
+		database.DropDatabase()
+		session.Close()
+	})
+
+	collections, err := database.Collections()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return collections
+}
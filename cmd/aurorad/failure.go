@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// Exit codes of makepkg, see /usr/share/makepkg/util/error.sh
+const (
+	makepkgExitUserFunctionFailed = 4
+	makepkgExitPackageFailed      = 5
+	makepkgExitInstallDepsFailed  = 8
+	makepkgExitPkgbuildError      = 12
+	makepkgExitMissingMakepkgDeps = 15
+	makepkgExitPrettyBadPrivacy   = 16
+)
+
+// Exit codes of aurora scripts, see docker/dir.sh and docker/prepare.sh
+const (
+	auroraExitCloneFailed   = 64
+	auroraExitPatchConflict = 65
+)
+
+// Stages of the build process.
+const (
+	failureStageInternal = "internal"
+	failureStageClone    = "clone"
+	failureStageKeys     = "keys"
+	failureStagePkgver   = "pkgver"
+	failureStageBuild    = "build"
+	failureStageLint     = "lint"
+	failureStageInstall  = "install"
+	failureStageRepoAdd  = "repo-add"
+)
+
+const failureLogSize = 64 * 1024
+
+// defaultFailurePatterns are checked after patterns specified in config.
+var defaultFailurePatterns = []ConfigFailurePattern{
+	{
+		Reason: proto.FailureReasonUnknownPGPKey,
+		Regexp: `unknown public key`,
+	},
+	{
+		Reason: proto.FailureReasonPGP,
+		Regexp: `PGP signatures could not be verified`,
+	},
+	{
+		Reason: proto.FailureReasonChecksum,
+		Regexp: `did not pass the validity check`,
+	},
+	{
+		Reason: proto.FailureReasonDownload,
+		Regexp: `Failure while downloading|` +
+			`Failure while updating|` +
+			`could not resolve host|` +
+			`The requested URL returned error`,
+	},
+	{
+		Reason: proto.FailureReasonDependencies,
+		Regexp: `could not satisfy dependencies|` +
+			`target not found|` +
+			`Failed to install missing dependencies`,
+	},
+	{
+		Reason: proto.FailureReasonPackage,
+		Regexp: `A failure occurred in package(_\S+)?\(\)`,
+	},
+	{
+		Reason: proto.FailureReasonBuild,
+		Regexp: `A failure occurred in (prepare|build|check)\(\)`,
+	},
+}
+
+type failurePattern struct {
+	reason string
+	regexp *regexp.Regexp
+}
+
+// FailureClassifier detects machine-readable reason of a build failure using
+// the stage that failed, exit code of the failed command and its output.
+type FailureClassifier struct {
+	patterns []failurePattern
+}
+
+func NewFailureClassifier(
+	config []ConfigFailurePattern,
+) (*FailureClassifier, error) {
+	classifier := &FailureClassifier{}
+
+	for _, item := range append(config, defaultFailurePatterns...) {
+		expression, err := regexp.Compile(item.Regexp)
+		if err != nil {
+			return nil, karma.
+				Describe("reason", item.Reason).
+				Format(err, "unable to compile failure pattern")
+		}
+
+		classifier.patterns = append(classifier.patterns, failurePattern{
+			reason: item.Reason,
+			regexp: expression,
+		})
+	}
+
+	return classifier, nil
+}
+
+func (classifier *FailureClassifier) Classify(
+	stage string,
+	err error,
+	log string,
+) string {
+	switch {
+	case karma.Contains(err, ErrPatchConflict):
+		return proto.FailureReasonPatchConflict
+
+	case karma.Contains(err, ErrReviewRejected):
+		return proto.FailureReasonRejected
+
+	case karma.Contains(err, ErrUnknownPGPKey):
+		return proto.FailureReasonUnknownPGPKey
+
+	case karma.Contains(err, ErrLintFailed), stage == failureStageLint:
+		return proto.FailureReasonLint
+
+	// output of pacman -U is very similar to output of makepkg
+	case stage == failureStageInstall:
+		return proto.FailureReasonInstall
+
+	case karma.Contains(err, context.DeadlineExceeded):
+		return proto.FailureReasonTimeout
+	}
+
+	for _, pattern := range classifier.patterns {
+		if pattern.regexp.MatchString(log) {
+			return pattern.reason
+		}
+	}
+
+	var exec ExecError
+	if karma.Find(err, &exec) {
+		switch exec.ExitCode {
+		case auroraExitCloneFailed:
+			return proto.FailureReasonClone
+
+		case auroraExitPatchConflict:
+			return proto.FailureReasonPatchConflict
+
+		case makepkgExitPkgbuildError:
+			return proto.FailureReasonPkgbuild
+
+		case makepkgExitInstallDepsFailed, makepkgExitMissingMakepkgDeps:
+			return proto.FailureReasonDependencies
+
+		case makepkgExitPrettyBadPrivacy:
+			return proto.FailureReasonPGP
+
+		case makepkgExitPackageFailed:
+			return proto.FailureReasonPackage
+
+		case makepkgExitUserFunctionFailed:
+			if stage == failureStagePkgver {
+				return proto.FailureReasonPkgver
+			}
+
+			return proto.FailureReasonBuild
+		}
+	}
+
+	switch stage {
+	case failureStageClone:
+		return proto.FailureReasonClone
+	case failureStageKeys:
+		return proto.FailureReasonPGP
+	case failureStagePkgver:
+		return proto.FailureReasonPkgver
+	case failureStageBuild:
+		return proto.FailureReasonBuild
+	case failureStageRepoAdd:
+		return proto.FailureReasonRepoAdd
+	}
+
+	return proto.FailureReasonInternal
+}
+
+// isRemovable returns true if the failed package should be removed from
+// the queue, packages with priority and packages built from pushed sources
+// are kept, pushed sources would be lost otherwise.
+func isRemovable(pkg proto.Package) bool {
+	return pkg.Failures >= FAILURES_TO_REMOVE &&
+		pkg.Priority == 0 &&
+		pkg.Source == 0
+}
+
+// failureLog keeps tail of output of the current build stage.
+type failureLog struct {
+	mutex  sync.Mutex
+	buffer strings.Builder
+}
+
+func (log *failureLog) Write(data string) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	if log.buffer.Len()+len(data) > failureLogSize {
+		tail := log.buffer.String()
+		if len(tail) > failureLogSize/2 {
+			tail = tail[len(tail)-failureLogSize/2:]
+		}
+
+		log.buffer.Reset()
+		log.buffer.WriteString(tail)
+	}
+
+	log.buffer.WriteString(data)
+}
+
+func (log *failureLog) Reset() {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	log.buffer.Reset()
+}
+
+func (log *failureLog) String() string {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+
+	return log.buffer.String()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureClassifier_Classify(t *testing.T) {
+	test := assert.New(t)
+
+	classifier, err := NewFailureClassifier([]ConfigFailurePattern{
+		{Reason: "network", Regexp: `Connection timed out`},
+	})
+	test.NoError(err)
+
+	exec := func(code int) error {
+		return karma.Format(ExecError{ExitCode: code}, "script failed")
+	}
+
+	testcases := []struct {
+		Stage  string
+		Err    error
+		Log    string
+		Reason string
+	}{
+		{
+			failureStageClone, exec(64),
+			"fatal: repository not found", proto.FailureReasonClone,
+		},
+		{
+			failureStageClone,
+			karma.Format(ErrPatchConflict, "patch does not apply"),
+			"", proto.FailureReasonPatchConflict,
+		},
+		{
+			failureStageClone,
+			karma.Format(ErrReviewRejected, "recipe has been rejected"),
+			"", proto.FailureReasonRejected,
+		},
+		{
+			failureStageKeys,
+			karma.Format(ErrUnknownPGPKey, "key not found"),
+			"", proto.FailureReasonUnknownPGPKey,
+		},
+		{
+			failureStageKeys, errors.New("connection refused"),
+			"", proto.FailureReasonPGP,
+		},
+		{
+			failureStagePkgver, exec(1),
+			"==> ERROR: One or more files did not pass the validity check!",
+			proto.FailureReasonChecksum,
+		},
+		{
+			failureStagePkgver, exec(1),
+			"foo.tar.gz.sig ... FAILED (unknown public key 0123456789ABCDEF)",
+			proto.FailureReasonUnknownPGPKey,
+		},
+		{
+			failureStagePkgver, exec(16),
+			"", proto.FailureReasonPGP,
+		},
+		{
+			failureStagePkgver, exec(1),
+			"==> ERROR: Failure while downloading https://example.com",
+			proto.FailureReasonDownload,
+		},
+		{
+			failureStagePkgver, exec(8),
+			"", proto.FailureReasonDependencies,
+		},
+		{
+			failureStagePkgver, exec(4),
+			"", proto.FailureReasonPkgver,
+		},
+		{
+			failureStagePkgver, exec(12),
+			"", proto.FailureReasonPkgbuild,
+		},
+		{
+			failureStageBuild, exec(4),
+			"==> ERROR: A failure occurred in build().",
+			proto.FailureReasonBuild,
+		},
+		{
+			failureStageBuild, exec(4),
+			"==> ERROR: A failure occurred in package_foo().",
+			proto.FailureReasonPackage,
+		},
+		{
+			failureStageBuild,
+			karma.Format(context.DeadlineExceeded, "build timed out"),
+			"", proto.FailureReasonTimeout,
+		},
+		{
+			failureStageBuild, exec(1),
+			"curl: (28) Connection timed out", "network",
+		},
+		{
+			failureStageLint,
+			karma.Format(ErrLintFailed, "namcap reported issues"),
+			"", proto.FailureReasonLint,
+		},
+		{
+			failureStageInstall, exec(1),
+			"error: target not found: bar", proto.FailureReasonInstall,
+		},
+		{
+			failureStageRepoAdd, errors.New("repo-add failed"),
+			"", proto.FailureReasonRepoAdd,
+		},
+		{
+			failureStageInternal, errors.New("can't create container"),
+			"", proto.FailureReasonInternal,
+		},
+	}
+
+	for _, testcase := range testcases {
+		test.Equal(
+			testcase.Reason,
+			classifier.Classify(testcase.Stage, testcase.Err, testcase.Log),
+			"%s: %s", testcase.Stage, testcase.Err,
+		)
+	}
+}
+
+func TestNewFailureClassifier_InvalidPattern(t *testing.T) {
+	test := assert.New(t)
+
+	_, err := NewFailureClassifier([]ConfigFailurePattern{
+		{Reason: "broken", Regexp: `(`},
+	})
+	test.Error(err)
+}
+
+func TestIsRemovable(t *testing.T) {
+	test := assert.New(t)
+
+	testcases := []struct {
+		pkg       proto.Package
+		removable bool
+	}{
+		{proto.Package{Failures: FAILURES_TO_REMOVE}, true},
+		{proto.Package{Failures: FAILURES_TO_REMOVE - 1}, false},
+		{proto.Package{Failures: FAILURES_TO_REMOVE, Priority: 1}, false},
+		{proto.Package{Failures: FAILURES_TO_REMOVE, Source: 1}, false},
+	}
+
+	for _, testcase := range testcases {
+		test.Equal(testcase.removable, isRemovable(testcase.pkg), "%+v", testcase.pkg)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/kovetskiy/aurora/pkg/fsck"
+	"github.com/reconquest/karma-go"
+)
+
+// checkRepositories reports inconsistencies between archives, repository
+// databases and packages and repairs them if repair is true. Packages which
+// archives are lost are queued for rebuilding by the processor.
+func checkRepositories(collections *Collections, config *Config, repair bool) error {
+	var signer *Signer
+	if repair && config.Signing.Key != "" {
+		var err error
+		signer, err = NewSigner(config.Signing)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to init signer",
+			)
+		}
+	}
+
+	issues, err := fsck.Run(
+		collections.Packages,
+		config.RepoDir,
+		config.repositoryNames(),
+		config.architectureNames(),
+		signer.sign(),
+		repair,
+	)
+	if err != nil {
+		return err
+	}
+
+	unrepaired := 0
+	for _, issue := range issues {
+		switch {
+		case issue.Repaired:
+			fmt.Printf("%s (repaired)\n", issue)
+		case issue.Error != "":
+			fmt.Printf("%s (unable to repair: %s)\n", issue, issue.Error)
+			unrepaired++
+		default:
+			fmt.Println(issue)
+			unrepaired++
+		}
+	}
+
+	if unrepaired > 0 {
+		return fmt.Errorf("%d issues found", unrepaired)
+	}
+
+	if len(issues) == 0 {
+		infof("no issues found")
+	}
+
+	return nil
+}
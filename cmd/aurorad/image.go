@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/reconquest/karma-go"
+)
+
+// imageTopic is a bus topic of image builds, topics which are not packages
+// start with a colon which is not allowed in package names.
+const imageTopic = ":image"
+
+func buildImage(config *Config) error {
+	cloud, err := NewCloud(config.Resources, config.Threads)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to init cloud (docker) client",
+		)
+	}
+
+	for _, architecture := range config.Architectures {
+		name := config.image(architecture.Name)
+
+		infof("building image %s", name)
+
+		err = cloud.BuildImage(
+			context.Background(), name, getImageArgs(architecture), logger,
+			func(log string) {
+				fmt.Print(log)
+			},
+		)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to build image %s", name,
+			)
+		}
+
+		image, err := cloud.InspectImage(name)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to inspect image %s", name,
+			)
+		}
+
+		infof("image %s has been built: %s", image.Name, image.Digest)
+	}
+
+	return nil
+}
+
+func (proc *Processor) loopImage(done func()) {
+	defer done()
+	for {
+		time.Sleep(proc.config.Image.Refresh)
+
+		for _, architecture := range proc.config.Architectures {
+			proc.refreshImage(architecture)
+		}
+	}
+}
+
+func (proc *Processor) refreshImage(architecture ConfigArchitecture) {
+	name := proc.config.image(architecture.Name)
+
+	infof("refreshing image %s", name)
+
+	err := proc.cloud.BuildImage(
+		context.Background(), name, getImageArgs(architecture), logger,
+		func(log string) {
+			proc.bus.Publish(imageTopic, "image: "+log)
+		},
+	)
+	if err != nil {
+		errorh(err, "unable to refresh image %s", name)
+		return
+	}
+
+	image, err := proc.cloud.InspectImage(name)
+	if err != nil {
+		errorh(err, "unable to inspect image %s", name)
+		return
+	}
+
+	infof("image %s has been refreshed: %s", image.Name, image.Digest)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reconquest/karma-go"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+var ErrUnknownPGPKey = errors.New("unknown-pgp-key")
+
+var reFingerprint = regexp.MustCompile(`^[0-9A-F]{16,40}$`)
+
+// Keyring caches public keys listed in validpgpkeys of packages. Keys are
+// fetched from a keyserver and stored as armored files, the directory is
+// mounted read-only into build containers.
+type Keyring struct {
+	dir       string
+	keyserver string
+	client    *http.Client
+	mutex     sync.Mutex
+}
+
+func NewKeyring(config ConfigKeyring) (*Keyring, error) {
+	dir, err := filepath.Abs(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, karma.Format(
+			err, "can't mkdir %s", dir,
+		)
+	}
+
+	return &Keyring{
+		dir:       dir,
+		keyserver: strings.TrimRight(config.Keyserver, "/"),
+		client:    &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+// Import ensures that all specified keys exist in the keyring, missing keys
+// are fetched from the keyserver.
+func (keyring *Keyring) Import(fingerprints []string) error {
+	keyring.mutex.Lock()
+	defer keyring.mutex.Unlock()
+
+	for _, fingerprint := range fingerprints {
+		fingerprint = strings.ToUpper(fingerprint)
+		if !reFingerprint.MatchString(fingerprint) {
+			return karma.
+				Describe("key", fingerprint).
+				Format(ErrUnknownPGPKey, "invalid key fingerprint")
+		}
+
+		path := keyring.getPath(fingerprint)
+
+		_, err := os.Stat(path)
+		if err == nil {
+			continue
+		}
+
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		if keyring.keyserver == "" {
+			return karma.
+				Describe("key", fingerprint).
+				Format(ErrUnknownPGPKey, "key is not cached and keyserver is not set")
+		}
+
+		err = keyring.fetch(fingerprint, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (keyring *Keyring) fetch(fingerprint string, path string) error {
+	query := url.Values{}
+	query.Set("op", "get")
+	query.Set("options", "mr")
+	query.Set("search", "0x"+fingerprint)
+
+	address := keyring.keyserver + "/pks/lookup?" + query.Encode()
+
+	infof("keyring: fetching key %s from %s", fingerprint, address)
+
+	response, err := keyring.client.Get(address)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to request keyserver: %s", address,
+		)
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return karma.
+			Describe("key", fingerprint).
+			Format(ErrUnknownPGPKey, "key not found on keyserver")
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf(
+			"unexpected keyserver response status: %s", response.Status,
+		)
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(response.Body)
+	if err != nil {
+		return karma.
+			Describe("key", fingerprint).
+			Format(err, "unable to parse key returned by keyserver")
+	}
+
+	// keyserver is not trusted, it could return other keys along with the
+	// requested one, so only the requested key is stored
+	entity := findEntity(entities, fingerprint)
+	if entity == nil {
+		return karma.
+			Describe("key", fingerprint).
+			Format(ErrUnknownPGPKey, "keyserver returned unexpected key")
+	}
+
+	armored, err := armorEntity(entity)
+	if err != nil {
+		return karma.
+			Describe("key", fingerprint).
+			Format(err, "unable to serialize key returned by keyserver")
+	}
+
+	temp := path + ".tmp"
+
+	err = ioutil.WriteFile(temp, armored, 0o644)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(temp, path)
+	if err != nil {
+		return err
+	}
+
+	infof("keyring: key %s has been cached", fingerprint)
+
+	return nil
+}
+
+func (keyring *Keyring) getPath(fingerprint string) string {
+	return filepath.Join(keyring.dir, fingerprint+".asc")
+}
+
+// findEntity returns the entity which primary key has given fingerprint,
+// fingerprint can be shortened to a long key ID.
+func findEntity(entities openpgp.EntityList, fingerprint string) *openpgp.Entity {
+	for _, entity := range entities {
+		actual := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+		if strings.HasSuffix(actual, fingerprint) {
+			return entity
+		}
+	}
+
+	return nil
+}
+
+// armorEntity returns armored public key of given entity.
+func armorEntity(entity *openpgp.Entity) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+
+	writer, err := armor.Encode(buffer, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = entity.Serialize(writer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
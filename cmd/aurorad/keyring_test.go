@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/reconquest/karma-go"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func generatePublicKey(t *testing.T, dir string, name string) (string, []byte) {
+	signer, err := GenerateSigningKey(ConfigSigning{
+		Key:   filepath.Join(dir, name+".key"),
+		Name:  name,
+		Email: name + "@localhost",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, err := signer.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer.Fingerprint(), public
+}
+
+// armorKeys returns given armored keys in one armored block like
+// keyservers do.
+func armorKeys(t *testing.T, keys ...[]byte) []byte {
+	buffer := &bytes.Buffer{}
+
+	writer, err := armor.Encode(buffer, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range keys {
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, entity := range entities {
+			err := entity.Serialize(writer)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestKeyring_Import(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "keyring")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	served, public := generatePublicKey(t, dir, "john")
+	other, otherPublic := generatePublicKey(t, dir, "mary")
+	missing := strings.Repeat("A", 40)
+
+	requests := []string{}
+
+	// the keyserver returns an unrelated key along with the served one and
+	// the served key for every other known fingerprint
+	server := httptest.NewServer(http.HandlerFunc(
+		func(writer http.ResponseWriter, request *http.Request) {
+			query := request.URL.Query()
+			test.Equal("/pks/lookup", request.URL.Path)
+			test.Equal("get", query.Get("op"))
+
+			requests = append(requests, query.Get("search"))
+
+			if query.Get("search") == "0x"+missing {
+				http.NotFound(writer, request)
+				return
+			}
+
+			if query.Get("search") == "0x"+served {
+				writer.Write(armorKeys(t, otherPublic, public))
+				return
+			}
+
+			writer.Write(public)
+		},
+	))
+	defer server.Close()
+
+	keyring, err := NewKeyring(ConfigKeyring{
+		Dir:       filepath.Join(dir, "keyring"),
+		Keyserver: server.URL + "/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test.NoError(keyring.Import([]string{strings.ToLower(served)}))
+	test.FileExists(keyring.getPath(served))
+
+	cached, err := ioutil.ReadFile(keyring.getPath(served))
+	test.NoError(err)
+
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(cached))
+	test.NoError(err)
+
+	if test.Len(entities, 1) {
+		test.Equal(
+			served,
+			fmt.Sprintf("%X", entities[0].PrimaryKey.Fingerprint),
+		)
+	}
+
+	// cached keys are not fetched again
+	test.NoError(keyring.Import([]string{served}))
+	test.Equal([]string{"0x" + served}, requests)
+
+	err = keyring.Import([]string{other})
+	test.True(karma.Contains(err, ErrUnknownPGPKey), err)
+	test.Contains(err.Error(), "keyserver returned unexpected key")
+
+	_, err = os.Stat(keyring.getPath(other))
+	test.True(os.IsNotExist(err))
+
+	err = keyring.Import([]string{missing})
+	test.True(karma.Contains(err, ErrUnknownPGPKey), err)
+	test.Contains(err.Error(), "key not found on keyserver")
+
+	_, err = os.Stat(keyring.getPath(missing))
+	test.True(os.IsNotExist(err))
+
+	err = keyring.Import([]string{"../../etc/passwd"})
+	test.True(karma.Contains(err, ErrUnknownPGPKey), err)
+	test.Len(requests, 3)
+}
@@ -25,7 +25,10 @@ Usage:
   aurorad [options] -R <package>...
   aurorad [options] -Q
   aurorad [options] -P
+  aurorad [options] --build-image
+  aurorad [options] --fsck [--repair]
   aurorad [options] --generate-config
+  aurorad [options] --generate-signing-key
   aurorad -h | --help
   aurorad --version
 
@@ -35,6 +38,14 @@ Options:
   -R --remove         Remove specified package from watch and make cycle queue.
   -P --process        Process watch and make cycle queue.
   -Q --query          Query package database.
+  --build-image       Build base image from the embedded docker context.
+  --fsck              Check consistency of repository databases, archives
+                       and packages.
+  --repair            Repair found issues, packages which archives are lost
+                       are queued for rebuilding.
+  --generate-signing-key
+                      Generate key for signing packages at path specified
+                       in config.
   -c --config <path>  Configuration file path.
                        [default: ` + defaultConfigPath + `]
   -p --priority <n>   Priority level of the package [default: 0].
@@ -93,21 +104,41 @@ func main() {
 		logger.SetLevel(lorg.LevelTrace)
 	}
 
+	if args["--build-image"].(bool) {
+		err := buildImage(config)
+		if err != nil {
+			fatalln(err)
+		}
+
+		os.Exit(0)
+	}
+
+	if args["--generate-signing-key"].(bool) {
+		signer, err := GenerateSigningKey(config.Signing)
+		if err != nil {
+			fatalh(err, "unable to generate signing key")
+		}
+
+		infof(
+			"signing key %s has been written to %s",
+			signer.Fingerprint(), config.Signing.Key,
+		)
+
+		os.Exit(0)
+	}
+
 	database, err := NewDatabase("mongodb://localhost/aurora")
 	if err != nil {
 		fatalh(err, "can't open aurora database")
 	}
 
-	packages := database.C("packages")
-
-	err = packages.EnsureIndex(mgo.Index{
-		Key:    []string{"name"},
-		Unique: true,
-	})
+	collections, err := database.Collections()
 	if err != nil {
-		fatalh(err, "can't ensure index for collection")
+		fatalh(err, "can't prepare database collections")
 	}
 
+	packages := collections.Packages
+
 	switch {
 	case args["--add"].(bool):
 		priority, _ := strconv.Atoi(args["--priority"].(string))
@@ -117,13 +148,16 @@ func main() {
 		err = removePackage(packages, args["<package>"].([]string))
 
 	case args["--process"].(bool):
-		err = processQueue(packages, config)
+		err = processQueue(collections, config)
 
 	case args["--query"].(bool):
 		err = queryPackage(packages)
 
+	case args["--fsck"].(bool):
+		err = checkRepositories(collections, config, args["--repair"].(bool))
+
 	case args["--listen"].(bool):
-		err = serveWeb(packages, config)
+		err = serveWeb(collections, config)
 	}
 
 	if err != nil {
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/reconquest/karma-go"
+)
+
+// officialTopic is a bus topic of changes of superseded packages, it's
+// prefixed like imageTopic.
+const officialTopic = ":official"
+
+func (proc *Processor) loopOfficial(done func()) {
+	defer done()
+
+	client := newOfficialClient()
+
+	for {
+		err := proc.checkOfficial(client)
+		if err != nil {
+			errorh(err, "unable to check official repositories")
+		}
+
+		time.Sleep(proc.config.Official.Interval)
+	}
+}
+
+// newOfficialClient returns a client which fetches databases of official
+// repositories from http mirrors and from file:// mirrors.
+func newOfficialClient() *http.Client {
+	transport := &http.Transport{}
+	transport.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+
+	return &http.Client{Transport: transport, Timeout: time.Minute * 5}
+}
+
+// checkOfficial marks packages which are provided by official repositories
+// as superseded and unmarks packages which are not provided anymore.
+func (proc *Processor) checkOfficial(client *http.Client) error {
+	index := newOfficialIndex()
+
+	for _, repo := range proc.config.Official.Repos {
+		entries, err := proc.fetchOfficial(client, repo)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to fetch database of %s", repo,
+			)
+		}
+
+		index.add(repo, entries)
+	}
+
+	var packages []proto.Package
+	err := proc.storage.Find(bson.M{}).All(&packages)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find packages in database",
+		)
+	}
+
+	provides, err := proc.getPublishedProvides(packages)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range packages {
+		err := proc.updateSuperseded(pkg, index.find(pkg, provides[pkg.Name]))
+		if err != nil {
+			errorh(err, "unable to update superseded state of %s", pkg.Name)
+		}
+	}
+
+	return nil
+}
+
+func (proc *Processor) fetchOfficial(
+	client *http.Client,
+	repo string,
+) ([]*repodb.Entry, error) {
+	url := strings.NewReplacer(
+		"$repo", repo,
+		"$arch", proc.config.Official.Arch,
+	).Replace(proc.config.Official.Mirror)
+
+	url = strings.TrimSuffix(url, "/") + "/" + repo + ".db"
+
+	tracef("fetching %s", url)
+
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code of %s: %s", url, response.Status)
+	}
+
+	return repodb.Read(response.Body)
+}
+
+// getPublishedProvides returns provides of published archives of packages
+// by names of packages.
+func (proc *Processor) getPublishedProvides(
+	packages []proto.Package,
+) (map[string][]string, error) {
+	published := []string{}
+	for _, pkg := range packages {
+		for _, record := range pkg.Repositories {
+			published = append(published, record.Archive)
+		}
+	}
+
+	var archives []proto.ArchiveMetadata
+	err := proc.archives.Find(
+		bson.M{"_id": bson.M{"$in": published}},
+	).Select(
+		bson.M{"package": 1, "provides": 1},
+	).All(&archives)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find archives in database",
+		)
+	}
+
+	provides := map[string][]string{}
+	for _, archive := range archives {
+		provides[archive.Package] = append(
+			provides[archive.Package], archive.Provides...,
+		)
+	}
+
+	return provides, nil
+}
+
+// officialIndex holds names and provides of packages of official
+// repositories, values are like extra/foo 1.0-1.
+type officialIndex struct {
+	names    map[string]string
+	provides map[string]string
+}
+
+func newOfficialIndex() *officialIndex {
+	return &officialIndex{
+		names:    map[string]string{},
+		provides: map[string]string{},
+	}
+}
+
+// add adds names and provides of packages of the repository into the index,
+// packages of repositories added first take precedence.
+func (index *officialIndex) add(repo string, entries []*repodb.Entry) {
+	for _, entry := range entries {
+		value := fmt.Sprintf("%s/%s %s", repo, entry.Name, entry.Version)
+
+		if _, ok := index.names[entry.Name]; !ok {
+			index.names[entry.Name] = value
+		}
+
+		for _, provide := range entry.Provides {
+			name := repodb.StripVersion(provide)
+			if _, ok := index.provides[name]; !ok {
+				index.provides[name] = value
+			}
+		}
+	}
+}
+
+// find returns the official package which supersedes the package. The name
+// of the package and pkgname of its archives are looked up in names and
+// provides of official packages, provides of the package are looked up in
+// names only, so shared sonames and virtual packages like sh don't
+// supersede anything.
+func (index *officialIndex) find(pkg proto.Package, provides []string) string {
+	names := []string{pkg.Name, pkg.ArchiveName()}
+
+	// names of real packages take precedence over provides
+	for _, name := range names {
+		if value, ok := index.names[name]; ok {
+			return value
+		}
+	}
+
+	for _, name := range names {
+		if value, ok := index.provides[name]; ok {
+			return value
+		}
+	}
+
+	for _, provide := range provides {
+		if value, ok := index.names[repodb.StripVersion(provide)]; ok {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func (proc *Processor) updateSuperseded(pkg proto.Package, official string) error {
+	query := bson.M{"name": pkg.Name}
+
+	switch {
+	case official == "" && pkg.Superseded == "":
+		return nil
+
+	case official == "":
+		infof("official: %s is not superseded anymore", pkg.Name)
+
+		proc.publishOfficial(pkg.Name, "package is not available in official repositories anymore")
+
+		set := bson.M{"status": proto.BuildStatusQueued.String()}
+
+		// removed package is published again even if pkgver is not changed
+		if pkg.SupersededRemoved {
+			set["rebuild"] = true
+		}
+
+		return proc.storage.Update(query, bson.M{
+			"$set": set,
+			"$unset": bson.M{
+				"superseded":         "",
+				"superseded_at":      "",
+				"superseded_removed": "",
+			},
+		})
+
+	case pkg.Superseded == "":
+		infof("official: %s is superseded by %s", pkg.Name, official)
+
+		proc.publishOfficial(pkg.Name, "package is superseded by "+official)
+
+		set := bson.M{
+			"superseded":    official,
+			"superseded_at": time.Now(),
+		}
+
+		// package could be being built at the moment of marking
+		if pkg.Status != proto.BuildStatusProcessing.String() {
+			set["status"] = proto.BuildStatusSuperseded.String()
+		}
+
+		return proc.storage.Update(query, bson.M{"$set": set})
+	}
+
+	set := bson.M{"superseded": official}
+
+	// package could be being built at the moment of marking
+	if pkg.Status != proto.BuildStatusSuperseded.String() &&
+		pkg.Status != proto.BuildStatusProcessing.String() {
+		set["status"] = proto.BuildStatusSuperseded.String()
+	}
+
+	removeAfter := proc.config.Official.RemoveAfter
+	if removeAfter > 0 && !pkg.SupersededRemoved &&
+		time.Since(pkg.SupersededAt) > removeAfter {
+		infof("official: removing superseded %s from repositories", pkg.Name)
+
+		err := proc.repoRemove(pkg)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to remove superseded package from repositories",
+			)
+		}
+
+		proc.publishOfficial(pkg.Name, "package has been removed from aurora repositories")
+
+		set["superseded_removed"] = true
+	}
+
+	return proc.storage.Update(query, bson.M{"$set": set})
+}
+
+func (proc *Processor) publishOfficial(name string, message string) {
+	event := fmt.Sprintf("official: %s: %s\n", name, message)
+
+	proc.bus.Publish(name, event)
+	proc.bus.Publish(officialTopic, event)
+}
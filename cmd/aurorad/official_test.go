@@ -0,0 +1,278 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfficialIndex(t *testing.T) {
+	test := assert.New(t)
+
+	index := newOfficialIndex()
+
+	index.add("core", []*repodb.Entry{
+		{Name: "foo", Version: "1.0-1", Provides: []string{"libfoo.so=1-64", "foo-git", "sh"}},
+	})
+
+	index.add("extra", []*repodb.Entry{
+		{Name: "bar", Version: "2.0-1", Provides: []string{"foo-git=2.0"}},
+		{Name: "foo-git", Version: "3.0-1"},
+	})
+
+	published := func(archive string) map[string]proto.PackageRepository {
+		return map[string]proto.PackageRepository{
+			"aurora": {Archive: archive},
+		}
+	}
+
+	testcases := []struct {
+		Name     string
+		Package  proto.Package
+		Provides []string
+		Official string
+	}{
+		{
+			"name",
+			proto.Package{Name: "foo"},
+			nil,
+			"core/foo 1.0-1",
+		},
+		{
+			"names take precedence over provides",
+			proto.Package{Name: "foo-git"},
+			nil,
+			"extra/foo-git 3.0-1",
+		},
+		{
+			"provided name",
+			proto.Package{Name: "libfoo.so"},
+			nil,
+			"core/foo 1.0-1",
+		},
+		{
+			"pkgname of archive",
+			proto.Package{
+				Name:         "custom",
+				Repositories: published("1600000000.bar-2.1-1-x86_64.pkg.tar.zst"),
+			},
+			nil,
+			"extra/bar 2.0-1",
+		},
+		{
+			"provides of package",
+			proto.Package{Name: "bar-bin"},
+			[]string{"bar=2.1"},
+			"extra/bar 2.0-1",
+		},
+		{
+			"provides of package are not looked up in provides",
+			proto.Package{Name: "busybox"},
+			[]string{"sh", "libfoo.so=1-64"},
+			"",
+		},
+		{
+			"not superseded",
+			proto.Package{Name: "baz"},
+			nil,
+			"",
+		},
+	}
+
+	for _, testcase := range testcases {
+		test.Equal(
+			testcase.Official,
+			index.find(testcase.Package, testcase.Provides),
+			testcase.Name,
+		)
+	}
+}
+
+func TestProcessor_CheckOfficial(t *testing.T) {
+	test := assert.New(t)
+
+	collections := newTestCollections(t)
+
+	dir, err := ioutil.TempDir("", "official")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	mirror := filepath.Join(dir, "mirror")
+
+	err = repodb.Update(
+		repodb.Path(mirror, "extra"),
+		nil,
+		func(database *repodb.Database) error {
+			database.Add(&repodb.Entry{Name: "foo", Version: "2.0-1"})
+			database.Add(&repodb.Entry{Name: "bar", Version: "2.0-1"})
+			database.Add(&repodb.Entry{Name: "busy", Version: "2.0-1"})
+			database.Add(&repodb.Entry{Name: "gone", Version: "2.0-1"})
+			database.Add(&repodb.Entry{
+				Name:     "bash",
+				Version:  "5.0-1",
+				Provides: []string{"sh"},
+			})
+
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proc := NewProcessor(collections, &Config{
+		Repositories:  []ConfigRepository{{Name: "aurora"}},
+		Architectures: []ConfigArchitecture{{Name: "x86_64"}},
+		Official: ConfigOfficial{
+			Mirror:      "file://" + mirror,
+			Repos:       []string{"extra"},
+			RemoveAfter: time.Hour * 24,
+		},
+	}, NewBus())
+
+	proc.repoDir = filepath.Join(dir, "repo")
+
+	err = repodb.Update(
+		repodb.Path(proc.repoDir, "aurora"),
+		nil,
+		func(database *repodb.Database) error {
+			database.Add(&repodb.Entry{Name: "gone-bin", Version: "1.0-1"})
+			database.Add(&repodb.Entry{Name: "other", Version: "1.0-1"})
+
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queued := proto.BuildStatusQueued.String()
+	processing := proto.BuildStatusProcessing.String()
+	superseded := proto.BuildStatusSuperseded.String()
+
+	packages := []proto.Package{
+		{Name: "foo", Status: queued},
+		{
+			Name:   "bar-git",
+			Status: queued,
+			Repositories: map[string]proto.PackageRepository{
+				"aurora": {Archive: "1600000000.bar-git-2.1-1-x86_64.pkg.tar.zst"},
+			},
+		},
+		{Name: "busy", Status: processing},
+		{
+			Name:   "sh-static",
+			Status: queued,
+			Repositories: map[string]proto.PackageRepository{
+				"aurora": {Archive: "1600000000.sh-static-1.0-1-x86_64.pkg.tar.zst"},
+			},
+		},
+		{
+			Name:              "old",
+			Status:            superseded,
+			Superseded:        "extra/old 1.0-1",
+			SupersededAt:      time.Now().Add(-time.Hour * 48),
+			SupersededRemoved: true,
+		},
+		{
+			Name:         "gone",
+			Status:       superseded,
+			Superseded:   "extra/gone 1.0-1",
+			SupersededAt: time.Now().Add(-time.Hour * 48),
+			Repositories: map[string]proto.PackageRepository{
+				"aurora": {Archive: "1600000000.gone-bin-1.0-1-x86_64.pkg.tar.zst"},
+			},
+		},
+	}
+
+	for _, pkg := range packages {
+		err := collections.Packages.Insert(pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archives := []proto.ArchiveMetadata{
+		{
+			Filename: "1600000000.bar-git-2.1-1-x86_64.pkg.tar.zst",
+			Package:  "bar-git",
+			Name:     "bar-git",
+			Provides: []string{"bar=2.1"},
+		},
+		{
+			Filename: "1600000000.sh-static-1.0-1-x86_64.pkg.tar.zst",
+			Package:  "sh-static",
+			Name:     "sh-static",
+			Provides: []string{"sh"},
+		},
+	}
+
+	for _, archive := range archives {
+		err := collections.Archives.Insert(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = proc.checkOfficial(newOfficialClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(name string) proto.Package {
+		var pkg proto.Package
+		err := collections.Packages.Find(bson.M{"name": name}).One(&pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return pkg
+	}
+
+	foo := get("foo")
+	test.Equal("extra/foo 2.0-1", foo.Superseded)
+	test.Equal(superseded, foo.Status)
+	test.False(foo.SupersededAt.IsZero())
+
+	bar := get("bar-git")
+	test.Equal("extra/bar 2.0-1", bar.Superseded)
+	test.Equal(superseded, bar.Status)
+
+	busy := get("busy")
+	test.Equal("extra/busy 2.0-1", busy.Superseded)
+	test.Equal(processing, busy.Status)
+
+	sh := get("sh-static")
+	test.Empty(sh.Superseded)
+	test.Equal(queued, sh.Status)
+
+	old := get("old")
+	test.Empty(old.Superseded)
+	test.True(old.SupersededAt.IsZero())
+	test.False(old.SupersededRemoved)
+	test.Equal(queued, old.Status)
+	test.True(old.Rebuild)
+
+	gone := get("gone")
+	test.Equal("extra/gone 2.0-1", gone.Superseded)
+	test.True(gone.SupersededRemoved)
+	test.Empty(gone.Repositories)
+
+	entries, err := repodb.ReadFile(repodb.Path(proc.repoDir, "aurora"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test.Len(entries, 1)
+	test.Equal("other", entries[0].Name)
+}
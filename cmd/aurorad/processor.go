@@ -1,15 +1,10 @@
 package main
 
 import (
-	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
-	"strconv"
-	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/globalsign/mgo"
@@ -25,31 +20,42 @@ type Processor struct {
 	logsDir   string
 	pool      *threadpool.ThreadPool
 
-	storage *mgo.Collection
-	cloud   *Cloud
-	config  *Config
-	bus     *Bus
+	storage  *mgo.Collection
+	builds   *mgo.Collection
+	patches  *mgo.Collection
+	sources  *mgo.Collection
+	recipes  *mgo.Collection
+	archives *mgo.Collection
+	files    *mgo.Collection
+	cloud    *Cloud
+	keyring  *Keyring
+	signer   *Signer
+	config   *Config
+
+	classifier *FailureClassifier
+	bus        *Bus
 }
 
 func NewProcessor(
-	storage *mgo.Collection,
+	collections *Collections,
 	config *Config,
 	bus *Bus,
 ) *Processor {
 	return &Processor{
-		storage: storage,
-		config:  config,
-		bus:     bus,
+		storage:  collections.Packages,
+		builds:   collections.Builds,
+		patches:  collections.Patches,
+		sources:  collections.Sources,
+		recipes:  collections.Recipes,
+		archives: collections.Archives,
+		files:    collections.ArchiveFiles,
+		config:   config,
+		bus:      bus,
 	}
 }
 
 func (proc *Processor) Init() error {
-	err := proc.removeLock()
-	if err != nil {
-		return err
-	}
-
-	err = cleanupQueue(proc.config.Instance, proc.storage)
+	err := cleanupQueue(proc.config.Instance, proc.storage)
 	if err != nil {
 		return karma.Format(
 			err,
@@ -62,7 +68,7 @@ func (proc *Processor) Init() error {
 		return err
 	}
 
-	proc.cloud, err = NewCloud(proc.config.BaseImage, proc.config.Resources, proc.config.Threads)
+	proc.cloud, err = NewCloud(proc.config.Resources, proc.config.Threads)
 	if err != nil {
 		return karma.Format(
 			err,
@@ -78,6 +84,44 @@ func (proc *Processor) Init() error {
 		)
 	}
 
+	if proc.config.Keyring.Dir != "" {
+		proc.keyring, err = NewKeyring(proc.config.Keyring)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to init keyring",
+			)
+		}
+	}
+
+	if proc.config.Signing.Key != "" {
+		proc.signer, err = NewSigner(proc.config.Signing)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to init signer",
+			)
+		}
+
+		infof("signing packages using key %s", proc.signer.Fingerprint())
+	}
+
+	err = proc.initDatabases()
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to init repository databases",
+		)
+	}
+
+	proc.classifier, err = NewFailureClassifier(proc.config.Failures.Patterns)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to init failure classifier",
+		)
+	}
+
 	proc.pool = spawnThreadpool(proc.config.Instance, proc.config.Threads)
 
 	return nil
@@ -89,6 +133,42 @@ func (proc *Processor) Process() {
 
 	go proc.loopBuild(loops.Done)
 
+	if proc.config.Image.Refresh > 0 {
+		loops.Add(1)
+
+		go proc.loopImage(loops.Done)
+	}
+
+	if proc.config.AUR.Interval > 0 {
+		loops.Add(1)
+
+		go proc.loopAUR(loops.Done)
+	}
+
+	if proc.config.Official.Interval > 0 {
+		loops.Add(1)
+
+		go proc.loopOfficial(loops.Done)
+	}
+
+	if len(proc.config.Repositories) > 1 {
+		loops.Add(1)
+
+		go proc.loopPromote(loops.Done)
+	}
+
+	if proc.config.Snapshots.Interval > 0 {
+		loops.Add(1)
+
+		go proc.loopSnapshots(loops.Done)
+	}
+
+	if proc.config.Retention.Interval > 0 {
+		loops.Add(1)
+
+		go proc.loopRetention(loops.Done)
+	}
+
 	loops.Wait()
 }
 
@@ -97,6 +177,19 @@ func (proc *Processor) loopBuild(done func()) {
 	for {
 		pkg := proto.Package{}
 
+		images := map[string]*Image{}
+		for _, arch := range proc.config.architectureNames() {
+			name := proc.config.image(arch)
+
+			image, err := proc.cloud.InspectImage(name)
+			if err != nil {
+				errorh(err, "unable to inspect image %s", name)
+				continue
+			}
+
+			images[arch] = image
+		}
+
 		iterator := proc.storage.
 			Find(bson.M{}).
 			Sort("-priority").
@@ -109,6 +202,11 @@ func (proc *Processor) loopBuild(done func()) {
 
 			since = time.Since(pkg.Date)
 
+			if pkg.Hold != "" {
+				tracef("skip package %s on hold: %s", pkg.Name, pkg.Hold)
+				continue
+			}
+
 			// uh? looks ugly
 			switch pkg.Status {
 			case proto.BuildStatusProcessing.String():
@@ -122,9 +220,35 @@ func (proc *Processor) loopBuild(done func()) {
 			case proto.BuildStatusFailure.String():
 				interval = proc.config.Interval.Build.StatusFailure
 				canSkip = true
+
+			// approve or reject queues the package again
+			case proto.BuildStatusAwaitingReview.String():
+				tracef("skip package %s awaiting review", pkg.Name)
+				continue
+
+			case proto.BuildStatusSuperseded.String():
+				tracef("skip package %s superseded by %s", pkg.Name, pkg.Superseded)
+				continue
+			}
+
+			// packages are built for other architectures using the image
+			// of the first one
+			arch := getArchitectures(pkg, proc.config.architectureNames())[0]
+
+			outdated := proc.isImageOutdated(pkg, images[arch])
+			if outdated {
+				infof(
+					"package %s was built using outdated image %s created at %s",
+					pkg.Name, pkg.ImageDigest, pkg.ImageCreated,
+				)
 			}
 
-			if canSkip && since < interval {
+			// requested rebuild doesn't wait for the interval, but the
+			// running build is not duplicated
+			force := (outdated || pkg.Rebuild) &&
+				pkg.Status != proto.BuildStatusProcessing.String()
+
+			if canSkip && since < interval && !force {
 				tracef(
 					"skip package %s in status %s: "+
 						"time since last build %v is less than %v",
@@ -141,12 +265,26 @@ func (proc *Processor) loopBuild(done func()) {
 					bus:           proc.bus,
 					instance:      proc.config.Instance,
 					cloud:         proc.cloud,
+					keyring:       proc.keyring,
+					signer:        proc.signer,
+					classifier:    proc.classifier,
 					storage:       proc.storage,
+					builds:        proc.builds,
+					patches:       proc.patches,
+					sources:       proc.sources,
+					recipes:       proc.recipes,
+					archives:      proc.archives,
+					files:         proc.files,
 					pkg:           pkg,
+					force:         outdated || pkg.Rebuild,
 					repoDir:       proc.repoDir,
+					repositories:  proc.config.repositoryNames(),
+					architectures: proc.config.architectureNames(),
+					baseImage:     proc.config.BaseImage,
 					bufferDir:     proc.bufferDir,
 					logsDir:       proc.logsDir,
 					configHistory: proc.config.History,
+					configVerify:  proc.config.Verify,
 				},
 			)
 		}
@@ -155,6 +293,23 @@ func (proc *Processor) loopBuild(done func()) {
 	}
 }
 
+func (proc *Processor) isImageOutdated(pkg proto.Package, image *Image) bool {
+	if proc.config.Image.MaxAge == 0 || image == nil {
+		return false
+	}
+
+	if pkg.ImageDigest == "" || pkg.ImageDigest == image.Digest {
+		return false
+	}
+
+	// failed and processing packages will be rebuilt anyway
+	if pkg.Status != proto.BuildStatusSuccess.String() {
+		return false
+	}
+
+	return time.Since(pkg.ImageCreated) > proc.config.Image.MaxAge
+}
+
 func spawnThreadpool(instance string, size int) *threadpool.ThreadPool {
 	capacity := size
 	if capacity == 0 {
@@ -241,67 +396,3 @@ func cleanupQueue(instance string, storage *mgo.Collection) error {
 
 	return nil
 }
-
-func (proc *Processor) removeLock() error {
-	path := filepath.Join(proc.config.RepoDir, packagesDatabaseFile+".lck")
-
-	infof("ensuring database lock file does not exist: %s", path)
-
-	raw, err := ioutil.ReadFile(path)
-	if err != nil {
-		// That's best case that lck file is not held by something
-		if os.IsNotExist(err) {
-			return nil
-		}
-
-		return karma.Format(
-			err,
-			"unable to open %s", path,
-		)
-	}
-
-	warningf("database lock file exists: %s", path)
-
-	pid, err := strconv.Atoi(strings.TrimSpace(string(raw)))
-	if err != nil {
-		return karma.
-			Describe("path", path).
-			Format(
-				err,
-				"unexpected content in lck file: %q", string(raw),
-			)
-	}
-
-	warningf("database lock pid: %d", pid)
-
-	process, err := os.FindProcess(pid)
-	if err != nil {
-		return karma.Format(
-			err,
-			"unable to find process %d", pid,
-		)
-	}
-
-	defer process.Release()
-
-	err = process.Signal(syscall.Signal(0))
-	if err == nil {
-		// process found, we can't remove lock
-		return fmt.Errorf("process %d that locked %s is still running", pid, path)
-	}
-
-	warningf("database lock process is not running: %d", pid)
-
-	err = os.Remove(path)
-	if err != nil {
-		return karma.Format(
-			err,
-			"unable to remove lck file: %s",
-			path,
-		)
-	}
-
-	warningf("database lock file has been removed: %s", path)
-
-	return nil
-}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/reconquest/karma-go"
+)
+
+const promoteInterval = time.Minute
+
+func (proc *Processor) loopPromote(done func()) {
+	defer done()
+
+	for {
+		err := proc.checkPromotions()
+		if err != nil {
+			errorh(err, "unable to check promotions")
+		}
+
+		time.Sleep(promoteInterval)
+	}
+}
+
+// checkPromotions promotes packages requested using aurora promote and
+// packages which have been soaking in a repository long enough.
+func (proc *Processor) checkPromotions() error {
+	var packages []proto.Package
+	err := proc.storage.Find(
+		bson.M{"repositories": bson.M{"$exists": true}},
+	).All(&packages)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find published packages",
+		)
+	}
+
+	for _, pkg := range packages {
+		from, to, err := proc.getPromotion(pkg)
+		if err != nil {
+			errorh(err, "unable to check promotion of %s", pkg.Name)
+			continue
+		}
+
+		if to == "" {
+			continue
+		}
+
+		err = proc.promote(pkg, from, to)
+		if err != nil {
+			errorh(err, "unable to promote %s from %s to %s", pkg.Name, from, to)
+
+			proc.bus.Publish(
+				pkg.Name,
+				fmt.Sprintf("promote: unable to promote from %s to %s: %s\n", from, to, err),
+			)
+		}
+	}
+
+	return nil
+}
+
+func (proc *Processor) getPromotion(pkg proto.Package) (string, string, error) {
+	if pkg.Promote != "" {
+		from, to, err := rpc.FindPromotion(
+			proc.config.repositoryNames(), pkg, pkg.Promote,
+		)
+		if err != nil {
+			// the request is not valid anymore, e.g. the package has been
+			// removed from the repository
+			unsetErr := proc.storage.Update(
+				bson.M{"name": pkg.Name},
+				bson.M{"$unset": bson.M{"promote": ""}},
+			)
+			if unsetErr != nil {
+				return "", "", unsetErr
+			}
+
+			return "", "", err
+		}
+
+		return from, to, nil
+	}
+
+	from, to := getAutoPromotion(proc.config.Repositories, pkg, time.Now())
+	if to == "" {
+		return "", "", nil
+	}
+
+	failures, err := proc.builds.Find(bson.M{
+		"package": pkg.Name,
+		"status":  proto.BuildStatusFailure.String(),
+		"started": bson.M{"$gt": pkg.Repositories[from].Published},
+	}).Count()
+	if err != nil {
+		return "", "", karma.Format(
+			err,
+			"unable to count failed builds",
+		)
+	}
+
+	if failures > 0 {
+		return "", "", nil
+	}
+
+	return from, to, nil
+}
+
+// getAutoPromotion returns repositories which the package should be promoted
+// from and to automatically, empty strings are returned if the package
+// shouldn't be promoted.
+func getAutoPromotion(
+	repositories []ConfigRepository,
+	pkg proto.Package,
+	now time.Time,
+) (string, string) {
+	if pkg.Failures > 0 {
+		return "", ""
+	}
+
+	for i := 1; i < len(repositories); i++ {
+		from, to := repositories[i-1].Name, repositories[i]
+		if to.PromoteAfter <= 0 {
+			continue
+		}
+
+		published, ok := pkg.Repositories[from]
+		if !ok || published.Archive == pkg.Repositories[to.Name].Archive {
+			continue
+		}
+
+		if now.Sub(published.Published) < to.PromoteAfter {
+			continue
+		}
+
+		return from, to.Name
+	}
+
+	return "", ""
+}
+
+func (proc *Processor) promote(pkg proto.Package, from string, to string) error {
+	published := pkg.Repositories[from]
+
+	architectures := proc.config.architectureNames()
+
+	entries, err := findPromotedEntries(
+		proc.repoDir, from, architectures, published.Archive,
+	)
+	if err != nil {
+		return err
+	}
+
+	for arch, entry := range entries {
+		entry := entry
+
+		err := repodb.Update(
+			repodb.ArchPath(proc.repoDir, to, architectures, arch),
+			proc.signer.sign(),
+			func(database *repodb.Database) error {
+				database.Add(entry)
+				return nil
+			},
+		)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to update repository %s for %s", to, arch,
+			)
+		}
+	}
+
+	published.Published = time.Now()
+
+	err = proc.storage.Update(
+		bson.M{"name": pkg.Name},
+		bson.M{
+			"$set":   bson.M{"repositories." + to: published},
+			"$unset": bson.M{"promote": ""},
+		},
+	)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to update published versions of package",
+		)
+	}
+
+	infof("promote: %s %s has been promoted from %s to %s", pkg.Name, published.Version, from, to)
+
+	proc.bus.Publish(
+		pkg.Name,
+		fmt.Sprintf("promote: %s has been promoted from %s to %s\n", published.Version, from, to),
+	)
+
+	return nil
+}
+
+// findPromotedEntries returns entries of the published archive in databases
+// of the repository for all architectures, archives of all architectures are
+// promoted together. Entries are found by the name of the published archive
+// since it's not the name of the package when the package is cloned from
+// custom URL or pushed.
+func findPromotedEntries(
+	repoDir string,
+	repository string,
+	architectures []string,
+	archive string,
+) (map[string]*repodb.Entry, error) {
+	databases := map[string][]*repodb.Entry{}
+	name := ""
+	for _, arch := range architectures {
+		database, err := repodb.Open(
+			repodb.ArchPath(repoDir, repository, architectures, arch),
+		)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to open repository %s for %s", repository, arch,
+			)
+		}
+
+		databases[arch] = database.Entries()
+
+		database.Close()
+
+		for _, entry := range databases[arch] {
+			if entry.Filename == archive {
+				name = entry.Name
+			}
+		}
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf(
+			"archive %s is not published to repository %s", archive, repository,
+		)
+	}
+
+	found := map[string]*repodb.Entry{}
+	for arch, entries := range databases {
+		for _, entry := range entries {
+			if entry.Name == name {
+				found[arch] = entry
+			}
+		}
+	}
+
+	return found, nil
+}
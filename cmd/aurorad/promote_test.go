@@ -0,0 +1,155 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAutoPromotion(t *testing.T) {
+	test := assert.New(t)
+
+	now := time.Unix(1600000000, 0)
+
+	repositories := []ConfigRepository{
+		{Name: "testing"},
+		{Name: "stable", PromoteAfter: time.Hour},
+		{Name: "frozen"},
+	}
+
+	published := func(archive string, ago time.Duration) proto.PackageRepository {
+		return proto.PackageRepository{Archive: archive, Published: now.Add(-ago)}
+	}
+
+	testcases := []struct {
+		Name    string
+		Package proto.Package
+		From    string
+		To      string
+	}{
+		{
+			"not published",
+			proto.Package{},
+			"", "",
+		},
+		{
+			"soaking",
+			proto.Package{Repositories: map[string]proto.PackageRepository{
+				"testing": published("b", time.Minute),
+			}},
+			"", "",
+		},
+		{
+			"soaked",
+			proto.Package{Repositories: map[string]proto.PackageRepository{
+				"testing": published("b", time.Hour*2),
+				"stable":  published("a", time.Hour*24),
+			}},
+			"testing", "stable",
+		},
+		{
+			"failing",
+			proto.Package{
+				Failures: 1,
+				Repositories: map[string]proto.PackageRepository{
+					"testing": published("b", time.Hour*2),
+				},
+			},
+			"", "",
+		},
+		{
+			"already promoted",
+			proto.Package{Repositories: map[string]proto.PackageRepository{
+				"testing": published("b", time.Hour*2),
+				"stable":  published("b", time.Hour),
+			}},
+			"", "",
+		},
+	}
+
+	for _, testcase := range testcases {
+		from, to := getAutoPromotion(repositories, testcase.Package, now)
+		test.Equal(testcase.From, from, testcase.Name)
+		test.Equal(testcase.To, to, testcase.Name)
+	}
+}
+
+func TestValidateRepositories(t *testing.T) {
+	test := assert.New(t)
+
+	config := &Config{}
+	test.NoError(validateRepositories(config))
+	test.Equal([]string{"aurora"}, config.repositoryNames())
+
+	config = &Config{Repositories: []ConfigRepository{{Name: "testing"}, {Name: "testing"}}}
+	test.Error(validateRepositories(config))
+
+	config = &Config{Repositories: []ConfigRepository{{Name: "../etc"}}}
+	test.Error(validateRepositories(config))
+
+	config = &Config{Repositories: []ConfigRepository{{Name: "testing", PromoteAfter: time.Hour}}}
+	test.Error(validateRepositories(config))
+}
+
+func TestFindPromotedEntries(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "aurora-promote")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	architectures := []string{"x86_64", "aarch64"}
+
+	add := func(arch string, entries ...*repodb.Entry) {
+		err := repodb.Update(
+			repodb.ArchPath(dir, "aurora", architectures, arch),
+			nil,
+			func(database *repodb.Database) error {
+				for _, entry := range entries {
+					database.Add(entry)
+				}
+
+				return nil
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the package is cloned from custom URL, so pkgname of its archives
+	// differs from the name of the package
+	add(
+		"x86_64",
+		&repodb.Entry{Name: "bar", Version: "1-1", Filename: "2.bar-1-1-x86_64.pkg.tar.zst"},
+		&repodb.Entry{Name: "foo-git", Version: "1-1", Filename: "1.foo-git-1-1-x86_64.pkg.tar.zst"},
+	)
+	add(
+		"aarch64",
+		&repodb.Entry{Name: "foo-git", Version: "1-1", Filename: "1.foo-git-1-1-aarch64.pkg.tar.zst"},
+	)
+
+	entries, err := findPromotedEntries(
+		dir, "aurora", architectures, "1.foo-git-1-1-x86_64.pkg.tar.zst",
+	)
+	test.NoError(err)
+	test.Len(entries, 2)
+	test.Equal("1.foo-git-1-1-x86_64.pkg.tar.zst", entries["x86_64"].Filename)
+	test.Equal("1.foo-git-1-1-aarch64.pkg.tar.zst", entries["aarch64"].Filename)
+
+	_, err = findPromotedEntries(
+		dir, "aurora", architectures, "1.foo-1-1-x86_64.pkg.tar.zst",
+	)
+	test.EqualError(
+		err,
+		"archive 1.foo-1-1-x86_64.pkg.tar.zst is not published to repository aurora",
+	)
+}
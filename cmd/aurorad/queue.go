@@ -1,19 +1,28 @@
 package main
 
 import (
+	"expvar"
 	"net/http"
 
-	"github.com/globalsign/mgo"
+	"github.com/kovetskiy/aurora/pkg/rpc"
 	"github.com/reconquest/karma-go"
 )
 
-func processQueue(storage *mgo.Collection, config *Config) error {
+func processQueue(collections *Collections, config *Config) error {
 	bus := NewBus()
 
-	processor := NewProcessor(storage, config, bus)
-	busServer := NewBusServer(bus)
+	auth, err := rpc.NewAuthService(config.AuthorizedKeysDir)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to initialize AuthService",
+		)
+	}
+
+	processor := NewProcessor(collections, config, bus)
+	busServer := NewBusServer(bus, auth)
 
-	err := processor.Init()
+	err = processor.Init()
 	if err != nil {
 		return karma.Format(
 			err,
@@ -25,7 +34,7 @@ func processQueue(storage *mgo.Collection, config *Config) error {
 	if err != nil {
 		return karma.Format(
 			err,
-			"unable to cleanup storage",
+			"unable to apply retention",
 		)
 	}
 
@@ -33,7 +42,11 @@ func processQueue(storage *mgo.Collection, config *Config) error {
 
 	infof("starting bus server at %s", config.Bus.Listen)
 
-	err = http.ListenAndServe(config.Bus.Listen, busServer)
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/", busServer)
+
+	err = http.ListenAndServe(config.Bus.Listen, mux)
 	if err != nil {
 		return karma.Format(
 			err,
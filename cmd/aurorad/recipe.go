@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/reconquest/karma-go"
+)
+
+// captureRecipe stores PKGBUILD, .SRCINFO and install scripts copied by
+// prepare.sh into the buffer directory, so recipes of builds can be compared.
+func (build *build) captureRecipe() error {
+	dir := filepath.Join(build.bufferDir, build.pkg.Name)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "recipe", "*"))
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to list recipe files",
+		)
+	}
+
+	paths = append(paths, filepath.Join(dir, ".SRCINFO"))
+
+	build.record.Recipe, err = build.putRecipe(paths)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// putRecipe stores given recipe files and returns them sorted by name.
+func (build *build) putRecipe(paths []string) ([]proto.RecipeFile, error) {
+	recipe := []proto.RecipeFile{}
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to read recipe file: %s", path,
+			)
+		}
+
+		file, err := rpc.PutRecipe(
+			build.recipes,
+			filepath.Base(path),
+			string(contents),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		recipe = append(recipe, file)
+	}
+
+	sort.Slice(recipe, func(i, j int) bool {
+		return recipe[i].Name < recipe[j].Name
+	})
+
+	return recipe, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/reconquest/karma-go"
+)
+
+// updateDatabases calls given function for databases of all architectures
+// of given repository.
+func (proc *Processor) updateDatabases(
+	repository string,
+	update func(*repodb.Database) error,
+) error {
+	architectures := proc.config.architectureNames()
+
+	for _, arch := range architectures {
+		err := repodb.Update(
+			repodb.ArchPath(proc.repoDir, repository, architectures, arch),
+			proc.signer.sign(),
+			update,
+		)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to update repository %s for %s", repository, arch,
+			)
+		}
+	}
+
+	return nil
+}
+
+// initDatabases creates databases of repositories which don't exist yet and
+// .files databases of repositories written by repo-add before, so pacman -Sy
+// and pacman -Fy work for every configured repository and architecture.
+func (proc *Processor) initDatabases() error {
+	architectures := proc.config.architectureNames()
+
+	for _, repository := range proc.config.repositoryNames() {
+		for _, arch := range architectures {
+			path := repodb.ArchPath(proc.repoDir, repository, architectures, arch)
+
+			_, err := os.Stat(repodb.FilesPath(path))
+			if err == nil {
+				continue
+			}
+			if !os.IsNotExist(err) {
+				return err
+			}
+
+			infof("creating .files database of repository %s for %s", repository, arch)
+
+			err = repodb.Update(
+				path,
+				proc.signer.sign(),
+				func(database *repodb.Database) error {
+					for _, entry := range database.Entries() {
+						if len(entry.Files) > 0 {
+							continue
+						}
+
+						archive := filepath.Join(proc.repoDir, entry.Filename)
+
+						read, err := repodb.ReadPackage(archive)
+						if err != nil {
+							logger.Error(
+								karma.Format(
+									err,
+									"unable to read files of %s", entry.Filename,
+								),
+							)
+							continue
+						}
+
+						database.Add(read)
+					}
+
+					return nil
+				},
+			)
+			if err != nil {
+				return karma.Format(
+					err,
+					"unable to update repository %s for %s", repository, arch,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// repoRemove removes the package from all repositories.
+func (proc *Processor) repoRemove(pkg proto.Package) error {
+	// entries are named by pkgname of archives which could differ from the
+	// name of the package
+	name := pkg.ArchiveName()
+
+	for _, repository := range proc.config.repositoryNames() {
+		err := proc.updateDatabases(
+			repository,
+			func(database *repodb.Database) error {
+				database.Remove(name)
+
+				return nil
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := proc.storage.Update(
+		bson.M{"name": pkg.Name},
+		bson.M{"$unset": bson.M{"repositories": ""}},
+	)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to update published versions of packages",
+		)
+	}
+
+	return nil
+}
+
+// repoRemoveArchive removes entries referring to given archive from all
+// repositories, the package could have been rebuilt already, so entries of
+// other archives are kept.
+func (proc *Processor) repoRemoveArchive(archive string) error {
+	for _, repository := range proc.config.repositoryNames() {
+		removed := []string{}
+
+		err := proc.updateDatabases(
+			repository,
+			func(database *repodb.Database) error {
+				for _, entry := range database.Entries() {
+					if entry.Filename == archive {
+						database.Remove(entry.Name)
+						removed = append(removed, entry.Name)
+					}
+				}
+
+				return nil
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		if len(removed) == 0 {
+			continue
+		}
+
+		_, err = proc.storage.UpdateAll(
+			bson.M{
+				"name": bson.M{"$in": removed},
+				"repositories." + repository + ".archive": archive,
+			},
+			bson.M{"$unset": bson.M{"repositories." + repository: ""}},
+		)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to update published versions of packages",
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/pkgtar"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+const (
+	// reproducibleBufferDir is a directory in the buffer directory where
+	// the second build of reproducibility check puts its files.
+	reproducibleBufferDir = ".reproducible"
+
+	// maxReproducibleDiff limits number of differences stored in the build
+	// record.
+	maxReproducibleDiff = 100
+)
+
+// checkReproducible builds the package second time in a separate container
+// and compares resulting archive with the published one file by file. The
+// verdict is stored in the build record, it doesn't affect status of the
+// build.
+func (build *build) checkReproducible(archive string) {
+	build.bus.Publish(build.pkg.Name, "builder: Checking reproducibility\n")
+
+	diff, err := build.reproduce(archive)
+	if err != nil {
+		build.log.Error(
+			karma.Format(
+				err, "unable to check reproducibility",
+			),
+		)
+
+		build.record.Reproducible = proto.ReproducibleUnknown
+		build.record.ReproducibleDiff = []string{err.Error()}
+	} else if len(diff) > 0 {
+		if len(diff) > maxReproducibleDiff {
+			diff = append(
+				diff[:maxReproducibleDiff],
+				fmt.Sprintf("... and %d more", len(diff)-maxReproducibleDiff),
+			)
+		}
+
+		build.record.Reproducible = proto.ReproducibleNo
+		build.record.ReproducibleDiff = diff
+	} else {
+		build.record.Reproducible = proto.ReproducibleYes
+		build.record.ReproducibleDiff = nil
+	}
+
+	build.log.Infof("reproducibility: %s", build.record.Reproducible)
+
+	build.bus.Publish(
+		build.pkg.Name,
+		fmt.Sprintf("builder: Package is %s\n", build.record.Reproducible),
+	)
+
+	build.updateRecord()
+}
+
+// reproduce runs the second build and returns differences between given
+// archive and the archive of the second build.
+func (build *build) reproduce(archive string) ([]string, error) {
+	bufferDir := filepath.Join(build.bufferDir, reproducibleBufferDir)
+
+	err := os.RemoveAll(filepath.Join(bufferDir, build.pkg.Name))
+	if err != nil {
+		return nil, karma.Format(
+			err, "unable to clean up buffer directory",
+		)
+	}
+
+	defer func() {
+		err := os.RemoveAll(filepath.Join(bufferDir, build.pkg.Name))
+		if err != nil {
+			build.log.Error(
+				karma.Format(
+					err, "unable to clean up buffer directory",
+				),
+			)
+		}
+	}()
+
+	err = build.prepareSource(bufferDir)
+	if err != nil {
+		return nil, karma.Format(
+			err, "can't prepare source",
+		)
+	}
+
+	patches, err := build.preparePatches(bufferDir)
+	if err != nil {
+		return nil, karma.Format(
+			err, "can't prepare patches",
+		)
+	}
+
+	if !reflect.DeepEqual(patches, build.record.Patches) {
+		return nil, errors.New("patches have been changed since the first build")
+	}
+
+	name := build.container + "-reproducible"
+
+	build.log.Debugf("creating container %s", name)
+
+	container, err := build.cloud.CreateContainer(
+		build.image(build.arch),
+		bufferDir,
+		name,
+		build.getPinnedEnv(),
+		build.getBinds(),
+	)
+	if err != nil {
+		return nil, karma.Format(
+			err, "can't create container",
+		)
+	}
+
+	defer func() {
+		err := build.cloud.DestroyContainer(container)
+		if err != nil {
+			build.log.Error(
+				karma.Format(
+					err, "can't destroy container %s", name,
+				),
+			)
+		}
+	}()
+
+	err = build.cloud.StartContainer(container)
+	if err != nil {
+		return nil, karma.Format(
+			err, "can't start container",
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*30)
+	defer cancel()
+
+	publish := func(log string) {
+		build.bus.Publish(build.pkg.Name, "reproducible: "+log)
+	}
+
+	for _, script := range []string{
+		"/app/clone.sh",
+		"/app/prepare.sh",
+		"/app/pkgver.sh",
+		"/app/run.sh",
+	} {
+		err = build.cloud.Exec(
+			ctx, build.log, publish,
+			container, []string{script}, nil,
+		)
+		if err != nil {
+			return nil, karma.Format(err, "%s failed", filepath.Base(script))
+		}
+	}
+
+	second, err := findReproducedArchive(
+		filepath.Join(bufferDir, build.pkg.Name),
+		filepath.Base(archive),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return pkgtar.Compare(archive, second)
+}
+
+// findReproducedArchive finds archive with the same name as the given one,
+// names of archives in buffer are prefixed with time of the build.
+func findReproducedArchive(dir string, basename string) (string, error) {
+	name := trimArchiveTime(basename)
+
+	archives, err := filepath.Glob(filepath.Join(dir, "*.pkg.*"))
+	if err != nil {
+		return "", karma.Format(
+			err, "can't stat built package archive",
+		)
+	}
+
+	for _, archive := range archives {
+		if trimArchiveTime(filepath.Base(archive)) == name {
+			return archive, nil
+		}
+	}
+
+	return "", fmt.Errorf("second build didn't produce archive %s", name)
+}
+
+func trimArchiveTime(basename string) string {
+	parts := strings.SplitN(basename, ".", 2)
+	if len(parts) < 2 {
+		return basename
+	}
+
+	return parts[1]
+}
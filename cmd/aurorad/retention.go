@@ -0,0 +1,449 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/reconquest/karma-go"
+)
+
+// retentionMetrics counts archives, logs, build records and buffers removed
+// by retention, metrics are served at /debug/vars of the bus server.
+var retentionMetrics = expvar.NewMap("retention")
+
+// expiredItem is a file or a record which should be removed by retention.
+type expiredItem struct {
+	Package string
+	Name    string
+	Reason  string
+}
+
+func (proc *Processor) loopRetention(done func()) {
+	defer done()
+
+	for {
+		time.Sleep(proc.config.Retention.Interval)
+
+		err := proc.Cleanup()
+		if err != nil {
+			errorh(err, "unable to apply retention")
+		}
+	}
+}
+
+// Cleanup removes archives, logs, build records and buffers according to
+// retention policies, every removed item is reported on the bus of its
+// package.
+func (proc *Processor) Cleanup() error {
+	now := time.Now()
+
+	for _, policy := range []struct {
+		kind   string
+		item   string
+		expire func(now time.Time) ([]expiredItem, error)
+		remove func(item expiredItem) error
+	}{
+		{"archives", "archive", proc.getExpiredArchives, proc.removeExpiredArchive},
+		{"logs", "log", proc.getExpiredLogs, proc.removeExpiredLog},
+		{"builds", "build", proc.getExpiredBuilds, proc.removeExpiredBuild},
+		{"buffers", "buffer", proc.getExpiredBuffers, proc.removeExpiredBuffer},
+	} {
+		items, err := policy.expire(now)
+		if err != nil {
+			retentionMetrics.Add("errors", 1)
+
+			return karma.Format(
+				err,
+				"unable to find expired %s", policy.kind,
+			)
+		}
+
+		removed := 0
+		for _, item := range items {
+			err := policy.remove(item)
+			if err != nil {
+				retentionMetrics.Add("errors", 1)
+				logger.Error(err)
+				continue
+			}
+
+			infof(
+				"retention: removed %s %s | %s | %s",
+				policy.item, item.Name, item.Package, item.Reason,
+			)
+
+			if item.Package != "" {
+				proc.bus.Publish(
+					item.Package,
+					fmt.Sprintf(
+						"retention: Removed %s %s (%s)\n",
+						policy.item, item.Name, item.Reason,
+					),
+				)
+			}
+
+			removed++
+		}
+
+		retentionMetrics.Add(policy.kind, int64(removed))
+
+		infof("retention: removed %d %s", removed, policy.kind)
+	}
+
+	lastRun := new(expvar.String)
+	lastRun.Set(now.Format(time.RFC3339))
+
+	retentionMetrics.Set("last_run", lastRun)
+
+	return nil
+}
+
+// getExpiredArchives returns archives of packages which don't exist anymore
+// and archives older than max age which are not published to any
+// repository, such archives are retained for rollback.
+func (proc *Processor) getExpiredArchives(now time.Time) ([]expiredItem, error) {
+	paths, err := filepath.Glob(filepath.Join(proc.repoDir, "*.pkg.*"))
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to glob for packages",
+		)
+	}
+
+	names := []string{}
+	for _, path := range paths {
+		// signatures are removed along with archives
+		if !strings.HasSuffix(path, ".sig") {
+			names = append(names, filepath.Base(path))
+		}
+	}
+
+	published, err := proc.getPublishedArchives()
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := proc.getPackageNames()
+	if err != nil {
+		return nil, err
+	}
+
+	return getExpiredArchives(
+		names, published, packages, now, proc.config.Retention.Archives,
+	), nil
+}
+
+func getExpiredArchives(
+	names []string,
+	published map[string]bool,
+	packages map[string]bool,
+	now time.Time,
+	policy ConfigRetentionPolicy,
+) []expiredItem {
+	expired := []expiredItem{}
+	for _, name := range names {
+		archive, ok := proto.ParseArchive(name)
+
+		switch {
+		case !ok:
+			expired = append(expired, expiredItem{"", name, "broken name"})
+
+		case published[name]:
+
+		case !packages[archive.Name]:
+			expired = append(expired, expiredItem{archive.Name, name, "package removed"})
+
+		case policy.MaxAge > 0 && now.Sub(archive.Built) > policy.MaxAge:
+			expired = append(expired, expiredItem{archive.Name, name, "too old"})
+		}
+	}
+
+	return expired
+}
+
+// getPublishedArchives returns archives referred by databases of all
+// repositories and architectures and by records of packages.
+func (proc *Processor) getPublishedArchives() (map[string]bool, error) {
+	published := map[string]bool{}
+
+	architectures := proc.config.architectureNames()
+	for _, repository := range proc.config.repositoryNames() {
+		for _, arch := range architectures {
+			path := repodb.ArchPath(proc.repoDir, repository, architectures, arch)
+
+			entries, err := repodb.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"unable to read repository %s for %s", repository, arch,
+				)
+			}
+
+			for _, entry := range entries {
+				published[entry.Filename] = true
+			}
+		}
+	}
+
+	var packages []proto.Package
+	err := proc.storage.Find(
+		bson.M{"repositories": bson.M{"$exists": true}},
+	).Select(bson.M{"repositories": 1}).All(&packages)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find published packages",
+		)
+	}
+
+	for _, pkg := range packages {
+		for _, record := range pkg.Repositories {
+			published[record.Archive] = true
+		}
+	}
+
+	return published, nil
+}
+
+// getPackageNames returns names of packages and pkgnames of their published
+// archives, they differ for packages cloned from custom URL or pushed.
+func (proc *Processor) getPackageNames() (map[string]bool, error) {
+	var packages []proto.Package
+	err := proc.storage.Find(bson.M{}).Select(
+		bson.M{"name": 1, "repositories": 1},
+	).All(&packages)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find packages in database",
+		)
+	}
+
+	names := map[string]bool{}
+	for _, pkg := range packages {
+		names[pkg.Name] = true
+		names[pkg.ArchiveName()] = true
+	}
+
+	return names, nil
+}
+
+func (proc *Processor) removeExpiredArchive(item expiredItem) error {
+	return proc.removeArchive(filepath.Join(proc.repoDir, item.Name))
+}
+
+// getExpiredLogs returns logs of last builds of packages which are older
+// than max age or exceed max count, logs are stored per package.
+func (proc *Processor) getExpiredLogs(now time.Time) ([]expiredItem, error) {
+	infos, err := ioutil.ReadDir(proc.logsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return getExpiredLogs(infos, now, proc.config.Retention.Logs), nil
+}
+
+func getExpiredLogs(
+	infos []os.FileInfo,
+	now time.Time,
+	policy ConfigRetentionPolicy,
+) []expiredItem {
+	logs := []os.FileInfo{}
+	for _, info := range infos {
+		if info.Mode().IsRegular() && proto.IsValidPackageName(info.Name()) {
+			logs = append(logs, info)
+		}
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].ModTime().After(logs[j].ModTime())
+	})
+
+	expired := []expiredItem{}
+	for i, log := range logs {
+		switch {
+		case policy.MaxAge > 0 && now.Sub(log.ModTime()) > policy.MaxAge:
+			expired = append(expired, expiredItem{log.Name(), log.Name(), "too old"})
+
+		case policy.MaxCount > 0 && i >= policy.MaxCount:
+			expired = append(expired, expiredItem{log.Name(), log.Name(), "too many logs"})
+		}
+	}
+
+	return expired
+}
+
+func (proc *Processor) removeExpiredLog(item expiredItem) error {
+	err := os.Remove(filepath.Join(proc.logsDir, item.Name))
+	if err != nil && !os.IsNotExist(err) {
+		return karma.Format(err, "unable to rm log of %s", item.Name)
+	}
+
+	return nil
+}
+
+// getExpiredBuilds returns records of builds older than max age, the latest
+// build of every package and builds awaiting review are kept.
+func (proc *Processor) getExpiredBuilds(now time.Time) ([]expiredItem, error) {
+	policy := proc.config.Retention.Builds
+	if policy.MaxAge == 0 {
+		return nil, nil
+	}
+
+	var latest []struct {
+		ID string `bson:"id"`
+	}
+	err := proc.builds.Pipe([]bson.M{
+		{"$sort": bson.M{"started": -1}},
+		{"$group": bson.M{"_id": "$package", "id": bson.M{"$first": "$_id"}}},
+	}).All(&latest)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find latest builds",
+		)
+	}
+
+	kept := []string{}
+	for _, build := range latest {
+		kept = append(kept, build.ID)
+	}
+
+	var pending []proto.Package
+	err = proc.storage.Find(
+		bson.M{"pending_build": bson.M{"$nin": []interface{}{nil, ""}}},
+	).Select(bson.M{"pending_build": 1}).All(&pending)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find packages awaiting review",
+		)
+	}
+
+	for _, pkg := range pending {
+		kept = append(kept, pkg.PendingBuild)
+	}
+
+	var builds []proto.Build
+	err = proc.builds.Find(bson.M{
+		"started": bson.M{"$lt": now.Add(-policy.MaxAge)},
+		"_id":     bson.M{"$nin": kept},
+	}).Select(bson.M{"package": 1}).All(&builds)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find expired builds",
+		)
+	}
+
+	expired := []expiredItem{}
+	for _, build := range builds {
+		expired = append(expired, expiredItem{build.Package, build.ID, "too old"})
+	}
+
+	return expired, nil
+}
+
+func (proc *Processor) removeExpiredBuild(item expiredItem) error {
+	err := proc.builds.RemoveId(item.Name)
+	if err != nil {
+		return karma.Format(err, "unable to remove build %s", item.Name)
+	}
+
+	return nil
+}
+
+// getExpiredBuffers returns directories of builds in buffer directory which
+// were not modified for max age, such directories are left by failed
+// builds. Directories of packages being built are kept.
+func (proc *Processor) getExpiredBuffers(now time.Time) ([]expiredItem, error) {
+	infos, err := ioutil.ReadDir(proc.bufferDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var packages []proto.Package
+	err = proc.storage.Find(
+		bson.M{"status": proto.BuildStatusProcessing.String()},
+	).Select(bson.M{"name": 1}).All(&packages)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find packages being built",
+		)
+	}
+
+	processing := map[string]bool{}
+	for _, pkg := range packages {
+		processing[pkg.Name] = true
+	}
+
+	return getExpiredBuffers(infos, processing, now, proc.config.Retention.Buffer), nil
+}
+
+func getExpiredBuffers(
+	infos []os.FileInfo,
+	processing map[string]bool,
+	now time.Time,
+	policy ConfigRetentionPolicy,
+) []expiredItem {
+	expired := []expiredItem{}
+	if policy.MaxAge == 0 {
+		return expired
+	}
+
+	for _, info := range infos {
+		if !info.IsDir() || !proto.IsValidPackageName(info.Name()) {
+			continue
+		}
+
+		if processing[info.Name()] || now.Sub(info.ModTime()) <= policy.MaxAge {
+			continue
+		}
+
+		expired = append(expired, expiredItem{info.Name(), info.Name(), "too old"})
+	}
+
+	return expired
+}
+
+func (proc *Processor) removeExpiredBuffer(item expiredItem) error {
+	err := os.RemoveAll(filepath.Join(proc.bufferDir, item.Name))
+	if err != nil {
+		return karma.Format(err, "unable to rm buffer of %s", item.Name)
+	}
+
+	return nil
+}
+
+func (proc *Processor) removeArchive(path string) error {
+	err := proc.repoRemoveArchive(filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return karma.Format(err, "unable to rm: %s", path)
+	}
+
+	err = os.Remove(path + ".sig")
+	if err != nil && !os.IsNotExist(err) {
+		return karma.Format(err, "unable to rm: %s.sig", path)
+	}
+
+	return rpc.RemoveArchive(proc.archives, proc.files, filepath.Base(path))
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetExpiredArchives(t *testing.T) {
+	test := assert.New(t)
+
+	now := time.Unix(1600000000, 0)
+
+	names := []string{
+		"1600000000.foo-2-1-x86_64.pkg.tar.zst",
+		"1599000000.foo-1-1-x86_64.pkg.tar.zst",
+		"1599000000.foo-1-1-aarch64.pkg.tar.zst",
+		"1590000000.foo-0-1-x86_64.pkg.tar.zst",
+		"1600000000.bar-1-1-x86_64.pkg.tar.zst",
+		"1600000000.baz-1-1-x86_64.pkg.tar.zst",
+		"foo-1-1-x86_64.pkg.tar.zst",
+	}
+
+	published := map[string]bool{
+		"1600000000.foo-2-1-x86_64.pkg.tar.zst":  true,
+		"1599000000.foo-1-1-aarch64.pkg.tar.zst": true,
+		// published archives are kept even if pkgname of the archive isn't
+		// known, e.g. it's published by previous versions
+		"1600000000.baz-1-1-x86_64.pkg.tar.zst": true,
+	}
+
+	packages := map[string]bool{"foo": true}
+
+	test.Equal(
+		[]expiredItem{
+			{"foo", "1599000000.foo-1-1-x86_64.pkg.tar.zst", "too old"},
+			{"foo", "1590000000.foo-0-1-x86_64.pkg.tar.zst", "too old"},
+			{"bar", "1600000000.bar-1-1-x86_64.pkg.tar.zst", "package removed"},
+			{"", "foo-1-1-x86_64.pkg.tar.zst", "broken name"},
+		},
+		getExpiredArchives(
+			names, published, packages, now,
+			ConfigRetentionPolicy{MaxAge: time.Hour * 96},
+		),
+	)
+
+	test.Equal(
+		[]expiredItem{
+			{"foo", "1590000000.foo-0-1-x86_64.pkg.tar.zst", "too old"},
+			{"bar", "1600000000.bar-1-1-x86_64.pkg.tar.zst", "package removed"},
+			{"", "foo-1-1-x86_64.pkg.tar.zst", "broken name"},
+		},
+		getExpiredArchives(
+			names, published, packages, now,
+			ConfigRetentionPolicy{MaxAge: time.Hour * 24 * 30},
+		),
+	)
+}
+
+func writeRetentionFiles(
+	t *testing.T,
+	dir string,
+	now time.Time,
+	ages map[string]time.Duration,
+) []os.FileInfo {
+	for name, age := range ages {
+		path := filepath.Join(dir, name)
+
+		err := ioutil.WriteFile(path, []byte(name), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.Chtimes(path, now.Add(-age), now.Add(-age))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return infos
+}
+
+func TestGetExpiredLogs(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	now := time.Unix(1600000000, 0)
+
+	infos := writeRetentionFiles(t, dir, now, map[string]time.Duration{
+		"foo":  time.Hour,
+		"bar":  time.Hour * 2,
+		"baz":  time.Hour * 3,
+		"qux":  time.Hour * 100,
+		".tmp": time.Hour * 100,
+	})
+
+	test.Empty(getExpiredLogs(infos, now, ConfigRetentionPolicy{}))
+
+	test.Equal(
+		[]expiredItem{{"qux", "qux", "too old"}},
+		getExpiredLogs(infos, now, ConfigRetentionPolicy{MaxAge: time.Hour * 96}),
+	)
+
+	test.Equal(
+		[]expiredItem{
+			{"baz", "baz", "too many logs"},
+			{"qux", "qux", "too old"},
+		},
+		getExpiredLogs(
+			infos, now,
+			ConfigRetentionPolicy{MaxAge: time.Hour * 96, MaxCount: 2},
+		),
+	)
+}
+
+func TestGetExpiredBuffers(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	now := time.Unix(1600000000, 0)
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		test.NoError(os.Mkdir(filepath.Join(dir, name), 0o755))
+	}
+
+	test.NoError(os.Chtimes(filepath.Join(dir, "foo"), now, now))
+
+	old := now.Add(-time.Hour * 48)
+	test.NoError(os.Chtimes(filepath.Join(dir, "bar"), old, old))
+	test.NoError(os.Chtimes(filepath.Join(dir, "baz"), old, old))
+
+	infos := writeRetentionFiles(t, dir, now, map[string]time.Duration{
+		"file": time.Hour * 48,
+	})
+
+	processing := map[string]bool{"baz": true}
+
+	test.Empty(getExpiredBuffers(infos, processing, now, ConfigRetentionPolicy{}))
+
+	test.Equal(
+		[]expiredItem{{"bar", "bar", "too old"}},
+		getExpiredBuffers(
+			infos, processing, now,
+			ConfigRetentionPolicy{MaxAge: time.Hour * 24},
+		),
+	)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/reconquest/karma-go"
+)
+
+var (
+	ErrAwaitingReview = errors.New("awaiting review")
+	ErrReviewRejected = errors.New("review-rejected")
+)
+
+// review stops the build if PKGBUILD or install scripts of a reviewed
+// package differ from the approved ones. Recipe of the first build is
+// trusted. Files are reviewed as they were cloned by clone.sh before patches
+// are applied and before PKGBUILD is sourced by makepkg.
+func (build *build) review() error {
+	if !build.pkg.Review {
+		return nil
+	}
+
+	paths, err := filepath.Glob(
+		filepath.Join(build.bufferDir, build.pkg.Name, "review", "*"),
+	)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to list cloned recipe files",
+		)
+	}
+
+	if len(paths) == 0 {
+		return errors.New("cloned recipe files not found")
+	}
+
+	recipe, err := build.putRecipe(paths)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to capture cloned recipe",
+		)
+	}
+
+	if len(build.pkg.ReviewedRecipe) == 0 {
+		build.log.Infof("trusting recipe on first use")
+
+		return build.updateReview(bson.M{"reviewed_recipe": recipe})
+	}
+
+	if proto.RecipeFilesEqual(recipe, build.pkg.ReviewedRecipe) {
+		return nil
+	}
+
+	if proto.RecipeFilesEqual(recipe, build.pkg.RejectedRecipe) {
+		return karma.Format(
+			ErrReviewRejected,
+			"recipe has been rejected by %s", build.pkg.ReviewedBy,
+		)
+	}
+
+	diff, err := rpc.RecipeDiff(build.recipes, build.pkg.ReviewedRecipe, recipe)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to compare recipe with reviewed one",
+		)
+	}
+
+	build.record.ReviewDiff = diff
+
+	build.bus.Publish(
+		build.pkg.Name,
+		fmt.Sprintf("builder: Recipe has been changed, awaiting review\n%s", diff),
+	)
+
+	err = build.updateReview(
+		bson.M{
+			"pending_recipe": recipe,
+			"pending_build":  build.record.ID,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return ErrAwaitingReview
+}
+
+// updateReview updates review fields of the package separately from status,
+// they are changed by reviewers as well.
+func (build *build) updateReview(set bson.M) error {
+	err := build.storage.Update(
+		bson.M{"name": build.pkg.Name},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to update review of package",
+		)
+	}
+
+	return nil
+}
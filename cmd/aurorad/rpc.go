@@ -1,15 +1,19 @@
 package main
 
 import (
+	"path/filepath"
+
 	jsonrpc "github.com/gorilla/rpc/v2"
 	"github.com/gorilla/rpc/v2/json2"
 	"github.com/kovetskiy/aurora/pkg/rpc"
 	"github.com/reconquest/karma-go"
-
-	"github.com/globalsign/mgo"
 )
 
-func NewRPCServer(collection *mgo.Collection, config *Config) (*jsonrpc.Server, error) {
+func NewRPCServer(
+	collections *Collections,
+	config *Config,
+	signer *Signer,
+) (*jsonrpc.Server, error) {
 	server := jsonrpc.NewServer()
 	server.RegisterCodec(json2.NewCodec(), "application/json")
 
@@ -22,14 +26,41 @@ func NewRPCServer(collection *mgo.Collection, config *Config) (*jsonrpc.Server,
 	}
 
 	pkg := rpc.NewPackageService(
-		collection,
 		auth,
-		config.LogsDir,
-		config.Instance,
+		rpc.PackageServiceConfig{
+			Packages:      collections.Packages,
+			Patches:       collections.Patches,
+			Builds:        collections.Builds,
+			Sources:       collections.Sources,
+			Recipes:       collections.Recipes,
+			Archives:      collections.Archives,
+			ArchiveFiles:  collections.ArchiveFiles,
+			LogsDir:       config.LogsDir,
+			Instance:      config.Instance,
+			Repositories:  config.repositoryNames(),
+			RepoDir:       config.RepoDir,
+			Sign:          signer.sign(),
+			Architectures: config.architectureNames(),
+		},
 	)
 
 	server.RegisterService(auth, "AuthService")
 	server.RegisterService(pkg, "PackageService")
+	server.RegisterService(
+		rpc.NewSnapshotService(auth, filepath.Join(config.RepoDir, snapshotsDir)),
+		"SnapshotService",
+	)
+	server.RegisterService(
+		rpc.NewAdminService(
+			auth,
+			collections.Packages,
+			config.RepoDir,
+			config.repositoryNames(),
+			config.architectureNames(),
+			signer.sign(),
+		),
+		"AdminService",
+	)
 
 	return server, nil
 }
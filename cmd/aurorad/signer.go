@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/reconquest/karma-go"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// signingKeyPath is a path of armored public key of the repository under
+// staticPrefix.
+const signingKeyPath = "/key.asc"
+
+const signingKeyBits = 4096
+
+// Signer creates detached signatures of archives and repository databases
+// which are verified by pacman.
+type Signer struct {
+	entity *openpgp.Entity
+}
+
+func NewSigner(config ConfigSigning) (*Signer, error) {
+	armored, err := ioutil.ReadFile(config.Key)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to read signing key",
+		)
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(armored))
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to parse signing key",
+		)
+	}
+
+	if len(entities) != 1 || entities[0].PrivateKey == nil {
+		return nil, errors.New("signing key file should contain exactly one private key")
+	}
+
+	if entities[0].PrivateKey.Encrypted {
+		return nil, errors.New("signing key should not be protected by passphrase")
+	}
+
+	return &Signer{entity: entities[0]}, nil
+}
+
+// Fingerprint returns fingerprint of the key as pacman-key expects it.
+func (signer *Signer) Fingerprint() string {
+	return fmt.Sprintf("%X", signer.entity.PrimaryKey.Fingerprint)
+}
+
+// PublicKey returns armored public key.
+func (signer *Signer) PublicKey() ([]byte, error) {
+	buffer := &bytes.Buffer{}
+
+	writer, err := armor.Encode(buffer, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = signer.entity.Serialize(writer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// SignFile writes detached binary signature of given file to path.sig.
+func (signer *Signer) SignFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	signature := &bytes.Buffer{}
+
+	err = openpgp.DetachSign(signature, signer.entity, file, nil)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to sign %s", path,
+		)
+	}
+
+	temp := path + ".sig.tmp"
+
+	err = ioutil.WriteFile(temp, signature.Bytes(), 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path+".sig")
+}
+
+// sign returns function signing repository database or nil if signing is not
+// configured.
+func (signer *Signer) sign() func(string) error {
+	if signer == nil {
+		return nil
+	}
+
+	return signer.SignFile
+}
+
+// GenerateSigningKey generates a new private key and writes it to the path
+// specified in config, existing key is never overwritten.
+func GenerateSigningKey(config ConfigSigning) (*Signer, error) {
+	if config.Key == "" {
+		return nil, errors.New("signing.key is not specified in config")
+	}
+
+	_, err := os.Stat(config.Key)
+	if err == nil {
+		return nil, fmt.Errorf("signing key already exists: %s", config.Key)
+	}
+
+	entity, err := openpgp.NewEntity(
+		config.Name, "", config.Email,
+		&packet.Config{RSABits: signingKeyBits},
+	)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to generate key",
+		)
+	}
+
+	buffer := &bytes.Buffer{}
+
+	writer, err := armor.Encode(buffer, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = entity.SerializePrivate(writer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(config.Key), 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(config.Key, buffer.Bytes(), 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{entity: entity}, nil
+}
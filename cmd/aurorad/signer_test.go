@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/openpgp"
+)
+
+func TestSigner(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	config := ConfigSigning{
+		Key:   filepath.Join(dir, "signing.key"),
+		Name:  "aurora",
+		Email: "aurora@localhost",
+	}
+
+	generated, err := GenerateSigningKey(config)
+	test.NoError(err)
+
+	_, err = GenerateSigningKey(config)
+	test.Error(err, "existing key should not be overwritten")
+
+	signer, err := NewSigner(config)
+	test.NoError(err)
+	test.Equal(generated.Fingerprint(), signer.Fingerprint())
+	test.Len(signer.Fingerprint(), 40)
+
+	path := filepath.Join(dir, "foo-1-1-x86_64.pkg.tar.zst")
+	test.NoError(ioutil.WriteFile(path, []byte("archive"), 0o644))
+	test.NoError(signer.SignFile(path))
+
+	public, err := signer.PublicKey()
+	test.NoError(err)
+
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(public))
+	test.NoError(err)
+
+	signature, err := os.Open(path + ".sig")
+	test.NoError(err)
+
+	defer signature.Close()
+
+	signed, err := openpgp.CheckDetachedSignature(
+		keyring, bytes.NewReader([]byte("archive")), signature,
+	)
+	test.NoError(err)
+	test.Equal(signer.Fingerprint(), (&Signer{entity: signed}).Fingerprint())
+
+	var nothing *Signer
+	test.Nil(nothing.sign())
+}
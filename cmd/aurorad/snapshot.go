@@ -0,0 +1,246 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/kovetskiy/aurora/pkg/rpc"
+	"github.com/reconquest/karma-go"
+)
+
+// snapshotsDir is a directory in repo_dir where snapshots are stored, so
+// snapshots are on the same filesystem and served at staticPrefix/snapshots/.
+const snapshotsDir = "snapshots"
+
+func (proc *Processor) loopSnapshots(done func()) {
+	defer done()
+
+	for {
+		err := proc.createSnapshot(time.Now())
+		if err != nil {
+			errorh(err, "unable to create snapshot")
+		}
+
+		err = proc.removeSnapshots(time.Now())
+		if err != nil {
+			errorh(err, "unable to remove expired snapshots")
+		}
+
+		time.Sleep(proc.config.Snapshots.Interval)
+	}
+}
+
+func (proc *Processor) createSnapshot(now time.Time) error {
+	dir := filepath.Join(proc.repoDir, snapshotsDir)
+	name := now.UTC().Format(proto.SnapshotDateFormat)
+	path := filepath.Join(dir, name)
+
+	_, err := os.Stat(path)
+	if err == nil {
+		tracef("snapshot %s already exists", name)
+		return nil
+	}
+
+	architectures := proc.config.architectureNames()
+
+	// databases are locked, so none of archives is added or removed while
+	// the snapshot is being made
+	for _, repository := range proc.config.repositoryNames() {
+		for _, arch := range architectures {
+			database, err := repodb.Open(
+				repodb.ArchPath(proc.repoDir, repository, architectures, arch),
+			)
+			if err != nil {
+				return karma.Format(
+					err,
+					"unable to lock repository %s for %s", repository, arch,
+				)
+			}
+
+			defer database.Close()
+		}
+	}
+
+	temp := filepath.Join(dir, "."+name)
+
+	err = os.RemoveAll(temp)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(temp, 0o755)
+	if err != nil {
+		return err
+	}
+
+	err = linkSnapshot(proc.repoDir, temp)
+	if err == nil {
+		err = linkArchitectures(proc.repoDir, temp, architectures)
+	}
+	if err != nil {
+		os.RemoveAll(temp)
+
+		return karma.Format(
+			err,
+			"unable to link files of snapshot",
+		)
+	}
+
+	err = os.Rename(temp, path)
+	if err != nil {
+		return err
+	}
+
+	infof("snapshot %s has been created", name)
+
+	return nil
+}
+
+// linkSnapshot hardlinks archives, signatures and databases of repositories
+// into given directory, symlinks are recreated as is.
+func linkSnapshot(repoDir string, dir string) error {
+	infos, err := ioutil.ReadDir(repoDir)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		name := info.Name()
+
+		// temporary files, lock files and directories like snapshots itself
+		if strings.HasPrefix(name, ".") ||
+			strings.HasSuffix(name, ".tmp") ||
+			strings.HasSuffix(name, ".lck") ||
+			info.IsDir() {
+			continue
+		}
+
+		source := filepath.Join(repoDir, name)
+		target := filepath.Join(dir, name)
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(source)
+			if err != nil {
+				return err
+			}
+
+			err = os.Symlink(link, target)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		err := os.Link(source, target)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// linkArchitectures makes snapshot available at <snapshot>/<arch>/ for all
+// architectures: databases of the first architecture are in the snapshot
+// itself, other architectures get their databases and hardlinks of all
+// archives in subdirectories.
+func linkArchitectures(repoDir string, dir string, architectures []string) error {
+	if len(architectures) == 0 {
+		return nil
+	}
+
+	err := os.Symlink(".", filepath.Join(dir, architectures[0]))
+	if err != nil {
+		return err
+	}
+
+	infos, err := ioutil.ReadDir(repoDir)
+	if err != nil {
+		return err
+	}
+
+	for _, arch := range architectures[1:] {
+		target := filepath.Join(dir, arch)
+
+		err := os.Mkdir(target, 0o755)
+		if err != nil {
+			return err
+		}
+
+		err = linkSnapshot(filepath.Join(repoDir, arch), target)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		for _, info := range infos {
+			if !info.Mode().IsRegular() || !strings.Contains(info.Name(), ".pkg.tar") {
+				continue
+			}
+
+			err := os.Link(
+				filepath.Join(repoDir, info.Name()),
+				filepath.Join(target, info.Name()),
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (proc *Processor) removeSnapshots(now time.Time) error {
+	dir := filepath.Join(proc.repoDir, snapshotsDir)
+
+	snapshots, err := rpc.FindSnapshots(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range getExpiredSnapshots(snapshots, now, proc.config.Snapshots) {
+		err := os.RemoveAll(filepath.Join(dir, name))
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to remove snapshot %s", name,
+			)
+		}
+
+		infof("snapshot %s has been removed", name)
+	}
+
+	return nil
+}
+
+// getExpiredSnapshots returns names of snapshots which should be removed
+// according to retention settings, snapshots are sorted newest first.
+func getExpiredSnapshots(
+	snapshots []proto.Snapshot,
+	now time.Time,
+	config ConfigSnapshots,
+) []string {
+	expired := []string{}
+	for i, snapshot := range snapshots {
+		switch {
+		case config.MaxCount > 0 && i >= config.MaxCount:
+		case config.MaxAge > 0 && now.Sub(snapshot.Date) > config.MaxAge:
+		default:
+			continue
+		}
+
+		expired = append(expired, snapshot.Name)
+	}
+
+	return expired
+}
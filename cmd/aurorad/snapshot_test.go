@@ -0,0 +1,152 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetExpiredSnapshots(t *testing.T) {
+	test := assert.New(t)
+
+	now := time.Date(2020, 9, 10, 12, 0, 0, 0, time.UTC)
+
+	snapshots := []proto.Snapshot{}
+	for day := 10; day > 0; day-- {
+		date := time.Date(2020, 9, day, 0, 0, 0, 0, time.UTC)
+		snapshots = append(snapshots, proto.Snapshot{
+			Name: date.Format(proto.SnapshotDateFormat),
+			Date: date,
+		})
+	}
+
+	test.Empty(getExpiredSnapshots(snapshots, now, ConfigSnapshots{}))
+
+	test.Equal(
+		[]string{"2020-09-02", "2020-09-01"},
+		getExpiredSnapshots(snapshots, now, ConfigSnapshots{MaxCount: 8}),
+	)
+
+	test.Equal(
+		[]string{"2020-09-07", "2020-09-06", "2020-09-05", "2020-09-04", "2020-09-03", "2020-09-02", "2020-09-01"},
+		getExpiredSnapshots(
+			snapshots, now,
+			ConfigSnapshots{MaxAge: time.Hour * 72, MaxCount: 8},
+		),
+	)
+}
+
+func TestLinkSnapshot(t *testing.T) {
+	test := assert.New(t)
+
+	repoDir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(repoDir)
+
+	files := []string{
+		"1600000000.foo-1-1-x86_64.pkg.tar.zst",
+		"1600000000.foo-1-1-x86_64.pkg.tar.zst.sig",
+		"aurora.db.tar",
+		"aurora.db.tar.lck",
+		".aurora.db.tar123",
+	}
+
+	for _, name := range files {
+		test.NoError(ioutil.WriteFile(filepath.Join(repoDir, name), []byte(name), 0o644))
+	}
+
+	test.NoError(os.Symlink("aurora.db.tar", filepath.Join(repoDir, "aurora.db")))
+	test.NoError(os.MkdirAll(filepath.Join(repoDir, snapshotsDir, "2020-09-01"), 0o755))
+
+	dir := filepath.Join(repoDir, snapshotsDir, ".2020-09-02")
+	test.NoError(os.MkdirAll(dir, 0o755))
+	test.NoError(linkSnapshot(repoDir, dir))
+
+	infos, err := ioutil.ReadDir(dir)
+	test.NoError(err)
+
+	names := []string{}
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+
+	test.Equal(
+		[]string{
+			"1600000000.foo-1-1-x86_64.pkg.tar.zst",
+			"1600000000.foo-1-1-x86_64.pkg.tar.zst.sig",
+			"aurora.db",
+			"aurora.db.tar",
+		},
+		names,
+	)
+
+	link, err := os.Readlink(filepath.Join(dir, "aurora.db"))
+	test.NoError(err)
+	test.Equal("aurora.db.tar", link)
+
+	source, err := os.Stat(filepath.Join(repoDir, "aurora.db.tar"))
+	test.NoError(err)
+
+	target, err := os.Stat(filepath.Join(dir, "aurora.db.tar"))
+	test.NoError(err)
+	test.True(os.SameFile(source, target))
+}
+
+func TestLinkArchitectures(t *testing.T) {
+	test := assert.New(t)
+
+	repoDir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(repoDir)
+
+	test.NoError(os.MkdirAll(filepath.Join(repoDir, "aarch64"), 0o755))
+
+	files := []string{
+		"1600000000.foo-1-1-x86_64.pkg.tar.zst",
+		"1600000000.foo-1-1-aarch64.pkg.tar.zst",
+		"aurora.db.tar",
+		"aarch64/aurora.db.tar",
+	}
+
+	for _, name := range files {
+		test.NoError(ioutil.WriteFile(filepath.Join(repoDir, name), []byte(name), 0o644))
+	}
+
+	dir := filepath.Join(repoDir, snapshotsDir, ".2020-09-02")
+	test.NoError(os.MkdirAll(dir, 0o755))
+	test.NoError(linkSnapshot(repoDir, dir))
+	test.NoError(
+		linkArchitectures(repoDir, dir, []string{"x86_64", "aarch64", "armv7h"}),
+	)
+
+	link, err := os.Readlink(filepath.Join(dir, "x86_64"))
+	test.NoError(err)
+	test.Equal(".", link)
+
+	for _, name := range []string{
+		"aarch64/1600000000.foo-1-1-aarch64.pkg.tar.zst",
+		"armv7h/1600000000.foo-1-1-x86_64.pkg.tar.zst",
+		"x86_64/aurora.db.tar",
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		test.NoError(err, name)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "aarch64", "aurora.db.tar"))
+	test.NoError(err)
+	test.Equal("aarch64/aurora.db.tar", string(data))
+
+	_, err = os.Stat(filepath.Join(dir, "armv7h", "aurora.db.tar"))
+	test.True(os.IsNotExist(err))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+var ErrLintFailed = errors.New("lint failed")
+
+// reNamcapLine matches machine-readable output of namcap -m, like:
+// foo E: dependency-detected-not-included bar (libraries ...)
+var reNamcapLine = regexp.MustCompile(`^\S+ ([EWI]): (\S+)(?: (.*))?$`)
+
+var lintSeverities = map[string]int{
+	proto.LintSeverityInfo:    0,
+	proto.LintSeverityWarning: 1,
+	proto.LintSeverityError:   2,
+}
+
+// verify runs namcap on built archive and installs it in a fresh container,
+// so broken packages are not published.
+func (build *build) verify(archive string) error {
+	if !build.configVerify.Namcap && !build.configVerify.Install {
+		return nil
+	}
+
+	name := build.container + "-verify"
+
+	build.log.Debugf("creating container %s", name)
+
+	build.bus.Publish(build.pkg.Name, "builder: Creating container for verification\n")
+
+	container, err := build.cloud.CreateContainer(
+		build.image(build.getArchiveArch(archive)),
+		build.bufferDir,
+		name,
+		[]string{fmt.Sprintf("AURORA_PACKAGE=%s", build.pkg.Name)},
+		nil,
+	)
+	if err != nil {
+		return karma.Format(
+			err, "can't create container",
+		)
+	}
+
+	defer func() {
+		err := build.cloud.DestroyContainer(container)
+		if err != nil {
+			build.log.Error(
+				karma.Format(
+					err, "can't destroy container %s", name,
+				),
+			)
+		}
+	}()
+
+	err = build.cloud.StartContainer(container)
+	if err != nil {
+		return karma.Format(
+			err, "can't start container",
+		)
+	}
+
+	path := filepath.Join("/buffer", build.pkg.Name, filepath.Base(archive))
+
+	if build.configVerify.Namcap {
+		err = build.lint(container, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if build.configVerify.Install {
+		build.setStage(failureStageInstall)
+
+		build.bus.Publish(build.pkg.Name, "builder: Installing package\n")
+
+		err = build.cloud.Exec(
+			context.Background(), build.log, build.publish("install: "),
+			container, []string{"/app/verify.sh", path}, nil,
+		)
+		if err != nil {
+			return karma.Format(err, "verify.sh failed")
+		}
+	}
+
+	return nil
+}
+
+func (build *build) lint(container string, path string) error {
+	build.setStage(failureStageLint)
+
+	build.bus.Publish(build.pkg.Name, "builder: Running namcap\n")
+
+	// output of the stage is truncated for failure logs, so results are
+	// collected apart
+	output := &strings.Builder{}
+	publish := build.publish("namcap: ")
+
+	err := build.cloud.Exec(
+		context.Background(), build.log,
+		func(log string) {
+			output.WriteString(log)
+			publish(log)
+		},
+		container, []string{"namcap", "-m", path}, nil,
+	)
+	if err != nil {
+		return karma.Format(err, "namcap failed")
+	}
+
+	build.record.Lint = parseNamcap(output.String())
+
+	threshold, ok := lintSeverities[build.configVerify.BlockSeverity]
+	if !ok {
+		return nil
+	}
+
+	blocking := []string{}
+	for _, result := range build.record.Lint {
+		if lintSeverities[result.Severity] >= threshold {
+			blocking = append(blocking, result.Tag)
+		}
+	}
+
+	if len(blocking) > 0 {
+		return karma.
+			Describe("tags", strings.Join(blocking, ", ")).
+			Format(
+				ErrLintFailed,
+				"namcap reported %d issues of severity %s or higher",
+				len(blocking), build.configVerify.BlockSeverity,
+			)
+	}
+
+	return nil
+}
+
+func parseNamcap(output string) []proto.LintResult {
+	results := []proto.LintResult{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		matches := reNamcapLine.FindStringSubmatch(
+			strings.TrimSpace(scanner.Text()),
+		)
+		if matches == nil {
+			continue
+		}
+
+		var severity string
+		switch matches[1] {
+		case "E":
+			severity = proto.LintSeverityError
+		case "W":
+			severity = proto.LintSeverityWarning
+		default:
+			severity = proto.LintSeverityInfo
+		}
+
+		results = append(results, proto.LintResult{
+			Severity: severity,
+			Tag:      matches[2],
+			Message:  matches[3],
+		})
+	}
+
+	return results
+}
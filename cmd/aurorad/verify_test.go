@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNamcap(t *testing.T) {
+	test := assert.New(t)
+
+	output := `foo E: dependency-detected-not-included bar (libraries ['usr/lib/libbar.so'] needed in files ['usr/bin/foo'])
+foo W: file-in-temporary-dir tmp/foo
+foo I: lots-of-docs
+==> something unrelated
+`
+
+	test.Equal(
+		[]proto.LintResult{
+			{
+				Severity: proto.LintSeverityError,
+				Tag:      "dependency-detected-not-included",
+				Message:  "bar (libraries ['usr/lib/libbar.so'] needed in files ['usr/bin/foo'])",
+			},
+			{
+				Severity: proto.LintSeverityWarning,
+				Tag:      "file-in-temporary-dir",
+				Message:  "tmp/foo",
+			},
+			{
+				Severity: proto.LintSeverityInfo,
+				Tag:      "lots-of-docs",
+			},
+		},
+		parseNamcap(output),
+	)
+
+	test.Empty(parseNamcap(""))
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/globalsign/mgo"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/kovetskiy/aurora/pkg/repodb"
 	"github.com/reconquest/karma-go"
 )
 
@@ -14,10 +17,11 @@ const (
 )
 
 type Web struct {
-	static http.Handler
+	static     http.Handler
+	signingKey []byte
 }
 
-func serveWeb(collection *mgo.Collection, config *Config) error {
+func serveWeb(collections *Collections, config *Config) error {
 	web := &Web{}
 
 	router := chi.NewRouter()
@@ -28,9 +32,57 @@ func serveWeb(collection *mgo.Collection, config *Config) error {
 
 	web.initStatic(config)
 
+	var signer *Signer
+	if config.Signing.Key != "" {
+		var err error
+		signer, err = NewSigner(config.Signing)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to init signer",
+			)
+		}
+
+		web.signingKey, err = signer.PublicKey()
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to init signing key",
+			)
+		}
+
+		router.Get(staticPrefix+signingKeyPath, web.serveSigningKey)
+	}
+
 	router.Get(staticPrefix+"/*", web.static.ServeHTTP)
 
-	rpc, err := NewRPCServer(collection, config)
+	// all repositories share the same directory, pacman downloads
+	// $repo.db and archives from /$repo/ or /$repo/$arch/
+	architectures := config.architectureNames()
+	for _, repository := range config.repositoryNames() {
+		prefix := "/" + repository
+
+		for _, arch := range architectures {
+			router.Get(
+				prefix+"/"+arch+"/*",
+				serveArchitecture(
+					config.RepoDir,
+					filepath.Dir(repodb.ArchPath(config.RepoDir, repository, architectures, arch)),
+				),
+			)
+		}
+
+		if prefix == staticPrefix {
+			continue
+		}
+
+		router.Get(
+			prefix+"/*",
+			http.StripPrefix(prefix, http.FileServer(http.Dir(config.RepoDir))).ServeHTTP,
+		)
+	}
+
+	rpc, err := NewRPCServer(collections, config, signer)
 	if err != nil {
 		return karma.Format(
 			err,
@@ -51,3 +103,29 @@ func (web *Web) initStatic(config *Config) {
 		http.FileServer(http.Dir(config.RepoDir)),
 	)
 }
+
+// serveArchitecture serves databases from the directory of an architecture
+// and archives from repository directory.
+func serveArchitecture(repoDir string, dir string) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		name := chi.URLParam(request, "*")
+		if name == "" || strings.Contains(name, "/") || strings.HasPrefix(name, ".") {
+			http.NotFound(writer, request)
+			return
+		}
+
+		path := filepath.Join(dir, name)
+
+		_, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			path = filepath.Join(repoDir, name)
+		}
+
+		http.ServeFile(writer, request, path)
+	}
+}
+
+func (web *Web) serveSigningKey(writer http.ResponseWriter, _ *http.Request) {
+	writer.Header().Set("Content-Type", "application/pgp-keys")
+	writer.Write(web.signingKey)
+}
@@ -0,0 +1,21 @@
+#!/bin/bash
+
+set -euo pipefail
+
+. $(dirname "$0")/dir.sh
+
+# PKGBUILD is not sourced until the recipe is reviewed, so install scripts
+# are found by name: *.install files and literal install= values
+echo ":: Capturing PKGBUILD and install scripts for review"
+rm -rf /buffer/$AURORA_PACKAGE/review
+mkdir -p /buffer/$AURORA_PACKAGE/review
+cp PKGBUILD /buffer/$AURORA_PACKAGE/review/
+
+{
+    find . -maxdepth 1 -type f -name '*.install' -printf '%P\n'
+    sed -n "s/^[[:space:]]*install=[\"']\{0,1\}\([^\"'\$/[:space:]]*\)[\"']\{0,1\}[[:space:]]*$/\1/p" PKGBUILD
+} | sort -u | while read install; do
+    if [[ "$install" && -f "$install" ]]; then
+        cp "$install" /buffer/$AURORA_PACKAGE/review/
+    fi
+done
@@ -0,0 +1,71 @@
+// Package docker embeds the build context of the aurora builder image, so
+// aurorad can (re)build the image without a checkout of the repository.
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"embed"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+//go:embed Dockerfile base keys *.sh etc
+var files embed.FS
+
+// Context returns the embedded build context as a tar stream suitable for
+// the Docker image build API.
+func Context() (io.Reader, error) {
+	buffer := &bytes.Buffer{}
+	archive := tar.NewWriter(buffer)
+
+	err := fs.WalkDir(
+		files, ".",
+		func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if entry.IsDir() {
+				return nil
+			}
+
+			contents, err := files.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			// embed.FS doesn't keep file modes, scripts are the only
+			// executables in the context
+			mode := int64(0o644)
+			if filepath.Ext(path) == ".sh" {
+				mode = 0o755
+			}
+
+			err = archive.WriteHeader(&tar.Header{
+				Name:    path,
+				Mode:    mode,
+				Size:    int64(len(contents)),
+				ModTime: time.Unix(0, 0),
+			})
+			if err != nil {
+				return err
+			}
+
+			_, err = archive.Write(contents)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer, nil
+}
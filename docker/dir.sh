@@ -4,19 +4,37 @@ set -euo pipefail
 
 export PATH=$PATH:/usr/bin/core_perl
 mkdir -p /buffer/$AURORA_PACKAGE
+rm -f /buffer/$AURORA_PACKAGE/commit
 
 rm /var/lib/pacman/db.lck 2> /dev/null || true
 
+if [[ "${AURORA_MAKEPKG_CONF:-}" ]]; then
+    echo ":: Applying makepkg.conf overrides"
+    printf '\n%s\n' "$AURORA_MAKEPKG_CONF" >> /etc/makepkg.conf
+fi
+
 sudo -u nobody mkdir /app/build/$AURORA_PACKAGE
 
 cd /app/build/$AURORA_PACKAGE
 
-if [[ ! "${AURORA_CLONE_URL:-}" ]]; then
-    AURORA_CLONE_URL=https://aur.archlinux.org/$AURORA_PACKAGE.git
-fi
+if [[ "${AURORA_SOURCE:-0}" != 0 ]]; then
+    echo ":: Extracting pushed source $AURORA_PACKAGE@$AURORA_SOURCE (subdir: ${AURORA_SUBDIR:-.})"
+    sudo -u nobody tar --no-same-owner -xf /buffer/$AURORA_PACKAGE/source.tar || exit 64
+else
+    if [[ ! "${AURORA_CLONE_URL:-}" ]]; then
+        AURORA_CLONE_URL=https://aur.archlinux.org/$AURORA_PACKAGE.git
+    fi
 
-echo ":: Cloning $AURORA_CLONE_URL for $AURORA_PACKAGE (subdir: ${AURORA_SUBDIR:-.})"
-sudo -u nobody git clone "${AURORA_CLONE_URL}" .
+    echo ":: Cloning $AURORA_CLONE_URL for $AURORA_PACKAGE (subdir: ${AURORA_SUBDIR:-.})"
+    sudo -u nobody git clone "${AURORA_CLONE_URL}" . || exit 64
+
+    if [[ "${AURORA_REF:-}" ]]; then
+        echo ":: Checking out $AURORA_REF"
+        sudo -u nobody git checkout --quiet "$AURORA_REF" -- || exit 64
+    fi
+
+    sudo -u nobody git rev-parse HEAD > /buffer/$AURORA_PACKAGE/commit
+fi
 
 if [[ "${AURORA_SUBDIR:-}" ]]; then
     echo ":: changing directory to $AURORA_SUBDIR"
@@ -0,0 +1,17 @@
+#!/bin/bash
+
+# Imports keys listed in validpgpkeys from the keyring managed by aurorad.
+
+if [[ ! -d /keyring ]]; then
+    return 0
+fi
+
+for key in $(sed -rn 's/^\s*validpgpkeys = (\S+)$/\1/p' "/buffer/$AURORA_PACKAGE/.SRCINFO"); do
+    key=${key^^}
+    if [[ -f "/keyring/$key.asc" ]]; then
+        echo ":: Importing PGP key $key"
+        sudo -u nobody gpg --batch --import "/keyring/$key.asc"
+    else
+        echo ":: PGP key $key is not found in keyring"
+    fi
+done
@@ -2,7 +2,14 @@
 
 set -euo pipefail
 
-. $(dirname "$0")/dir.sh
+cd /app/build/$AURORA_PACKAGE
+
+if [[ "${AURORA_SUBDIR:-}" ]]; then
+    echo ":: changing directory to $AURORA_SUBDIR"
+	cd "./$AURORA_SUBDIR"
+fi
+
+. $(dirname "$0")/keys.sh
 
 cp PKGBUILD PKGBUILD.pkgver
 
@@ -19,7 +26,7 @@ cat >> PKGBUILD.pkgver <<FUNC
 FUNC
 
 chown nobody: PKGBUILD.pkgver
-sudo -u nobody -E makepkg --syncdeps --noconfirm -p PKGBUILD.pkgver
+sudo -u nobody -E makepkg --syncdeps --noconfirm ${AURORA_MAKEPKG_FLAGS:-} -p PKGBUILD.pkgver
 
 cp /app/build/$AURORA_PACKAGE/pkgver /buffer/$AURORA_PACKAGE/pkgver
 rm PKGBUILD.pkgver
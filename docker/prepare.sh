@@ -0,0 +1,53 @@
+#!/bin/bash
+
+set -euo pipefail
+
+export PATH=$PATH:/usr/bin/core_perl
+
+cd /app/build/$AURORA_PACKAGE
+
+if [[ "${AURORA_SUBDIR:-}" ]]; then
+    cd "./$AURORA_SUBDIR"
+fi
+
+apply_patch() {
+    local patch="$1"
+    local strip
+
+    for strip in 1 0; do
+        if patch --batch --forward --dry-run -p$strip -i "$patch" > /dev/null; then
+            sudo -u nobody patch --batch --forward -p$strip -i "$patch"
+            return $?
+        fi
+    done
+
+    return 1
+}
+
+if [[ -d "/buffer/$AURORA_PACKAGE/patches" ]]; then
+    for patch in /buffer/$AURORA_PACKAGE/patches/*; do
+        name=$(basename "$patch")
+        name=${name#*-}
+
+        echo ":: Applying patch $name"
+        if ! apply_patch "$patch"; then
+            echo ":: Patch $name does not apply"
+            echo "$name" > /buffer/$AURORA_PACKAGE/patch-conflict
+            exit 65
+        fi
+    done
+fi
+
+echo ":: Generating .SRCINFO"
+sudo -u nobody makepkg --printsrcinfo > /buffer/$AURORA_PACKAGE/.SRCINFO
+
+echo ":: Capturing PKGBUILD and install scripts"
+rm -rf /buffer/$AURORA_PACKAGE/recipe
+mkdir -p /buffer/$AURORA_PACKAGE/recipe
+cp PKGBUILD /buffer/$AURORA_PACKAGE/recipe/
+
+sed -n 's/^\s*install = //p' /buffer/$AURORA_PACKAGE/.SRCINFO | sort -u | while read install; do
+    if [[ "$install" != */* && -f "$install" ]]; then
+        cp "$install" /buffer/$AURORA_PACKAGE/recipe/
+    fi
+done
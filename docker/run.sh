@@ -11,7 +11,7 @@ fi
 
 buildtime=$(date +%s)
 
-sudo -u nobody -E makepkg --syncdeps --noconfirm
+sudo -u nobody -E makepkg --syncdeps --noconfirm ${AURORA_MAKEPKG_FLAGS:-}
 
 find ./ -maxdepth 1 -type f -name '*.pkg.*' -printf '%P\n' | while read filename; do
     cp "${filename}" "/buffer/$AURORA_PACKAGE/${buildtime}.${filename}"
@@ -0,0 +1,11 @@
+#!/bin/bash
+
+set -euo pipefail
+
+archive="$1"
+
+rm /var/lib/pacman/db.lck 2> /dev/null || true
+
+echo ":: Installing $(basename "$archive") into a clean system"
+pacman -Sy --noconfirm
+pacman -U --noconfirm "$archive"
@@ -1,6 +1,6 @@
 module github.com/kovetskiy/aurora
 
-go 1.15
+go 1.16
 
 require (
 	github.com/Microsoft/go-winio v0.4.14 // indirect
@@ -15,6 +15,7 @@ require (
 	github.com/go-yaml/yaml v2.1.0+incompatible
 	github.com/gorilla/rpc v1.2.0
 	github.com/gorilla/websocket v1.4.2
+	github.com/klauspost/compress v1.11.13
 	github.com/kovetskiy/aur-go v0.0.0-20200922071921-ab2b5bbf5372
 	github.com/kovetskiy/ko v0.0.0-20200620085804-ec6b220882b0
 	github.com/kovetskiy/lorg v0.0.0-20200107130803-9a7136a95634
@@ -33,6 +34,9 @@ require (
 	github.com/reconquest/regexputil-go v0.0.0-20160905154124-38573e70c1f4
 	github.com/reconquest/ser-go v0.0.0-20181114141834-0d1f485292ce // indirect
 	github.com/reconquest/threadpool-go v0.0.0-20200611094221-afeb4fccf259
+	github.com/stretchr/testify v1.2.2
+	github.com/ulikunitz/xz v0.5.10
 	github.com/zazab/zhash v0.0.0-20170403032415-ad45b89afe7a // indirect
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/net v0.0.0-20200904194848-62affa334b73 // indirect
 )
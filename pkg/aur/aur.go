@@ -0,0 +1,173 @@
+// Package aur implements a client of AUR RPC interface, only package info
+// and search by name queries are supported.
+package aur
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/reconquest/karma-go"
+)
+
+// DefaultEndpoint is the endpoint of AUR RPC interface.
+const DefaultEndpoint = "https://aur.archlinux.org/rpc/"
+
+// MaxInfoNames limits number of names queried in a single request, AUR
+// rejects too long URLs.
+const MaxInfoNames = 100
+
+// Package is metadata of an AUR package.
+type Package struct {
+	Name         string `json:"Name"`
+	PackageBase  string `json:"PackageBase"`
+	Version      string `json:"Version"`
+	Maintainer   string `json:"Maintainer"`
+	OutOfDate    int64  `json:"OutOfDate"`
+	LastModified int64  `json:"LastModified"`
+}
+
+// OutOfDateSince returns time when the package was flagged out-of-date or
+// zero time if it isn't flagged.
+func (pkg Package) OutOfDateSince() time.Time {
+	if pkg.OutOfDate == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(pkg.OutOfDate, 0)
+}
+
+type response struct {
+	Type    string    `json:"type"`
+	Error   string    `json:"error"`
+	Results []Package `json:"results"`
+}
+
+// Client queries AUR RPC interface.
+type Client struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewClient returns a client of AUR RPC interface at given endpoint.
+func NewClient(endpoint string, timeout time.Duration) *Client {
+	return &Client{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Info returns metadata of specified packages indexed by name, packages which
+// don't exist in AUR are missing in the result.
+func (client *Client) Info(names []string) (map[string]Package, error) {
+	packages := map[string]Package{}
+
+	for start := 0; start < len(names); start += MaxInfoNames {
+		end := start + MaxInfoNames
+		if end > len(names) {
+			end = len(names)
+		}
+
+		results, err := client.info(names[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pkg := range results {
+			packages[pkg.Name] = pkg
+		}
+	}
+
+	return packages, nil
+}
+
+// Bases returns metadata of packages with specified package bases indexed
+// by package base, one of split packages is returned for every base. Bases
+// which don't exist in AUR are missing in the result.
+func (client *Client) Bases(bases []string) (map[string]Package, error) {
+	packages := map[string]Package{}
+
+	// usually one of split packages is named after its base, so most of
+	// bases are found using a single info query
+	infos, err := client.Info(bases)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range infos {
+		packages[pkg.PackageBase] = pkg
+	}
+
+	for _, base := range bases {
+		if _, ok := packages[base]; ok {
+			continue
+		}
+
+		// names of split packages usually contain their base
+		results, err := client.search("name", base)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pkg := range results {
+			if pkg.PackageBase == base {
+				packages[base] = pkg
+				break
+			}
+		}
+	}
+
+	return packages, nil
+}
+
+func (client *Client) info(names []string) ([]Package, error) {
+	query := url.Values{}
+	query.Set("v", "5")
+	query.Set("type", "info")
+
+	for _, name := range names {
+		query.Add("arg[]", name)
+	}
+
+	return client.get(query)
+}
+
+func (client *Client) search(by string, arg string) ([]Package, error) {
+	query := url.Values{}
+	query.Set("v", "5")
+	query.Set("type", "search")
+	query.Set("by", by)
+	query.Set("arg", arg)
+
+	return client.get(query)
+}
+
+func (client *Client) get(query url.Values) ([]Package, error) {
+	resp, err := client.client.Get(client.endpoint + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %s", resp.Status)
+	}
+
+	var reply response
+	err = json.NewDecoder(resp.Body).Decode(&reply)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to decode AUR response",
+		)
+	}
+
+	if reply.Type == "error" {
+		return nil, fmt.Errorf("AUR error: %s", reply.Error)
+	}
+
+	return reply.Results, nil
+}
@@ -0,0 +1,140 @@
+package aur
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Info(t *testing.T) {
+	test := assert.New(t)
+
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(writer http.ResponseWriter, request *http.Request) {
+			requests++
+
+			query := request.URL.Query()
+			test.Equal("5", query.Get("v"))
+			test.Equal("info", query.Get("type"))
+
+			results := ""
+			for _, name := range query["arg[]"] {
+				if name == "deleted" {
+					continue
+				}
+
+				if results != "" {
+					results += ","
+				}
+
+				switch name {
+				case "orphan":
+					results += `{"Name":"orphan","Maintainer":null,"OutOfDate":null}`
+				default:
+					results += fmt.Sprintf(
+						`{"Name":%q,"Maintainer":"john","OutOfDate":1600000000}`,
+						name,
+					)
+				}
+			}
+
+			fmt.Fprintf(writer, `{"type":"multiinfo","results":[%s]}`, results)
+		},
+	))
+	defer server.Close()
+
+	client := NewClient(server.URL, time.Second)
+
+	names := []string{"orphan", "deleted"}
+	for i := 0; i < MaxInfoNames; i++ {
+		names = append(names, fmt.Sprintf("pkg%d", i))
+	}
+
+	packages, err := client.Info(names)
+	test.NoError(err)
+	test.Equal(2, requests)
+	test.Len(packages, MaxInfoNames+1)
+
+	test.Equal("", packages["orphan"].Maintainer)
+	test.True(packages["orphan"].OutOfDateSince().IsZero())
+
+	test.Equal("john", packages["pkg0"].Maintainer)
+	test.Equal(time.Unix(1600000000, 0), packages["pkg0"].OutOfDateSince())
+
+	_, ok := packages["deleted"]
+	test.False(ok)
+}
+
+func TestClient_Info_Error(t *testing.T) {
+	test := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(writer http.ResponseWriter, request *http.Request) {
+			fmt.Fprint(writer, `{"type":"error","error":"Too many package results."}`)
+		},
+	))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, time.Second).Info([]string{"foo"})
+	test.Error(err)
+}
+
+func TestClient_Bases(t *testing.T) {
+	test := assert.New(t)
+
+	searches := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(writer http.ResponseWriter, request *http.Request) {
+			query := request.URL.Query()
+
+			switch query.Get("type") {
+			case "info":
+				results := ""
+				for _, name := range query["arg[]"] {
+					if name != "foo" {
+						continue
+					}
+
+					results = `{"Name":"foo","PackageBase":"foo","Maintainer":"john"}`
+				}
+
+				fmt.Fprintf(writer, `{"type":"multiinfo","results":[%s]}`, results)
+
+			case "search":
+				test.Equal("name", query.Get("by"))
+
+				searches = append(searches, query.Get("arg"))
+
+				results := ""
+				if query.Get("arg") == "bar" {
+					results = `{"Name":"bar-cli","PackageBase":"bar-cli","Maintainer":"mary"},` +
+						`{"Name":"bar-docs","PackageBase":"bar","Maintainer":"john"}`
+				}
+
+				fmt.Fprintf(writer, `{"type":"search","results":[%s]}`, results)
+			}
+		},
+	))
+	defer server.Close()
+
+	packages, err := NewClient(server.URL, time.Second).Bases(
+		[]string{"foo", "bar", "deleted"},
+	)
+	test.NoError(err)
+	test.Equal([]string{"bar", "deleted"}, searches)
+	test.Len(packages, 2)
+
+	test.Equal("foo", packages["foo"].Name)
+	test.Equal("bar-docs", packages["bar"].Name)
+	test.Equal("john", packages["bar"].Maintainer)
+
+	_, ok := packages["deleted"]
+	test.False(ok)
+}
@@ -0,0 +1,413 @@
+// Package fsck checks consistency of archives in repository directory,
+// repository databases and packages, and repairs found inconsistencies.
+package fsck
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/reconquest/karma-go"
+)
+
+// Repository is a repository database of an architecture opened for
+// checking.
+type Repository struct {
+	Name     string
+	Arch     string
+	Database *repodb.Database
+}
+
+// Run checks consistency of repositories stored in given directory and
+// repairs found issues if repair is true. Repository databases are locked
+// while checking.
+func Run(
+	collection *mgo.Collection,
+	dir string,
+	names []string,
+	architectures []string,
+	sign func(path string) error,
+	repair bool,
+) ([]proto.FsckIssue, error) {
+	var packages []proto.Package
+	err := collection.Find(bson.M{}).All(&packages)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find packages in database",
+		)
+	}
+
+	repositories := []Repository{}
+	defer func() {
+		for _, repository := range repositories {
+			repository.Database.Close()
+		}
+	}()
+
+	for _, name := range names {
+		for _, arch := range architectures {
+			database, err := repodb.Open(
+				repodb.ArchPath(dir, name, architectures, arch),
+			)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"unable to open repository %s for %s", name, arch,
+				)
+			}
+
+			repositories = append(repositories, Repository{name, arch, database})
+		}
+	}
+
+	issues, err := Check(dir, repositories, packages)
+	if err != nil {
+		return nil, err
+	}
+
+	if !repair || len(issues) == 0 {
+		return issues, nil
+	}
+
+	rebuild := Repair(dir, repositories, issues)
+
+	for _, repository := range repositories {
+		repository.Database.Sign = sign
+
+		err := repository.Database.Save()
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to save repository %s for %s",
+				repository.Name, repository.Arch,
+			)
+		}
+	}
+
+	for _, issue := range rebuild {
+		update := bson.M{
+			// the package is built as soon as possible, otherwise it's
+			// not published if pkgver is not changed
+			"$set": bson.M{"rebuild": true},
+		}
+
+		if issue.Repository != "" {
+			update["$unset"] = bson.M{"repositories." + issue.Repository: ""}
+		}
+
+		// pkgname of the archive differs from the name of the package
+		// cloned from custom URL or pushed
+		query := bson.M{"name": issue.Package}
+		if issue.Repository != "" && issue.File != "" {
+			query = bson.M{
+				"$or": []bson.M{
+					query,
+					{"repositories." + issue.Repository + ".archive": issue.File},
+				},
+			}
+		}
+
+		err := collection.Update(query, update)
+		if err != nil && err != mgo.ErrNotFound {
+			issue.Repaired = false
+			issue.Error = err.Error()
+		}
+	}
+
+	return issues, nil
+}
+
+// Check returns inconsistencies between archives in given directory,
+// repository databases and packages. Repositories of the first architecture
+// go first.
+func Check(
+	dir string,
+	repositories []Repository,
+	packages []proto.Package,
+) ([]proto.FsckIssue, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]bool{}
+	for _, info := range infos {
+		if info.Mode().IsRegular() && !strings.HasPrefix(info.Name(), ".") {
+			files[info.Name()] = true
+		}
+	}
+
+	// archives are named by pkgname which could differ from the name of
+	// the package
+	tracked := map[string]bool{}
+	for _, pkg := range packages {
+		tracked[pkg.Name] = true
+		tracked[pkg.ArchiveName()] = true
+	}
+
+	issues := []proto.FsckIssue{}
+
+	referred := map[string]bool{}
+	published := map[string]bool{}
+	for _, repository := range repositories {
+		for _, entry := range repository.Database.Entries() {
+			referred[entry.Filename] = true
+			published[entry.Name] = true
+
+			issue := proto.FsckIssue{
+				Repository:   repository.Name,
+				Architecture: repository.Arch,
+				Package:      entry.Name,
+				File:         entry.Filename,
+			}
+
+			if !files[entry.Filename] {
+				issue.Kind = proto.FsckMissingFile
+				issues = append(issues, issue)
+				continue
+			}
+
+			valid, err := verifyEntry(filepath.Join(dir, entry.Filename), entry)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"unable to verify %s", entry.Filename,
+				)
+			}
+
+			if !valid {
+				issue.Kind = proto.FsckChecksumMismatch
+				issues = append(issues, issue)
+			}
+		}
+	}
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if strings.HasSuffix(name, ".sig") {
+			if !files[strings.TrimSuffix(name, ".sig")] {
+				issues = append(issues, proto.FsckIssue{
+					Kind: proto.FsckStrayFile,
+					File: name,
+				})
+			}
+
+			continue
+		}
+
+		// archives of tracked packages which are not published are
+		// retained for rollback
+		archive, ok := proto.ParseArchive(name)
+		if !ok || referred[name] || tracked[archive.Name] {
+			continue
+		}
+
+		issues = append(issues, proto.FsckIssue{
+			Kind:    proto.FsckStrayFile,
+			Package: archive.Name,
+			File:    name,
+		})
+	}
+
+	packages = append([]proto.Package{}, packages...)
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+
+	for _, pkg := range packages {
+		// packages built before repositories were introduced have no
+		// records of published archives
+		if len(pkg.Repositories) == 0 {
+			if pkg.Status == proto.BuildStatusSuccess.String() &&
+				!published[pkg.Name] && len(repositories) > 0 {
+				issues = append(issues, proto.FsckIssue{
+					Kind:         proto.FsckNoArchive,
+					Repository:   repositories[0].Name,
+					Architecture: repositories[0].Arch,
+					Package:      pkg.Name,
+				})
+			}
+
+			continue
+		}
+
+		// records are kept for archives of the first architecture of the
+		// package only
+		arch := getArchitecture(pkg, repositories)
+
+		for _, repository := range repositories {
+			record, ok := pkg.Repositories[repository.Name]
+			if !ok || repository.Arch != arch {
+				continue
+			}
+
+			entry := repository.Database.Get(pkg.ArchiveName())
+
+			issue := proto.FsckIssue{
+				Repository:   repository.Name,
+				Architecture: repository.Arch,
+				Package:      pkg.Name,
+				File:         record.Archive,
+			}
+
+			switch {
+			case !files[record.Archive]:
+				// already reported as missing file
+				if entry != nil && entry.Filename == record.Archive {
+					continue
+				}
+
+				issue.Kind = proto.FsckNoArchive
+
+			case entry == nil:
+				issue.Kind = proto.FsckUnpublished
+
+			default:
+				continue
+			}
+
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, nil
+}
+
+// Repair fixes issues in repository directory and databases, databases
+// should be saved by caller. Issues which can be fixed only by rebuilding
+// packages are returned.
+func Repair(
+	dir string,
+	repositories []Repository,
+	issues []proto.FsckIssue,
+) []*proto.FsckIssue {
+	databases := map[string]*repodb.Database{}
+	for _, repository := range repositories {
+		databases[repository.Name+"/"+repository.Arch] = repository.Database
+	}
+
+	rebuild := []*proto.FsckIssue{}
+	for i := range issues {
+		issue := &issues[i]
+		path := filepath.Join(dir, issue.File)
+		database := databases[issue.Repository+"/"+issue.Architecture]
+
+		switch issue.Kind {
+		case proto.FsckMissingFile:
+			database.Remove(issue.Package)
+			rebuild = append(rebuild, issue)
+
+		case proto.FsckChecksumMismatch, proto.FsckUnpublished:
+			// the archive which doesn't match its entry could be replaced
+			// by anybody, so it's never published again
+			if issue.Kind == proto.FsckUnpublished {
+				entry, err := repodb.ReadPackage(path)
+				if err == nil {
+					database.Add(entry)
+					break
+				}
+			}
+
+			// the archive is broken or tampered, so it's removed and the
+			// package is built again
+			database.Remove(issue.Package)
+
+			err := removeArchive(path)
+			if err != nil {
+				issue.Error = err.Error()
+				continue
+			}
+
+			rebuild = append(rebuild, issue)
+
+		case proto.FsckStrayFile:
+			err := removeArchive(path)
+			if err != nil {
+				issue.Error = err.Error()
+				continue
+			}
+
+		case proto.FsckNoArchive:
+			rebuild = append(rebuild, issue)
+		}
+
+		issue.Repaired = true
+	}
+
+	return rebuild
+}
+
+// getArchitecture returns the first architecture of the package which
+// repositories are checked for.
+func getArchitecture(pkg proto.Package, repositories []Repository) string {
+	for _, arch := range pkg.Architectures {
+		for _, repository := range repositories {
+			if repository.Arch == arch {
+				return arch
+			}
+		}
+	}
+
+	if len(repositories) == 0 {
+		return ""
+	}
+
+	return repositories[0].Arch
+}
+
+func removeArchive(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = os.Remove(path + ".sig")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func verifyEntry(path string, entry *repodb.Entry) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return false, err
+	}
+
+	if entry.CSize != "" && entry.CSize != strconv.FormatInt(size, 10) {
+		return false, nil
+	}
+
+	if entry.SHA256Sum != "" && entry.SHA256Sum != hex.EncodeToString(hash.Sum(nil)) {
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,280 @@
+package fsck
+
+import (
+	"archive/tar"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePackage writes a package archive which contains only .PKGINFO and
+// returns its entry.
+func writePackage(t *testing.T, dir, name, pkgname, version string) *repodb.Entry {
+	path := filepath.Join(dir, name)
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compressor, err := zstd.NewWriter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkginfo := fmt.Sprintf(
+		"pkgname = %s\npkgbase = %s\npkgver = %s\narch = x86_64\n",
+		pkgname, pkgname, version,
+	)
+
+	archive := tar.NewWriter(compressor)
+
+	err = archive.WriteHeader(&tar.Header{
+		Name:     ".PKGINFO",
+		Typeflag: tar.TypeReg,
+		Mode:     0o644,
+		Size:     int64(len(pkginfo)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = archive.Write([]byte(pkginfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = archive.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = compressor.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := repodb.ReadPackage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return entry
+}
+
+func writeFile(t *testing.T, dir, name string) {
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "fsck")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	database, err := repodb.Open(repodb.Path(dir, "aurora"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer database.Close()
+
+	const (
+		fooArchive    = "1600000000.foo-1-1-x86_64.pkg.tar.zst"
+		fooOld        = "1500000000.foo-0.9-1-x86_64.pkg.tar.zst"
+		barArchive    = "1600000000.bar-1-1-x86_64.pkg.tar.zst"
+		bazArchive    = "1600000000.baz-1-1-x86_64.pkg.tar.zst"
+		quxArchive    = "1600000000.qux-1-1-x86_64.pkg.tar.zst"
+		strayArchive  = "1600000000.stray-1-1-x86_64.pkg.tar.zst"
+		brokenArchive = "1600000000.broken-1-1-x86_64.pkg.tar.zst"
+		forgedArchive = "1600000000.forged-1-1-x86_64.pkg.tar.zst"
+		customArchive = "1600000000.quux-1-1-x86_64.pkg.tar.zst"
+		customOld     = "1500000000.quux-0.9-1-x86_64.pkg.tar.zst"
+	)
+
+	database.Add(writePackage(t, dir, fooArchive, "foo", "1-1"))
+
+	writePackage(t, dir, fooOld, "foo", "0.9-1")
+	writeFile(t, dir, fooOld+".sig")
+
+	// pkgname of archives of the custom package is quux
+	database.Add(writePackage(t, dir, customArchive, "quux", "1-1"))
+	writePackage(t, dir, customOld, "quux", "0.9-1")
+
+	writeFile(t, dir, brokenArchive)
+	database.Add(&repodb.Entry{
+		Name:      "broken",
+		Filename:  brokenArchive,
+		SHA256Sum: "0000",
+	})
+
+	// the archive is a valid package, but it's not the published one
+	database.Add(writePackage(t, dir, forgedArchive, "forged", "1-1"))
+	writePackage(t, dir, forgedArchive, "forged", "1-2")
+
+	database.Add(&repodb.Entry{Name: "bar", Filename: barArchive})
+
+	writeFile(t, dir, bazArchive)
+	writeFile(t, dir, strayArchive)
+	writeFile(t, dir, "lost.pkg.tar.zst.sig")
+
+	test.NoError(database.Save())
+
+	repositories := []Repository{{"aurora", "x86_64", database}}
+
+	packages := []proto.Package{
+		{
+			Name:   "foo",
+			Status: proto.BuildStatusSuccess.String(),
+			Repositories: map[string]proto.PackageRepository{
+				"aurora": {Archive: fooArchive},
+			},
+		},
+		{
+			Name:   "custom",
+			Status: proto.BuildStatusSuccess.String(),
+			Repositories: map[string]proto.PackageRepository{
+				"aurora": {Archive: customArchive},
+			},
+		},
+		{
+			Name:   "baz",
+			Status: proto.BuildStatusSuccess.String(),
+			Repositories: map[string]proto.PackageRepository{
+				"aurora": {Archive: bazArchive},
+			},
+		},
+		{
+			Name:   "qux",
+			Status: proto.BuildStatusSuccess.String(),
+			Repositories: map[string]proto.PackageRepository{
+				"aurora": {Archive: quxArchive},
+			},
+		},
+		{
+			Name:   "legacy",
+			Status: proto.BuildStatusSuccess.String(),
+		},
+		{
+			Name:   "failed",
+			Status: proto.BuildStatusFailure.String(),
+		},
+	}
+
+	issues, err := Check(dir, repositories, packages)
+	test.NoError(err)
+	test.Equal(
+		[]proto.FsckIssue{
+			{
+				Kind:         proto.FsckMissingFile,
+				Repository:   "aurora",
+				Architecture: "x86_64",
+				Package:      "bar",
+				File:         barArchive,
+			},
+			{
+				Kind:         proto.FsckChecksumMismatch,
+				Repository:   "aurora",
+				Architecture: "x86_64",
+				Package:      "broken",
+				File:         brokenArchive,
+			},
+			{
+				Kind:         proto.FsckChecksumMismatch,
+				Repository:   "aurora",
+				Architecture: "x86_64",
+				Package:      "forged",
+				File:         forgedArchive,
+			},
+			{
+				Kind:    proto.FsckStrayFile,
+				Package: "stray",
+				File:    strayArchive,
+			},
+			{
+				Kind: proto.FsckStrayFile,
+				File: "lost.pkg.tar.zst.sig",
+			},
+			{
+				Kind:         proto.FsckUnpublished,
+				Repository:   "aurora",
+				Architecture: "x86_64",
+				Package:      "baz",
+				File:         bazArchive,
+			},
+			{
+				Kind:         proto.FsckNoArchive,
+				Repository:   "aurora",
+				Architecture: "x86_64",
+				Package:      "legacy",
+			},
+			{
+				Kind:         proto.FsckNoArchive,
+				Repository:   "aurora",
+				Architecture: "x86_64",
+				Package:      "qux",
+				File:         quxArchive,
+			},
+		},
+		issues,
+	)
+
+	rebuild := Repair(dir, repositories, issues)
+
+	names := []string{}
+	for _, issue := range rebuild {
+		names = append(names, issue.Package)
+	}
+
+	// baz and broken are not valid packages and forged doesn't match its
+	// entry, so they are removed
+	test.Equal([]string{"bar", "broken", "forged", "baz", "legacy", "qux"}, names)
+
+	for _, issue := range issues {
+		test.True(issue.Repaired, issue.String())
+		test.Empty(issue.Error)
+	}
+
+	test.NotNil(database.Get("foo"))
+	test.NotNil(database.Get("quux"))
+	test.Nil(database.Get("bar"))
+	test.Nil(database.Get("broken"))
+	test.Nil(database.Get("forged"))
+
+	for _, name := range []string{
+		fooArchive, fooOld, fooOld + ".sig", customArchive, customOld,
+	} {
+		test.FileExists(filepath.Join(dir, name))
+	}
+
+	for _, name := range []string{
+		bazArchive, brokenArchive, forgedArchive, strayArchive,
+		"lost.pkg.tar.zst.sig",
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		test.True(os.IsNotExist(err), name)
+	}
+
+	issues, err = Check(dir, repositories, packages[:2])
+	test.NoError(err)
+	test.Empty(issues)
+}
@@ -0,0 +1,47 @@
+package pkginfo
+
+import (
+	"io"
+)
+
+// BUILDINFO is a description of environment which a package was built in,
+// it's written by makepkg since pacman 5.1.
+type BUILDINFO struct {
+	Format         string
+	PkgbuildSHA256 string
+	Packager       string
+	BuildDate      string
+	BuildDir       string
+	BuildEnv       []string
+	Options        []string
+
+	// Installed are packages installed in the build environment, like
+	// foo-1.0-1-x86_64.
+	Installed []string
+}
+
+// ParseBUILDINFO parses .BUILDINFO.
+func ParseBUILDINFO(reader io.Reader) (*BUILDINFO, error) {
+	info := &BUILDINFO{}
+
+	values := map[string]*string{
+		"format":             &info.Format,
+		"pkgbuild_sha256sum": &info.PkgbuildSHA256,
+		"packager":           &info.Packager,
+		"builddate":          &info.BuildDate,
+		"builddir":           &info.BuildDir,
+	}
+
+	lists := map[string]*[]string{
+		"buildenv":  &info.BuildEnv,
+		"options":   &info.Options,
+		"installed": &info.Installed,
+	}
+
+	err := parse(reader, values, lists)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
@@ -0,0 +1,55 @@
+package pkginfo
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+)
+
+// Matcher matches paths of files of packages, paths are relative to the root
+// like usr/bin/foo.
+type Matcher func(path string) bool
+
+// NewMatcher returns matcher of files like pacman -F does: glob pattern
+// without slashes matches names of files, glob pattern with slashes matches
+// whole paths, leading slash is optional. Regular expression matches whole
+// paths with leading slash like /usr/bin/foo and isn't anchored.
+func NewMatcher(pattern string, regex bool) (Matcher, error) {
+	if regex {
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"invalid regular expression: %s", pattern,
+			)
+		}
+
+		return func(name string) bool {
+			return expression.MatchString("/" + name)
+		}, nil
+	}
+
+	_, err := path.Match(pattern, "")
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"invalid glob pattern: %s", pattern,
+		)
+	}
+
+	if !strings.Contains(pattern, "/") {
+		return func(name string) bool {
+			matched, _ := path.Match(pattern, path.Base(name))
+			return matched
+		}, nil
+	}
+
+	pattern = strings.Trim(pattern, "/")
+
+	return func(name string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}, nil
+}
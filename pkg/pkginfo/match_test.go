@@ -0,0 +1,50 @@
+package pkginfo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMatcher(t *testing.T) {
+	test := assert.New(t)
+
+	testcases := []struct {
+		pattern string
+		regex   bool
+		path    string
+		matched bool
+	}{
+		{"foo", false, "usr/bin/foo", true},
+		{"foo", false, "usr/bin/foobar", false},
+		{"foo*", false, "usr/bin/foobar", true},
+		{"foo", false, "usr/share/foo", true},
+		{"/usr/bin/foo", false, "usr/bin/foo", true},
+		{"usr/bin/foo", false, "usr/bin/foo", true},
+		{"usr/bin/*", false, "usr/bin/foo", true},
+		{"usr/bin/*", false, "usr/bin/sub/foo", false},
+		{"/usr/bin/foo", false, "usr/share/foo", false},
+		{"usr/bin", false, "usr/bin", true},
+		{"/bin/foo$", true, "usr/bin/foo", true},
+		{"^/usr/bin/", true, "usr/bin/foo", true},
+		{"^usr/bin/", true, "usr/bin/foo", false},
+		{"libfoo\\.so\\.[0-9]+$", true, "usr/lib/libfoo.so.1", true},
+		{"libfoo\\.so\\.[0-9]+$", true, "usr/lib/libfoo.so", false},
+	}
+
+	for _, testcase := range testcases {
+		match, err := NewMatcher(testcase.pattern, testcase.regex)
+		test.NoError(err, testcase.pattern)
+		test.Equal(
+			testcase.matched,
+			match(testcase.path),
+			"%s %s", testcase.pattern, testcase.path,
+		)
+	}
+
+	_, err := NewMatcher("[", false)
+	test.Error(err)
+
+	_, err = NewMatcher("(", true)
+	test.Error(err)
+}
@@ -0,0 +1,204 @@
+package pkginfo
+
+import (
+	"archive/tar"
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/kovetskiy/aurora/pkg/pkgtar"
+	"github.com/reconquest/karma-go"
+)
+
+// Types of files.
+const (
+	FileTypeFile = "file"
+	FileTypeDir  = "dir"
+	FileTypeLink = "link"
+)
+
+// File is a file of a package as it's described in .MTREE.
+type File struct {
+	// Path is relative to the root, like usr/bin/foo.
+	Path string
+	Type string
+	// Mode is octal permissions, like 755.
+	Mode   string
+	Size   int64
+	Link   string
+	SHA256 string
+}
+
+// ParseMTREE parses .MTREE written by bsdtar, it's usually compressed by
+// gzip, compression is detected automatically. Metadata files like .PKGINFO
+// are skipped.
+func ParseMTREE(reader io.Reader) ([]File, error) {
+	stream, close, err := pkgtar.Decompress(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	defer close()
+
+	defaults := map[string]string{}
+	files := []File{}
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := ""
+	for scanner.Scan() {
+		line += scanner.Text()
+
+		// long lines are continued on the next line
+		if strings.HasSuffix(line, "\\") {
+			line = strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+
+		fields := strings.Fields(line)
+		line = ""
+
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "/set":
+			for key, value := range parseKeywords(fields[1:]) {
+				defaults[key] = value
+			}
+
+			continue
+
+		case "/unset":
+			for _, key := range fields[1:] {
+				if key == "all" {
+					defaults = map[string]string{}
+				}
+
+				delete(defaults, key)
+			}
+
+			continue
+		}
+
+		path := strings.TrimPrefix(unescape(fields[0]), "./")
+		if path == "." || path == "" || strings.HasPrefix(path, ".") {
+			continue
+		}
+
+		keywords := map[string]string{}
+		for key, value := range defaults {
+			keywords[key] = value
+		}
+
+		for key, value := range parseKeywords(fields[1:]) {
+			keywords[key] = value
+		}
+
+		file := File{
+			Path:   path,
+			Type:   keywords["type"],
+			Mode:   keywords["mode"],
+			Link:   unescape(keywords["link"]),
+			SHA256: keywords["sha256digest"],
+		}
+
+		if file.Type == "" {
+			file.Type = FileTypeFile
+		}
+
+		if size, ok := keywords["size"]; ok {
+			file.Size, err = strconv.ParseInt(size, 10, 64)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"invalid size of %s: %s", path, size,
+				)
+			}
+		}
+
+		files = append(files, file)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func parseKeywords(fields []string) map[string]string {
+	keywords := map[string]string{}
+	for _, field := range fields {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) == 2 {
+			keywords[parts[0]] = parts[1]
+		} else {
+			keywords[parts[0]] = ""
+		}
+	}
+
+	return keywords
+}
+
+// unescape decodes \ooo octal escapes used by mtree for spaces and
+// non-printable characters in paths.
+func unescape(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+
+	result := []byte{}
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+4 <= len(value) && isOctal(value[i+1:i+4]) {
+			code, _ := strconv.ParseUint(value[i+1:i+4], 8, 8)
+			result = append(result, byte(code))
+			i += 3
+			continue
+		}
+
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+
+		result = append(result, value[i])
+	}
+
+	return string(result)
+}
+
+func isOctal(value string) bool {
+	for _, char := range value {
+		if char < '0' || char > '7' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newFile describes file of an archive without .MTREE by its tar header.
+func newFile(path string, header *tar.Header) File {
+	file := File{
+		Path: strings.TrimSuffix(path, "/"),
+		Type: FileTypeFile,
+		Mode: strconv.FormatInt(header.Mode&0o7777, 8),
+		Size: header.Size,
+	}
+
+	switch header.Typeflag {
+	case tar.TypeDir:
+		file.Type = FileTypeDir
+		file.Size = 0
+	case tar.TypeSymlink:
+		file.Type = FileTypeLink
+		file.Link = header.Linkname
+		file.Size = 0
+	}
+
+	return file
+}
@@ -0,0 +1,242 @@
+// Package pkginfo reads metadata of package archives built by makepkg:
+// .PKGINFO, .BUILDINFO and .MTREE files stored in the root of archives.
+package pkginfo
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kovetskiy/aurora/pkg/pkgtar"
+	"github.com/reconquest/karma-go"
+)
+
+// PKGINFO is a description of a package written by makepkg.
+type PKGINFO struct {
+	Name         string
+	Base         string
+	Version      string
+	Desc         string
+	URL          string
+	BuildDate    string
+	Packager     string
+	Size         int64
+	Arch         string
+	Groups       []string
+	License      []string
+	Replaces     []string
+	Conflicts    []string
+	Provides     []string
+	Backup       []string
+	Depends      []string
+	OptDepends   []string
+	MakeDepends  []string
+	CheckDepends []string
+}
+
+// Archive is metadata of a package archive.
+type Archive struct {
+	// Size is the size of the archive file and SHA256 is its digest.
+	Size   int64
+	SHA256 string
+
+	Info      *PKGINFO
+	BuildInfo *BUILDINFO
+
+	// Files are files of the package without metadata files like .PKGINFO
+	// listed in order of paths.
+	Files []File
+}
+
+// Read reads metadata of package archive, compression of the archive is
+// detected by its contents. Files are read from .MTREE, archives without
+// .MTREE are listed as is. .BUILDINFO is optional.
+func Read(path string) (*Archive, error) {
+	archive := &Archive{}
+
+	reader, err := pkgtar.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer reader.Close()
+
+	var (
+		mtree   []File
+		headers []File
+	)
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to read package archive",
+			)
+		}
+
+		name := strings.TrimPrefix(header.Name, "./")
+
+		switch name {
+		case ".PKGINFO":
+			archive.Info, err = ParsePKGINFO(reader)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"unable to read .PKGINFO",
+				)
+			}
+
+		case ".BUILDINFO":
+			archive.BuildInfo, err = ParseBUILDINFO(reader)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"unable to read .BUILDINFO",
+				)
+			}
+
+		case ".MTREE":
+			mtree, err = ParseMTREE(reader)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"unable to read .MTREE",
+				)
+			}
+
+		default:
+			if name == "" || strings.HasPrefix(name, ".") {
+				continue
+			}
+
+			headers = append(headers, newFile(name, header))
+		}
+	}
+
+	if archive.Info == nil {
+		return nil, errors.New("package archive has no .PKGINFO")
+	}
+
+	archive.Files = headers
+	if mtree != nil {
+		archive.Files = mtree
+	}
+
+	sort.Slice(archive.Files, func(i, j int) bool {
+		return archive.Files[i].Path < archive.Files[j].Path
+	})
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+
+	archive.Size, err = io.Copy(hash, file)
+	if err != nil {
+		return nil, err
+	}
+
+	archive.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	return archive, nil
+}
+
+// ParsePKGINFO parses .PKGINFO, pkgname and pkgver are required.
+func ParsePKGINFO(reader io.Reader) (*PKGINFO, error) {
+	info := &PKGINFO{}
+
+	var size string
+
+	values := map[string]*string{
+		"pkgname":   &info.Name,
+		"pkgbase":   &info.Base,
+		"pkgver":    &info.Version,
+		"pkgdesc":   &info.Desc,
+		"url":       &info.URL,
+		"builddate": &info.BuildDate,
+		"packager":  &info.Packager,
+		"size":      &size,
+		"arch":      &info.Arch,
+	}
+
+	lists := map[string]*[]string{
+		"group":       &info.Groups,
+		"license":     &info.License,
+		"replaces":    &info.Replaces,
+		"conflict":    &info.Conflicts,
+		"provides":    &info.Provides,
+		"backup":      &info.Backup,
+		"depend":      &info.Depends,
+		"optdepend":   &info.OptDepends,
+		"makedepend":  &info.MakeDepends,
+		"checkdepend": &info.CheckDepends,
+	}
+
+	err := parse(reader, values, lists)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Name == "" || info.Version == "" {
+		return nil, errors.New("pkgname or pkgver is not specified")
+	}
+
+	if size != "" {
+		info.Size, err = strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"invalid size: %s", size,
+			)
+		}
+	}
+
+	return info, nil
+}
+
+// parse parses KEY = VALUE lines of .PKGINFO and .BUILDINFO, unknown keys
+// are skipped.
+func parse(
+	reader io.Reader,
+	values map[string]*string,
+	lists map[string]*[]string,
+) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		parts := strings.SplitN(line, " = ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key, value := parts[0], parts[1]
+
+		if field, ok := values[key]; ok {
+			*field = value
+		}
+
+		if field, ok := lists[key]; ok {
+			*field = append(*field, value)
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,299 @@
+package pkginfo
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/ulikunitz/xz"
+)
+
+const testPKGINFO = `# Generated by makepkg 5.2.2
+pkgname = foo
+pkgbase = foo
+pkgver = 1.0-1
+pkgdesc = foo = bar
+url = https://example.com
+builddate = 1600000000
+packager = Unknown Packager
+size = 1024
+arch = x86_64
+license = MIT
+conflict = foo-git
+provides = libfoo.so=1-64
+depend = glibc
+depend = bar>=2.0
+optdepend = baz: for baz support
+makedepend = go
+`
+
+const testBUILDINFO = `format = 2
+pkgname = foo
+pkgbuild_sha256sum = 0123
+packager = Unknown Packager
+builddate = 1600000000
+builddir = /app/build
+buildenv = !distcc
+buildenv = color
+options = strip
+installed = glibc-2.32-1-x86_64
+installed = go-2:1.15-1-x86_64
+`
+
+const testMTREE = `#mtree
+/set type=file uid=0 gid=0 mode=644
+./.BUILDINFO time=1600000000.0 size=100 md5digest=aa sha256digest=bb
+./.PKGINFO time=1600000000.0 size=200 md5digest=cc sha256digest=dd
+/set mode=755
+./usr time=1600000000.0 type=dir
+./usr/bin time=1600000000.0 type=dir
+./usr/bin/foo time=1600000000.0 size=3 md5digest=ee sha256digest=ff
+./usr/bin/foo-link time=1600000000.0 mode=777 type=link link=foo
+/unset mode
+./usr/share/foo\040bar time=1600000000.0 mode=644 size=4 \
+    sha256digest=11
+`
+
+type testFile struct {
+	name    string
+	content string
+}
+
+func writeArchive(
+	t *testing.T,
+	path string,
+	compress func(io.Writer) io.WriteCloser,
+	files []testFile,
+) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	compressor := compress(file)
+	defer compressor.Close()
+
+	archive := tar.NewWriter(compressor)
+	defer archive.Close()
+
+	for _, item := range files {
+		header := &tar.Header{
+			Name:     item.name,
+			Typeflag: tar.TypeReg,
+			Mode:     0o644,
+			Size:     int64(len(item.content)),
+		}
+
+		if strings.HasSuffix(item.name, "/") {
+			header.Typeflag = tar.TypeDir
+			header.Mode = 0o755
+		}
+
+		err := archive.WriteHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = archive.Write([]byte(item.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func gzipString(t *testing.T, value string) string {
+	buffer := &bytes.Buffer{}
+
+	writer := gzip.NewWriter(buffer)
+
+	_, err := writer.Write([]byte(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.String()
+}
+
+func TestRead(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "pkginfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	compressors := map[string]func(io.Writer) io.WriteCloser{
+		"gz": func(writer io.Writer) io.WriteCloser {
+			return gzip.NewWriter(writer)
+		},
+		"xz": func(writer io.Writer) io.WriteCloser {
+			compressor, err := xz.NewWriter(writer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return compressor
+		},
+		"zst": func(writer io.Writer) io.WriteCloser {
+			compressor, err := zstd.NewWriter(writer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return compressor
+		},
+	}
+
+	for ext, compress := range compressors {
+		path := filepath.Join(dir, "foo-1.0-1-x86_64.pkg.tar."+ext)
+
+		writeArchive(t, path, compress, []testFile{
+			{".PKGINFO", testPKGINFO},
+			{".BUILDINFO", testBUILDINFO},
+			{".MTREE", gzipString(t, testMTREE)},
+			{"usr/", ""},
+			{"usr/bin/", ""},
+			{"usr/bin/foo", "foo"},
+		})
+
+		archive, err := Read(path)
+		test.NoError(err, ext)
+
+		info, err := os.Stat(path)
+		test.NoError(err)
+		test.Equal(info.Size(), archive.Size, ext)
+		test.Len(archive.SHA256, 64, ext)
+
+		test.Equal(
+			&PKGINFO{
+				Name:        "foo",
+				Base:        "foo",
+				Version:     "1.0-1",
+				Desc:        "foo = bar",
+				URL:         "https://example.com",
+				BuildDate:   "1600000000",
+				Packager:    "Unknown Packager",
+				Size:        1024,
+				Arch:        "x86_64",
+				License:     []string{"MIT"},
+				Conflicts:   []string{"foo-git"},
+				Provides:    []string{"libfoo.so=1-64"},
+				Depends:     []string{"glibc", "bar>=2.0"},
+				OptDepends:  []string{"baz: for baz support"},
+				MakeDepends: []string{"go"},
+			},
+			archive.Info,
+			ext,
+		)
+
+		test.Equal(
+			&BUILDINFO{
+				Format:         "2",
+				PkgbuildSHA256: "0123",
+				Packager:       "Unknown Packager",
+				BuildDate:      "1600000000",
+				BuildDir:       "/app/build",
+				BuildEnv:       []string{"!distcc", "color"},
+				Options:        []string{"strip"},
+				Installed: []string{
+					"glibc-2.32-1-x86_64",
+					"go-2:1.15-1-x86_64",
+				},
+			},
+			archive.BuildInfo,
+			ext,
+		)
+
+		test.Equal(
+			[]File{
+				{Path: "usr", Type: "dir", Mode: "755"},
+				{Path: "usr/bin", Type: "dir", Mode: "755"},
+				{Path: "usr/bin/foo", Type: "file", Mode: "755", Size: 3, SHA256: "ff"},
+				{Path: "usr/bin/foo-link", Type: "link", Mode: "777", Link: "foo"},
+				{Path: "usr/share/foo bar", Type: "file", Mode: "644", Size: 4, SHA256: "11"},
+			},
+			archive.Files,
+			ext,
+		)
+	}
+}
+
+func TestRead_WithoutMTREE(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "pkginfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo-1.0-1-x86_64.pkg.tar.gz")
+
+	writeArchive(
+		t, path,
+		func(writer io.Writer) io.WriteCloser {
+			return gzip.NewWriter(writer)
+		},
+		[]testFile{
+			{".PKGINFO", "pkgname = foo\npkgver = 1.0-1\n"},
+			{"usr/", ""},
+			{"usr/bin/foo", "foo"},
+		},
+	)
+
+	archive, err := Read(path)
+	test.NoError(err)
+	test.Nil(archive.BuildInfo)
+	test.Equal(
+		[]File{
+			{Path: "usr", Type: "dir", Mode: "755"},
+			{Path: "usr/bin/foo", Type: "file", Mode: "644", Size: 3},
+		},
+		archive.Files,
+	)
+
+	writeArchive(
+		t, path,
+		func(writer io.Writer) io.WriteCloser {
+			return gzip.NewWriter(writer)
+		},
+		[]testFile{
+			{"usr/bin/foo", "foo"},
+		},
+	)
+
+	_, err = Read(path)
+	test.Error(err)
+}
+
+func TestParsePKGINFO(t *testing.T) {
+	test := assert.New(t)
+
+	_, err := ParsePKGINFO(strings.NewReader("pkgname = foo\n"))
+	test.Error(err)
+
+	_, err = ParsePKGINFO(strings.NewReader("pkgname = foo\npkgver = 1-1\nsize = x\n"))
+	test.Error(err)
+
+	info, err := ParsePKGINFO(strings.NewReader("pkgname = foo\npkgver = 1-1\n"))
+	test.NoError(err)
+	test.Equal(int64(0), info.Size)
+}
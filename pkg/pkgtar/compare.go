@@ -0,0 +1,179 @@
+package pkgtar
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/reconquest/karma-go"
+)
+
+// Entry describes a single file of a package archive.
+type Entry struct {
+	Name     string
+	Type     byte
+	Mode     int64
+	Uid      int
+	Gid      int
+	Size     int64
+	Linkname string
+	ModTime  time.Time
+	Digest   string
+}
+
+// ReadEntries reads all entries of the package archive, contents of regular
+// files are represented by their sha256 digests.
+func ReadEntries(path string) (map[string]Entry, error) {
+	reader, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer reader.Close()
+
+	entries := map[string]Entry{}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to read archive: %s", path,
+			)
+		}
+
+		entry := Entry{
+			Name:     header.Name,
+			Type:     header.Typeflag,
+			Mode:     header.Mode,
+			Uid:      header.Uid,
+			Gid:      header.Gid,
+			Size:     header.Size,
+			Linkname: header.Linkname,
+			ModTime:  header.ModTime,
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			hash := sha256.New()
+
+			_, err := io.Copy(hash, reader)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"unable to read archive file: %s", header.Name,
+				)
+			}
+
+			entry.Digest = hex.EncodeToString(hash.Sum(nil))
+		}
+
+		entries[header.Name] = entry
+	}
+
+	return entries, nil
+}
+
+// Compare compares two package archives file by file and returns list of
+// differences sorted by file name, archives are identical if the list is
+// empty.
+func Compare(a, b string) ([]string, error) {
+	entriesA, err := ReadEntries(a)
+	if err != nil {
+		return nil, err
+	}
+
+	entriesB, err := ReadEntries(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return CompareEntries(entriesA, entriesB), nil
+}
+
+// CompareEntries returns list of differences between two sets of entries.
+func CompareEntries(a, b map[string]Entry) []string {
+	names := []string{}
+	for name := range a {
+		names = append(names, name)
+	}
+
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	diff := []string{}
+	for _, name := range names {
+		entryA, okA := a[name]
+		entryB, okB := b[name]
+
+		switch {
+		case !okB:
+			diff = append(diff, fmt.Sprintf("%s: only in first archive", name))
+		case !okA:
+			diff = append(diff, fmt.Sprintf("%s: only in second archive", name))
+		default:
+			for _, field := range compareEntry(entryA, entryB) {
+				diff = append(diff, fmt.Sprintf("%s: %s", name, field))
+			}
+		}
+	}
+
+	return diff
+}
+
+func compareEntry(a, b Entry) []string {
+	diff := []string{}
+
+	if a.Type != b.Type {
+		diff = append(diff, fmt.Sprintf("type differs (%q != %q)", a.Type, b.Type))
+	}
+
+	if a.Mode != b.Mode {
+		diff = append(diff, fmt.Sprintf("mode differs (%o != %o)", a.Mode, b.Mode))
+	}
+
+	if a.Uid != b.Uid || a.Gid != b.Gid {
+		diff = append(
+			diff,
+			fmt.Sprintf(
+				"owner differs (%d:%d != %d:%d)",
+				a.Uid, a.Gid, b.Uid, b.Gid,
+			),
+		)
+	}
+
+	if a.Linkname != b.Linkname {
+		diff = append(
+			diff,
+			fmt.Sprintf("link differs (%q != %q)", a.Linkname, b.Linkname),
+		)
+	}
+
+	if !a.ModTime.Equal(b.ModTime) {
+		diff = append(
+			diff,
+			fmt.Sprintf(
+				"mtime differs (%d != %d)",
+				a.ModTime.Unix(), b.ModTime.Unix(),
+			),
+		)
+	}
+
+	if a.Size != b.Size {
+		diff = append(diff, fmt.Sprintf("size differs (%d != %d)", a.Size, b.Size))
+	} else if a.Digest != b.Digest {
+		diff = append(diff, "content differs")
+	}
+
+	return diff
+}
@@ -0,0 +1,102 @@
+// Package pkgtar reads package archives built by makepkg, which are tar
+// streams compressed by any of compressors supported in PKGEXT.
+package pkgtar
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+var (
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicXz    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte{'B', 'Z', 'h'}
+)
+
+// Reader reads entries of a package archive.
+type Reader struct {
+	*tar.Reader
+
+	file  *os.File
+	close func()
+}
+
+// Open opens package archive, compression is detected by contents of the
+// file, not by its extension.
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, close, err := Decompress(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Reader{
+		Reader: tar.NewReader(stream),
+		file:   file,
+		close:  close,
+	}, nil
+}
+
+// Close releases the decompressor and closes the file.
+func (reader *Reader) Close() error {
+	reader.close()
+
+	return reader.file.Close()
+}
+
+// Decompress returns decompressed stream of given reader and function which
+// releases resources of the decompressor. Uncompressed streams are returned
+// as is.
+func Decompress(reader io.Reader) (io.Reader, func(), error) {
+	buffered := bufio.NewReader(reader)
+
+	magic, err := buffered.Peek(6)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, magicZstd):
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return decoder, decoder.Close, nil
+
+	case bytes.HasPrefix(magic, magicXz):
+		decoder, err := xz.NewReader(buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return decoder, func() {}, nil
+
+	case bytes.HasPrefix(magic, magicGzip):
+		decoder, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return decoder, func() { decoder.Close() }, nil
+
+	case bytes.HasPrefix(magic, magicBzip2):
+		return bzip2.NewReader(buffered), func() {}, nil
+	}
+
+	return buffered, func() {}, nil
+}
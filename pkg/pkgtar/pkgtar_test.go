@@ -0,0 +1,149 @@
+package pkgtar
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+type testFile struct {
+	name    string
+	content string
+	mode    int64
+	mtime   int64
+}
+
+func writeArchive(
+	t *testing.T,
+	path string,
+	compress func(io.Writer) io.WriteCloser,
+	files []testFile,
+) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	var stream io.Writer = file
+	if compress != nil {
+		compressor := compress(file)
+		defer compressor.Close()
+
+		stream = compressor
+	}
+
+	archive := tar.NewWriter(stream)
+	defer archive.Close()
+
+	for _, item := range files {
+		err := archive.WriteHeader(&tar.Header{
+			Name:     item.name,
+			Typeflag: tar.TypeReg,
+			Mode:     item.mode,
+			Size:     int64(len(item.content)),
+			ModTime:  time.Unix(item.mtime, 0),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = archive.Write([]byte(item.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func compressGzip(writer io.Writer) io.WriteCloser {
+	return gzip.NewWriter(writer)
+}
+
+func compressZstd(writer io.Writer) io.WriteCloser {
+	encoder, err := zstd.NewWriter(writer)
+	if err != nil {
+		panic(err)
+	}
+
+	return encoder
+}
+
+func TestCompare(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "pkgtar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	files := []testFile{
+		{name: ".PKGINFO", content: "pkgname = foo\n", mode: 0o644, mtime: 100},
+		{name: "usr/bin/foo", content: "binary", mode: 0o755, mtime: 100},
+	}
+
+	testcases := []struct {
+		name     string
+		compress func(io.Writer) io.WriteCloser
+		files    []testFile
+		diff     []string
+	}{
+		{
+			name:     "same.pkg.tar",
+			compress: nil,
+			files:    files,
+			diff:     []string{},
+		},
+		{
+			name:     "same.pkg.tar.gz",
+			compress: compressGzip,
+			files:    files,
+			diff:     []string{},
+		},
+		{
+			name:     "content.pkg.tar.zst",
+			compress: compressZstd,
+			files: []testFile{
+				files[0],
+				{name: "usr/bin/foo", content: "BINARY", mode: 0o755, mtime: 100},
+			},
+			diff: []string{"usr/bin/foo: content differs"},
+		},
+		{
+			name:     "meta.pkg.tar.zst",
+			compress: compressZstd,
+			files: []testFile{
+				{name: ".PKGINFO", content: "pkgname = foo\n", mode: 0o600, mtime: 200},
+				{name: "usr/bin/bar", content: "binary", mode: 0o755, mtime: 100},
+			},
+			diff: []string{
+				".PKGINFO: mode differs (644 != 600)",
+				".PKGINFO: mtime differs (100 != 200)",
+				"usr/bin/bar: only in second archive",
+				"usr/bin/foo: only in first archive",
+			},
+		},
+	}
+
+	original := filepath.Join(dir, "original.pkg.tar.zst")
+	writeArchive(t, original, compressZstd, files)
+
+	for _, testcase := range testcases {
+		path := filepath.Join(dir, testcase.name)
+		writeArchive(t, path, testcase.compress, testcase.files)
+
+		diff, err := Compare(original, path)
+		test.NoError(err, testcase.name)
+		test.Equal(testcase.diff, diff, testcase.name)
+	}
+}
@@ -0,0 +1,134 @@
+package proto
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/reconquest/regexputil-go"
+)
+
+const (
+	reArchiveTime = `(?P<time>\d+)`
+	reArchiveName = `(?P<name>[a-zA-Z0-9][a-zA-Z0-9@\._+-]+)`
+	reArchiveVer  = `(?P<ver>[a-zA-Z0-9_.:]+-[0-9]+)`
+	reArchiveArch = `(?P<arch>[a-zA-Z0-9_]+)`
+	reArchiveExt  = `(?P<ext>tar(.(gz|bz2|xz|zst|lrz|lzo|sz))?)`
+)
+
+// ArchiveFilename matches names of archives in repository directory, archives
+// are prefixed by time of build.
+var ArchiveFilename = regexp.MustCompile(
+	`^` + reArchiveTime +
+		`\.` + reArchiveName +
+		`-` + reArchiveVer +
+		`-` + reArchiveArch +
+		`\.pkg\.` + reArchiveExt + `$`,
+)
+
+// ArchitectureAny is the architecture of archives which can be installed on
+// any architecture.
+const ArchitectureAny = "any"
+
+// Archive is an archive of a package retained in repository directory.
+type Archive struct {
+	Filename string    `json:"filename"`
+	Name     string    `json:"name"`
+	Version  string    `json:"version"`
+	Arch     string    `json:"arch"`
+	Built    time.Time `json:"built"`
+}
+
+// ParseArchive parses name of an archive like
+// 1600000000.foo-1.0-1-x86_64.pkg.tar.zst.
+func ParseArchive(filename string) (Archive, bool) {
+	matches := ArchiveFilename.FindStringSubmatch(filename)
+	if matches == nil {
+		return Archive{}, false
+	}
+
+	group := func(name string) string {
+		return regexputil.Subexp(ArchiveFilename, matches, name)
+	}
+
+	built, err := strconv.ParseInt(group("time"), 10, 64)
+	if err != nil {
+		return Archive{}, false
+	}
+
+	return Archive{
+		Filename: filename,
+		Name:     group("name"),
+		Version:  group("ver"),
+		Arch:     group("arch"),
+		Built:    time.Unix(built, 0),
+	}, true
+}
+
+// ArchiveMetadata is metadata of a published archive read from its .PKGINFO,
+// .BUILDINFO and .MTREE.
+type ArchiveMetadata struct {
+	Filename string `bson:"_id" json:"filename"`
+	// Package is the name of aurora package which the archive is built
+	// from, Name is pkgname of the archive.
+	Package       string    `bson:"package" json:"package"`
+	Name          string    `bson:"name" json:"name"`
+	Version       string    `bson:"version" json:"version"`
+	Arch          string    `bson:"arch" json:"arch"`
+	Size          int64     `bson:"size" json:"size"`
+	InstalledSize int64     `bson:"installed_size" json:"installed_size"`
+	SHA256        string    `bson:"sha256" json:"sha256"`
+	Depends       []string  `bson:"depends" json:"depends,omitempty"`
+	Provides      []string  `bson:"provides" json:"provides,omitempty"`
+	Conflicts     []string  `bson:"conflicts" json:"conflicts,omitempty"`
+	Published     time.Time `bson:"published" json:"published"`
+
+	// PkgbuildSHA256 and Installed are read from .BUILDINFO, Installed are
+	// packages installed in the build environment.
+	PkgbuildSHA256 string   `bson:"pkgbuild_sha256" json:"pkgbuild_sha256,omitempty"`
+	Installed      []string `bson:"installed" json:"installed,omitempty"`
+
+	// Files are stored apart in chunks of ArchiveFiles, since files of
+	// large archives don't fit into one document.
+	Files []ArchiveFile `bson:"-" json:"files,omitempty"`
+}
+
+// ArchiveFiles is a chunk of files of a published archive.
+type ArchiveFiles struct {
+	Archive string        `bson:"archive"`
+	Package string        `bson:"package"`
+	Chunk   int           `bson:"chunk"`
+	Files   []ArchiveFile `bson:"files"`
+}
+
+// ArchiveFile is a file of a published archive.
+type ArchiveFile struct {
+	Path   string `bson:"path" json:"path"`
+	Type   string `bson:"type" json:"type"`
+	Mode   string `bson:"mode" json:"mode,omitempty"`
+	Size   int64  `bson:"size" json:"size,omitempty"`
+	Link   string `bson:"link" json:"link,omitempty"`
+	SHA256 string `bson:"sha256" json:"sha256,omitempty"`
+}
+
+// FileMatch is a published archive which files match a search pattern.
+type FileMatch struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+	// Repositories are repositories which the archive is published to,
+	// repositories of archives of other architectures aren't recorded.
+	Repositories []string `json:"repositories,omitempty"`
+	Files        []string `json:"files"`
+}
+
+// RemovedEntry is an entry of a package removed from a repository database
+// along with a removed package.
+type RemovedEntry struct {
+	Repository string `json:"repository"`
+	Arch       string `json:"arch"`
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Filename   string `json:"filename"`
+}
@@ -0,0 +1,52 @@
+package proto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseArchive(t *testing.T) {
+	test := assert.New(t)
+
+	archive, ok := ParseArchive("1600000000.foo-bar-1:2.0_r3-1-x86_64.pkg.tar.zst")
+	test.True(ok)
+	test.Equal(
+		Archive{
+			Filename: "1600000000.foo-bar-1:2.0_r3-1-x86_64.pkg.tar.zst",
+			Name:     "foo-bar",
+			Version:  "1:2.0_r3-1",
+			Arch:     "x86_64",
+			Built:    time.Unix(1600000000, 0),
+		},
+		archive,
+	)
+
+	for _, arch := range []string{"any", "i686", "aarch64", "armv7h", "riscv64"} {
+		archive, ok := ParseArchive("1600000000.foo-1.0-1-" + arch + ".pkg.tar.xz")
+		test.True(ok, arch)
+		test.Equal("foo", archive.Name, arch)
+		test.Equal("1.0-1", archive.Version, arch)
+		test.Equal(arch, archive.Arch)
+	}
+
+	_, ok = ParseArchive("1600000000.foo-1-1-x86_64.pkg.tar.zst.sig")
+	test.False(ok)
+
+	_, ok = ParseArchive("foo-1-1-x86_64.pkg.tar.zst")
+	test.False(ok)
+}
+
+func TestPackage_ArchiveName(t *testing.T) {
+	test := assert.New(t)
+
+	pkg := Package{Name: "foo"}
+	test.Equal("foo", pkg.ArchiveName())
+
+	// the package is cloned from custom URL with different pkgname
+	pkg.Repositories = map[string]PackageRepository{
+		"aurora": {Archive: "1600000000.foo-git-1.0-1-x86_64.pkg.tar.zst"},
+	}
+	test.Equal("foo-git", pkg.ArchiveName())
+}
@@ -0,0 +1,59 @@
+package proto
+
+import "time"
+
+// Build is a record of a single build attempt of a package.
+type Build struct {
+	ID           string       `bson:"_id" json:"id"`
+	Package      string       `bson:"package" json:"package"`
+	Instance     string       `bson:"instance" json:"instance"`
+	Status       string       `bson:"status" json:"status"`
+	Version      string       `bson:"version" json:"version"`
+	Image        string       `bson:"image" json:"image"`
+	ImageDigest  string       `bson:"image_digest" json:"image_digest"`
+	ImageCreated time.Time    `bson:"image_created" json:"image_created"`
+	Forced       bool         `bson:"forced" json:"forced"`
+	Source       int          `bson:"source" json:"source,omitempty"`
+	Commit       string       `bson:"commit" json:"commit,omitempty"`
+	Patches      []string     `bson:"patches" json:"patches,omitempty"`
+	Recipe       []RecipeFile `bson:"recipe" json:"recipe,omitempty"`
+	ReviewDiff   string       `bson:"review_diff" json:"review_diff,omitempty"`
+	Archive      string       `bson:"archive" json:"archive"`
+
+	// Archives are archives built for other architectures of the package by
+	// architectures.
+	Archives map[string]string `bson:"archives" json:"archives,omitempty"`
+
+	Lint          []LintResult `bson:"lint" json:"lint,omitempty"`
+	Error         string       `bson:"error" json:"error"`
+	FailureReason string       `bson:"failure_reason" json:"failure_reason,omitempty"`
+
+	SourceDateEpoch  int64    `bson:"source_date_epoch" json:"source_date_epoch,omitempty"`
+	Reproducible     string   `bson:"reproducible" json:"reproducible,omitempty"`
+	ReproducibleDiff []string `bson:"reproducible_diff" json:"reproducible_diff,omitempty"`
+
+	Started  time.Time `bson:"started" json:"started"`
+	Finished time.Time `bson:"finished" json:"finished"`
+}
+
+// Verdicts of reproducibility check, the check is unknown when the second
+// build failed or archives couldn't be compared.
+const (
+	ReproducibleYes     = "reproducible"
+	ReproducibleNo      = "unreproducible"
+	ReproducibleUnknown = "unknown"
+)
+
+// Severities of lint results.
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+	LintSeverityInfo    = "info"
+)
+
+// LintResult is a single issue reported by namcap for a built archive.
+type LintResult struct {
+	Severity string `bson:"severity" json:"severity"`
+	Tag      string `bson:"tag" json:"tag"`
+	Message  string `bson:"message" json:"message"`
+}
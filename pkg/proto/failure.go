@@ -0,0 +1,23 @@
+package proto
+
+// Machine-readable reasons of build failures, every reason corresponds to
+// a stage of the build process.
+const (
+	FailureReasonClone         = "clone"
+	FailureReasonPatchConflict = "patch-conflict"
+	FailureReasonRejected      = "review-rejected"
+	FailureReasonPkgbuild      = "pkgbuild"
+	FailureReasonPkgver        = "pkgver"
+	FailureReasonDependencies  = "dependencies"
+	FailureReasonDownload      = "download"
+	FailureReasonChecksum      = "checksum"
+	FailureReasonPGP           = "pgp"
+	FailureReasonUnknownPGPKey = "unknown-pgp-key"
+	FailureReasonBuild         = "build"
+	FailureReasonPackage       = "package"
+	FailureReasonLint          = "lint"
+	FailureReasonInstall       = "install"
+	FailureReasonRepoAdd       = "repo-add"
+	FailureReasonTimeout       = "timeout"
+	FailureReasonInternal      = "internal"
+)
@@ -0,0 +1,74 @@
+package proto
+
+import "fmt"
+
+const (
+	// FsckMissingFile is reported for an entry of a repository database
+	// referring to an archive which doesn't exist.
+	FsckMissingFile = "missing-file"
+
+	// FsckChecksumMismatch is reported for an entry of a repository
+	// database which checksum or size differs from the archive.
+	FsckChecksumMismatch = "checksum-mismatch"
+
+	// FsckStrayFile is reported for an archive or a signature which is not
+	// referred by any repository database and doesn't belong to any
+	// package.
+	FsckStrayFile = "stray-file"
+
+	// FsckNoArchive is reported for a package which published archive
+	// doesn't exist.
+	FsckNoArchive = "no-archive"
+
+	// FsckUnpublished is reported for a package which archive exists but is
+	// missing in repository database.
+	FsckUnpublished = "unpublished"
+)
+
+// FsckIssue is an inconsistency between archives in repository directory,
+// repository databases and packages.
+type FsckIssue struct {
+	Kind         string `json:"kind"`
+	Repository   string `json:"repository,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	Package      string `json:"package,omitempty"`
+	File         string `json:"file,omitempty"`
+	Repaired     bool   `json:"repaired"`
+	Error        string `json:"error,omitempty"`
+}
+
+func (issue FsckIssue) String() string {
+	switch issue.Kind {
+	case FsckMissingFile:
+		return fmt.Sprintf(
+			"%s: entry of %s refers to missing file %s",
+			issue.location(), issue.Package, issue.File,
+		)
+	case FsckChecksumMismatch:
+		return fmt.Sprintf(
+			"%s: checksum of %s doesn't match entry of %s",
+			issue.location(), issue.File, issue.Package,
+		)
+	case FsckStrayFile:
+		return fmt.Sprintf("%s is not referred by any repository", issue.File)
+	case FsckNoArchive:
+		return fmt.Sprintf("%s: package %s has no archive", issue.location(), issue.Package)
+	case FsckUnpublished:
+		return fmt.Sprintf(
+			"%s: archive %s of package %s is not in repository",
+			issue.location(), issue.File, issue.Package,
+		)
+	}
+
+	return issue.Kind
+}
+
+// location returns the repository of the issue with architecture of its
+// database, the same way as the repository is served.
+func (issue FsckIssue) location() string {
+	if issue.Architecture == "" {
+		return issue.Repository
+	}
+
+	return issue.Repository + "/" + issue.Architecture
+}
@@ -1,11 +1,15 @@
 package proto
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 type Package struct {
 	Name       string        `bson:"name" json:"name"`
 	CloneURL   string        `bson:"clone_url" json:"clone_url"`
 	Subdir     string        `bson:"subdir" json:"subdir"`
+	Ref        string        `bson:"ref" json:"ref,omitempty"`
 	Version    string        `bson:"version" json:"version"`
 	Status     string        `bson:"status" json:"status"`
 	Instance   string        `bson:"instance" json:"instance"`
@@ -14,4 +18,88 @@ type Package struct {
 	Failures   int           `bson:"failures" json:"failures"`
 	BuildTime  time.Duration `bson:"build_time" json:"build_time"`
 	PkgverTime time.Duration `bson:"pkgver_time" json:"pkgver_time"`
+
+	FailureReason string `bson:"failure_reason" json:"failure_reason,omitempty"`
+
+	// Rebuild forces the next build even if pkgver is not changed, it's set
+	// when settings, patches or recipe of the package are changed and it's
+	// cleared when the build starts.
+	Rebuild bool `bson:"rebuild" json:"rebuild,omitempty"`
+
+	// Source is a version of pushed source, packages without pushed
+	// sources are cloned.
+	Source int `bson:"source" json:"source,omitempty"`
+
+	Env          []string `bson:"env" json:"env,omitempty"`
+	MakepkgFlags []string `bson:"makepkg_flags" json:"makepkg_flags,omitempty"`
+	MakepkgConf  []string `bson:"makepkg_conf" json:"makepkg_conf,omitempty"`
+
+	VerifyReproducible bool `bson:"verify_reproducible" json:"verify_reproducible"`
+
+	// Review enables review of changes of PKGBUILD and install scripts,
+	// recipe of the first build is trusted.
+	Review         bool         `bson:"review" json:"review"`
+	ReviewedRecipe []RecipeFile `bson:"reviewed_recipe" json:"reviewed_recipe,omitempty"`
+	ReviewedBy     string       `bson:"reviewed_by" json:"reviewed_by,omitempty"`
+	PendingRecipe  []RecipeFile `bson:"pending_recipe" json:"pending_recipe,omitempty"`
+	PendingBuild   string       `bson:"pending_build" json:"pending_build,omitempty"`
+	RejectedRecipe []RecipeFile `bson:"rejected_recipe" json:"rejected_recipe,omitempty"`
+
+	// AUR metadata, it's updated periodically for packages cloned from AUR.
+	Maintainer string    `bson:"maintainer" json:"maintainer,omitempty"`
+	OutOfDate  time.Time `bson:"out_of_date" json:"out_of_date"`
+	Deleted    bool      `bson:"deleted" json:"deleted,omitempty"`
+	AURChecked time.Time `bson:"aur_checked" json:"aur_checked"`
+
+	// Superseded is a package of official repositories which provides the
+	// package, like extra/foo 1.0-1.
+	Superseded        string    `bson:"superseded" json:"superseded,omitempty"`
+	SupersededAt      time.Time `bson:"superseded_at" json:"superseded_at"`
+	SupersededRemoved bool      `bson:"superseded_removed" json:"superseded_removed,omitempty"`
+
+	// Architectures are architectures which the package is built for, the
+	// first architecture of aurorad is used if it's empty.
+	Architectures []string `bson:"architectures" json:"architectures,omitempty"`
+
+	// Repositories are versions of the package published to repositories by
+	// names of repositories, Promote is a repository which the package is
+	// requested to be promoted to.
+	Repositories map[string]PackageRepository `bson:"repositories" json:"repositories,omitempty"`
+	Promote      string                       `bson:"promote" json:"promote,omitempty"`
+
+	// Hold is a reason why the package is not being built until somebody
+	// acknowledges it.
+	Hold string `bson:"hold" json:"hold,omitempty"`
+
+	ImageDigest  string    `bson:"image_digest" json:"image_digest"`
+	ImageCreated time.Time `bson:"image_created" json:"image_created"`
+}
+
+// ArchiveName returns pkgname of published archives of the package, it
+// differs from the name of the package when the package is cloned from
+// custom URL or pushed. The name of the package is returned if the package
+// is not published yet.
+func (pkg Package) ArchiveName() string {
+	repositories := []string{}
+	for repository := range pkg.Repositories {
+		repositories = append(repositories, repository)
+	}
+
+	sort.Strings(repositories)
+
+	for _, repository := range repositories {
+		archive, ok := ParseArchive(pkg.Repositories[repository].Archive)
+		if ok {
+			return archive.Name
+		}
+	}
+
+	return pkg.Name
+}
+
+// PackageRepository is a version of a package published to a repository.
+type PackageRepository struct {
+	Version   string    `bson:"version" json:"version"`
+	Archive   string    `bson:"archive" json:"archive"`
+	Published time.Time `bson:"published" json:"published"`
 }
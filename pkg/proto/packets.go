@@ -13,6 +13,8 @@ type RequestListPackages struct {
 type RequestGetPackage struct {
 	Signature *signature.Signature `json:"signature"`
 	Name      string               `json:"name"`
+	// Details requests metadata of published archives of the package.
+	Details bool `json:"details"`
 }
 
 type RequestGetLogs struct {
@@ -30,12 +32,114 @@ type RequestAddPackage struct {
 	Name      string               `json:"name"`
 	CloneURL  string               `json:"clone_url,omitempty"`
 	Subdir    string               `json:"subdir,omitempty"`
+	Ref       string               `json:"ref,omitempty"`
 	Priority  int                  `json:"priority"`
+
+	Architectures []string `json:"architectures,omitempty"`
+
+	Env          []string `json:"env,omitempty"`
+	MakepkgFlags []string `json:"makepkg_flags,omitempty"`
+	MakepkgConf  []string `json:"makepkg_conf,omitempty"`
+
+	VerifyReproducible bool `json:"verify_reproducible,omitempty"`
+	Review             bool `json:"review,omitempty"`
+}
+
+// RequestSetPackage changes settings of existing package, only specified
+// (non-nil) fields are changed.
+type RequestSetPackage struct {
+	Signature *signature.Signature `json:"signature"`
+	Name      string               `json:"name"`
+	Ref       *string              `json:"ref,omitempty"`
+
+	Architectures *[]string `json:"architectures,omitempty"`
+
+	Env          *[]string `json:"env,omitempty"`
+	MakepkgFlags *[]string `json:"makepkg_flags,omitempty"`
+	MakepkgConf  *[]string `json:"makepkg_conf,omitempty"`
+
+	VerifyReproducible *bool `json:"verify_reproducible,omitempty"`
+	Review             *bool `json:"review,omitempty"`
 }
 
 type RequestRemovePackage struct {
 	Signature *signature.Signature `json:"signature"`
 	Name      string               `json:"name"`
+	// KeepFiles moves archives of the package out of the repository
+	// directory instead of removing them.
+	KeepFiles bool `json:"keep_files"`
+}
+
+type RequestUploadPatch struct {
+	Signature *signature.Signature `json:"signature"`
+	Name      string               `json:"name"`
+	Patch     string               `json:"patch"`
+	Content   string               `json:"content"`
+}
+
+type RequestListPatches struct {
+	Signature *signature.Signature `json:"signature"`
+	Name      string               `json:"name"`
+}
+
+type RequestRemovePatch struct {
+	Signature *signature.Signature `json:"signature"`
+	Name      string               `json:"name"`
+	Patch     string               `json:"patch"`
+}
+
+// RequestPushSource uploads a tarball of PKGBUILD directory, the package is
+// created if it doesn't exist yet.
+type RequestPushSource struct {
+	Signature *signature.Signature `json:"signature"`
+	Name      string               `json:"name"`
+	Content   []byte               `json:"content"`
+}
+
+type RequestListBuilds struct {
+	Signature *signature.Signature `json:"signature"`
+	Name      string               `json:"name"`
+	Limit     int                  `json:"limit,omitempty"`
+}
+
+// RequestGetRecipeDiff compares recipes of two builds of a package, latest
+// build is used if BuildB is not specified and latest successful build
+// before BuildB is used if BuildA is not specified.
+type RequestGetRecipeDiff struct {
+	Signature *signature.Signature `json:"signature"`
+	Name      string               `json:"name"`
+	BuildA    string               `json:"build_a,omitempty"`
+	BuildB    string               `json:"build_b,omitempty"`
+}
+
+// RequestReviewPackage approves or rejects pending changes of a package in
+// awaiting-review status.
+type RequestReviewPackage struct {
+	Signature *signature.Signature `json:"signature"`
+	Name      string               `json:"name"`
+}
+
+// RequestAckPackage acknowledges reason of hold of a package, so the package
+// is built again.
+type RequestAckPackage struct {
+	Signature *signature.Signature `json:"signature"`
+	Name      string               `json:"name"`
+}
+
+// RequestPromotePackage requests promotion of a package to given repository,
+// by default the package is promoted to the next repository.
+type RequestPromotePackage struct {
+	Signature  *signature.Signature `json:"signature"`
+	Name       string               `json:"name"`
+	Repository string               `json:"repository"`
+}
+
+// RequestRollback republishes retained archive of given version, archives are
+// only listed if version is not specified.
+type RequestRollback struct {
+	Signature *signature.Signature `json:"signature"`
+	Name      string               `json:"name"`
+	Version   string               `json:"version"`
 }
 
 type ResponseListPackages struct {
@@ -43,7 +147,8 @@ type ResponseListPackages struct {
 }
 
 type ResponseGetPackage struct {
-	Package *Package `json:"package"`
+	Package  *Package          `json:"package"`
+	Archives []ArchiveMetadata `json:"archives,omitempty"`
 }
 
 type ResponseGetLogs struct {
@@ -56,7 +161,70 @@ type ResponseGetBus struct {
 
 type ResponseAddPackage struct{}
 
-type ResponseRemovePackage struct{}
+type ResponseRemovePackage struct {
+	Entries []RemovedEntry `json:"entries"`
+	// Files are archives which were removed or moved to Dir.
+	Files []string `json:"files"`
+	Dir   string   `json:"dir,omitempty"`
+}
+
+type ResponseUploadPatch struct {
+	Patch *Patch `json:"patch"`
+}
+
+type ResponseListPatches struct {
+	Patches []*Patch `json:"patches"`
+}
+
+type ResponseRemovePatch struct {
+	Patch *Patch `json:"patch"`
+}
+
+type ResponseSetPackage struct {
+	Package *Package `json:"package"`
+}
+
+type ResponsePushSource struct {
+	Source *Source `json:"source"`
+}
+
+type ResponseListBuilds struct {
+	Builds []*Build `json:"builds"`
+}
+
+type ResponseGetRecipeDiff struct {
+	BuildA string `json:"build_a"`
+	BuildB string `json:"build_b"`
+	Diff   string `json:"diff"`
+}
+
+type ResponseReviewPackage struct {
+	Package *Package `json:"package"`
+}
+
+type ResponseAckPackage struct {
+	Package *Package `json:"package"`
+}
+
+type ResponseRollback struct {
+	Package  *Package  `json:"package"`
+	Archives []Archive `json:"archives"`
+	Archive  *Archive  `json:"archive"`
+}
+
+type ResponsePromotePackage struct {
+	Package *Package `json:"package"`
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+}
+
+type RequestListSnapshots struct {
+	Signature *signature.Signature `json:"signature"`
+}
+
+type ResponseListSnapshots struct {
+	Snapshots []Snapshot `json:"snapshots"`
+}
 
 type RequestWhoAmI struct {
 	Signature *signature.Signature `json:"signature"`
@@ -65,3 +233,26 @@ type RequestWhoAmI struct {
 type ResponseWhoAmI struct {
 	Name string `json:"name"`
 }
+
+// RequestFsck checks consistency of repositories, found issues are repaired
+// if Repair is true.
+type RequestFsck struct {
+	Signature *signature.Signature `json:"signature"`
+	Repair    bool                 `json:"repair"`
+}
+
+type ResponseFsck struct {
+	Issues []FsckIssue `json:"issues"`
+}
+
+// RequestSearchFiles searches files of published archives, Pattern is a
+// glob pattern unless Regex is true.
+type RequestSearchFiles struct {
+	Signature *signature.Signature `json:"signature"`
+	Pattern   string               `json:"pattern"`
+	Regex     bool                 `json:"regex"`
+}
+
+type ResponseSearchFiles struct {
+	Matches []FileMatch `json:"matches"`
+}
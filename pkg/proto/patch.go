@@ -0,0 +1,24 @@
+package proto
+
+import (
+	"strconv"
+	"time"
+)
+
+// Patch is a versioned patch file which is applied to PKGBUILD directory of
+// a package before building it. Every upload of a patch with the same name
+// creates a new version, removal is recorded as a version too.
+type Patch struct {
+	Package string    `bson:"package" json:"package"`
+	Name    string    `bson:"name" json:"name"`
+	Version int       `bson:"version" json:"version"`
+	Content string    `bson:"content" json:"content,omitempty"`
+	Removed bool      `bson:"removed" json:"removed"`
+	Author  string    `bson:"author" json:"author"`
+	Date    time.Time `bson:"date" json:"date"`
+}
+
+// String returns name of the patch with its version.
+func (patch Patch) String() string {
+	return patch.Name + "@" + strconv.Itoa(patch.Version)
+}
@@ -0,0 +1,37 @@
+package proto
+
+// Recipe is contents of a file of PKGBUILD directory captured during a build,
+// contents are deduplicated by sha256 digest.
+type Recipe struct {
+	Digest  string `bson:"_id" json:"digest"`
+	Content string `bson:"content" json:"content"`
+}
+
+// RecipeFile is a file of PKGBUILD directory captured during a build: the
+// PKGBUILD, .SRCINFO and install scripts.
+type RecipeFile struct {
+	Name   string `bson:"name" json:"name"`
+	Digest string `bson:"digest" json:"digest"`
+}
+
+// RecipeFilesEqual checks that two recipes consist of the same files with the
+// same contents, order of files doesn't matter.
+func RecipeFilesEqual(a, b []RecipeFile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	digests := map[string]string{}
+	for _, file := range a {
+		digests[file.Name] = file.Digest
+	}
+
+	for _, file := range b {
+		digest, ok := digests[file.Name]
+		if !ok || digest != file.Digest {
+			return false
+		}
+	}
+
+	return true
+}
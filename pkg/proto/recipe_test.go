@@ -0,0 +1,56 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecipeFilesEqual(t *testing.T) {
+	test := assert.New(t)
+
+	recipe := []RecipeFile{
+		{Name: "PKGBUILD", Digest: "a"},
+		{Name: "foo.install", Digest: "b"},
+	}
+
+	testcases := []struct {
+		Other []RecipeFile
+		Equal bool
+	}{
+		{
+			[]RecipeFile{
+				{Name: "foo.install", Digest: "b"},
+				{Name: "PKGBUILD", Digest: "a"},
+			},
+			true,
+		},
+		{
+			[]RecipeFile{
+				{Name: "PKGBUILD", Digest: "a"},
+				{Name: "foo.install", Digest: "c"},
+			},
+			false,
+		},
+		{
+			[]RecipeFile{
+				{Name: "PKGBUILD", Digest: "a"},
+			},
+			false,
+		},
+		{
+			[]RecipeFile{
+				{Name: "PKGBUILD", Digest: "a"},
+				{Name: "bar.install", Digest: "b"},
+			},
+			false,
+		},
+		{nil, false},
+	}
+
+	for _, testcase := range testcases {
+		test.Equal(testcase.Equal, RecipeFilesEqual(recipe, testcase.Other))
+	}
+
+	test.True(RecipeFilesEqual(nil, nil))
+}
@@ -0,0 +1,14 @@
+package proto
+
+import "time"
+
+// SnapshotDateFormat is a format of names of snapshots, a snapshot is
+// created once a day at most.
+const SnapshotDateFormat = "2006-01-02"
+
+// Snapshot is an immutable copy of repositories made of hardlinks.
+type Snapshot struct {
+	Name     string    `json:"name"`
+	Date     time.Time `json:"date"`
+	Archives int       `json:"archives"`
+}
@@ -0,0 +1,85 @@
+package proto
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/pkgtar"
+)
+
+// MaxSourceSize limits size of a single pushed PKGBUILD directory tarball,
+// a source is stored in a single MongoDB document which is limited to 16MB.
+const MaxSourceSize = 8 * 1024 * 1024
+
+// Source is a versioned tarball of PKGBUILD directory pushed by a user,
+// packages with a pushed source are built from it instead of cloning.
+// Pushing the same content again doesn't create a new version.
+type Source struct {
+	Package string    `bson:"package" json:"package"`
+	Version int       `bson:"version" json:"version"`
+	Content []byte    `bson:"content" json:"content,omitempty"`
+	Size    int       `bson:"size" json:"size"`
+	Digest  string    `bson:"digest" json:"digest"`
+	Author  string    `bson:"author" json:"author"`
+	Date    time.Time `bson:"date" json:"date"`
+}
+
+// String returns name of the package with version of the source.
+func (source Source) String() string {
+	return source.Package + "@" + strconv.Itoa(source.Version)
+}
+
+// ValidateSource checks that given tarball contains PKGBUILD in its root and
+// nothing but regular files and directories inside of it.
+func ValidateSource(content []byte) error {
+	stream, close, err := pkgtar.Decompress(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+
+	defer close()
+
+	reader := tar.NewReader(stream)
+
+	found := false
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read source tarball: %s", err)
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("file outside of source directory: %q", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeDir:
+		default:
+			return fmt.Errorf(
+				"only regular files and directories are allowed: %q",
+				header.Name,
+			)
+		}
+
+		if name == "PKGBUILD" && header.Typeflag == tar.TypeReg {
+			found = true
+		}
+	}
+
+	if !found {
+		return errors.New("PKGBUILD not found in root of source tarball")
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package proto
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeSource(headers ...*tar.Header) []byte {
+	buffer := &bytes.Buffer{}
+	compressor := gzip.NewWriter(buffer)
+	archive := tar.NewWriter(compressor)
+
+	for _, header := range headers {
+		if header.Typeflag == tar.TypeReg {
+			header.Size = 1
+		}
+
+		err := archive.WriteHeader(header)
+		if err != nil {
+			panic(err)
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			archive.Write([]byte("x"))
+		}
+	}
+
+	archive.Close()
+	compressor.Close()
+
+	return buffer.Bytes()
+}
+
+func TestValidateSource(t *testing.T) {
+	test := assert.New(t)
+
+	testcases := []struct {
+		Content []byte
+		Valid   bool
+	}{
+		{
+			makeSource(
+				&tar.Header{Name: "PKGBUILD", Typeflag: tar.TypeReg},
+				&tar.Header{Name: "foo.install", Typeflag: tar.TypeReg},
+			),
+			true,
+		},
+		{
+			makeSource(
+				&tar.Header{Name: "./", Typeflag: tar.TypeDir},
+				&tar.Header{Name: "./PKGBUILD", Typeflag: tar.TypeReg},
+				&tar.Header{Name: "./files/", Typeflag: tar.TypeDir},
+				&tar.Header{Name: "./files/foo.conf", Typeflag: tar.TypeReg},
+			),
+			true,
+		},
+		{
+			makeSource(
+				&tar.Header{Name: "foo/PKGBUILD", Typeflag: tar.TypeReg},
+			),
+			false,
+		},
+		{
+			makeSource(
+				&tar.Header{Name: "PKGBUILD", Typeflag: tar.TypeReg},
+				&tar.Header{Name: "../evil", Typeflag: tar.TypeReg},
+			),
+			false,
+		},
+		{
+			makeSource(
+				&tar.Header{Name: "PKGBUILD", Typeflag: tar.TypeReg},
+				&tar.Header{Name: "/etc/passwd", Typeflag: tar.TypeReg},
+			),
+			false,
+		},
+		{
+			makeSource(
+				&tar.Header{Name: "PKGBUILD", Typeflag: tar.TypeReg},
+				&tar.Header{
+					Name:     "link",
+					Typeflag: tar.TypeSymlink,
+					Linkname: "/etc/passwd",
+				},
+			),
+			false,
+		},
+		{
+			[]byte("not a tarball"),
+			false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		err := ValidateSource(testcase.Content)
+		if testcase.Valid {
+			test.NoError(err)
+		} else {
+			test.Error(err)
+		}
+	}
+}
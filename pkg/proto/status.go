@@ -18,6 +18,15 @@ var (
 	BuildStatusFailure    BuildStatus = buildStatus{"failure"}
 	BuildStatusSuccess    BuildStatus = buildStatus{"success"}
 	BuildStatusQueued     BuildStatus = buildStatus{"queued"}
+
+	// BuildStatusAwaitingReview means that PKGBUILD or install scripts of
+	// a reviewed package have been changed and the build is stopped until
+	// the change is approved or rejected.
+	BuildStatusAwaitingReview BuildStatus = buildStatus{"awaiting-review"}
+
+	// BuildStatusSuperseded means that the package is available in official
+	// repositories and it's not built anymore.
+	BuildStatusSuperseded BuildStatus = buildStatus{"superseded"}
 )
 
 func (status buildStatus) MarshalJSON() ([]byte, error) {
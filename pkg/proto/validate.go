@@ -1,11 +1,110 @@
 package proto
 
-import "regexp"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
 
 var (
-	rePkgName = regexp.MustCompile(`^[a-z0-9][a-z0-9@\._+-]+$`)
+	rePkgName     = regexp.MustCompile(`^[a-z0-9][a-z0-9@\._+-]+$`)
+	reEnvName     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	reMakepkgFlag = regexp.MustCompile(`^--?[a-zA-Z][a-zA-Z0-9-]*$`)
+	rePatchName   = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9@\._+-]*$`)
+	reRef         = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9\._/+-]*$`)
+	reArch        = regexp.MustCompile(`^[a-z0-9][a-z0-9_]*$`)
 )
 
 func IsValidPackageName(name string) bool {
 	return rePkgName.MatchString(name)
 }
+
+// IsValidPatchName checks that given value can be used as a file name of
+// a patch.
+func IsValidPatchName(name string) bool {
+	return rePatchName.MatchString(name)
+}
+
+// IsValidArchitecture checks that given value is a name of an architecture
+// like x86_64 or aarch64 which can be used as CARCH and as a directory name.
+// Packages are built for specific architectures, so any is not valid.
+func IsValidArchitecture(name string) bool {
+	return reArch.MatchString(name) && name != ArchitectureAny
+}
+
+// IsValidRef checks that given value is a git branch, tag or commit which
+// can be safely passed to git checkout.
+func IsValidRef(ref string) bool {
+	return reRef.MatchString(ref) &&
+		!strings.Contains(ref, "..") &&
+		!strings.HasSuffix(ref, ".lock") &&
+		!strings.HasSuffix(ref, "/")
+}
+
+// IsValidEnv checks that given value is a NAME=VALUE pair which can be passed
+// into a build container. AURORA_ variables are reserved for aurora itself.
+func IsValidEnv(value string) bool {
+	name, body, ok := splitVariable(value)
+	if !ok {
+		return false
+	}
+
+	if strings.HasPrefix(name, "AURORA_") {
+		return false
+	}
+
+	return !strings.ContainsAny(body, "\x00")
+}
+
+// IsValidMakepkgFlag checks that given value is a single makepkg flag without
+// value, like --nocheck or -A.
+func IsValidMakepkgFlag(value string) bool {
+	return reMakepkgFlag.MatchString(value)
+}
+
+// IsValidMakepkgConf checks that given value is a NAME=VALUE line which can be
+// appended to makepkg.conf.
+func IsValidMakepkgConf(value string) bool {
+	_, body, ok := splitVariable(value)
+	if !ok {
+		return false
+	}
+
+	return !strings.ContainsAny(body, "\n\r\x00")
+}
+
+func splitVariable(value string) (string, string, bool) {
+	chunks := strings.SplitN(value, "=", 2)
+	if len(chunks) != 2 {
+		return "", "", false
+	}
+
+	if !reEnvName.MatchString(chunks[0]) {
+		return "", "", false
+	}
+
+	return chunks[0], chunks[1], true
+}
+
+// ValidateBuildSettings validates per-package build environment settings.
+func ValidateBuildSettings(env, flags, conf []string) error {
+	for _, value := range env {
+		if !IsValidEnv(value) {
+			return fmt.Errorf("invalid environment variable: %q", value)
+		}
+	}
+
+	for _, value := range flags {
+		if !IsValidMakepkgFlag(value) {
+			return fmt.Errorf("invalid makepkg flag: %q", value)
+		}
+	}
+
+	for _, value := range conf {
+		if !IsValidMakepkgConf(value) {
+			return fmt.Errorf("invalid makepkg.conf override: %q", value)
+		}
+	}
+
+	return nil
+}
@@ -34,3 +34,147 @@ func TestIsValidPackageName(t *testing.T) {
 		test.Equal(testcase.Valid, actual, testcase.Input)
 	}
 }
+
+func TestIsValidEnv(t *testing.T) {
+	test := assert.New(t)
+
+	testcases := []struct {
+		Input string
+		Valid bool
+	}{
+		{"A=1", true},
+		{"GOFLAGS=-mod=vendor", true},
+		{"_A=", true},
+		{"A", false},
+		{"=1", false},
+		{"1A=1", false},
+		{"A B=1", false},
+		{"AURORA_PACKAGE=foo", false},
+	}
+
+	for _, testcase := range testcases {
+		actual := IsValidEnv(testcase.Input)
+
+		test.Equal(testcase.Valid, actual, testcase.Input)
+	}
+}
+
+func TestIsValidMakepkgFlag(t *testing.T) {
+	test := assert.New(t)
+
+	testcases := []struct {
+		Input string
+		Valid bool
+	}{
+		{"--nocheck", true},
+		{"--skippgpcheck", true},
+		{"-A", true},
+		{"nocheck", false},
+		{"--", false},
+		{"--nocheck;rm", false},
+		{"--config=/tmp/x", false},
+		{"--nocheck --skippgpcheck", false},
+	}
+
+	for _, testcase := range testcases {
+		actual := IsValidMakepkgFlag(testcase.Input)
+
+		test.Equal(testcase.Valid, actual, testcase.Input)
+	}
+}
+
+func TestIsValidMakepkgConf(t *testing.T) {
+	test := assert.New(t)
+
+	testcases := []struct {
+		Input string
+		Valid bool
+	}{
+		{`MAKEFLAGS="-j4"`, true},
+		{`OPTIONS=(!strip docs)`, true},
+		{`CFLAGS="$CFLAGS -O3"`, true},
+		{`MAKEFLAGS`, false},
+		{"MAKEFLAGS=-j4\nrm -rf /", false},
+	}
+
+	for _, testcase := range testcases {
+		actual := IsValidMakepkgConf(testcase.Input)
+
+		test.Equal(testcase.Valid, actual, testcase.Input)
+	}
+}
+
+func TestIsValidPatchName(t *testing.T) {
+	test := assert.New(t)
+
+	testcases := []struct {
+		Input string
+		Valid bool
+	}{
+		{"fix-gcc11.patch", true},
+		{"0001-bump-checksum.diff", true},
+		{"a", true},
+		{".patch", false},
+		{"../PKGBUILD", false},
+		{"a/b.patch", false},
+		{"", false},
+	}
+
+	for _, testcase := range testcases {
+		actual := IsValidPatchName(testcase.Input)
+
+		test.Equal(testcase.Valid, actual, testcase.Input)
+	}
+}
+
+func TestIsValidRef(t *testing.T) {
+	test := assert.New(t)
+
+	testcases := []struct {
+		Input string
+		Valid bool
+	}{
+		{"master", true},
+		{"v1.2.3", true},
+		{"release/2.x", true},
+		{"3f5c2b1e9a0d", true},
+		{"-b", false},
+		{"--upload-pack=evil", false},
+		{"a..b", false},
+		{"master.lock", false},
+		{"feature/", false},
+		{"a b", false},
+		{"", false},
+	}
+
+	for _, testcase := range testcases {
+		actual := IsValidRef(testcase.Input)
+
+		test.Equal(testcase.Valid, actual, testcase.Input)
+	}
+}
+
+func TestIsValidArchitecture(t *testing.T) {
+	test := assert.New(t)
+
+	testcases := []struct {
+		Input string
+		Valid bool
+	}{
+		{"x86_64", true},
+		{"aarch64", true},
+		{"armv7h", true},
+		{"i686", true},
+		{"any", false},
+		{"", false},
+		{"_x", false},
+		{"../x86_64", false},
+		{"X86_64", false},
+	}
+
+	for _, testcase := range testcases {
+		actual := IsValidArchitecture(testcase.Input)
+
+		test.Equal(testcase.Valid, actual, testcase.Input)
+	}
+}
@@ -0,0 +1,404 @@
+package repodb
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/reconquest/karma-go"
+	"github.com/ulikunitz/xz"
+)
+
+// Database is a repository database opened for modification, both .db and
+// .files databases are written on Save. Database is locked using flock(2) on
+// .lck file, so the lock is released even if the process dies.
+type Database struct {
+	// Sign creates detached signature path.sig of given file, databases are
+	// not signed if it's nil.
+	Sign func(path string) error
+
+	path    string
+	lock    *os.File
+	entries map[string]*Entry
+}
+
+// Open locks and reads repository database, path is a path to .db database
+// like /srv/aurora/aurora.db.tar. Database which doesn't exist yet is empty,
+// its directory is created.
+func Open(path string) (*Database, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := os.OpenFile(path+".lck", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to open lock file",
+		)
+	}
+
+	err = syscall.Flock(int(lock.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		lock.Close()
+
+		return nil, karma.Format(
+			err,
+			"unable to lock database",
+		)
+	}
+
+	database := &Database{
+		path:    path,
+		lock:    lock,
+		entries: map[string]*Entry{},
+	}
+
+	err = database.read()
+	if err != nil {
+		database.Close()
+
+		return nil, err
+	}
+
+	return database, nil
+}
+
+// Update opens database, calls given function and saves database if the
+// function succeeds, sign can be nil.
+func Update(
+	path string,
+	sign func(path string) error,
+	update func(*Database) error,
+) error {
+	database, err := Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer database.Close()
+
+	database.Sign = sign
+
+	err = update(database)
+	if err != nil {
+		return err
+	}
+
+	return database.Save()
+}
+
+func (database *Database) read() error {
+	// .files database contains the same desc files plus lists of files
+	for _, path := range []string{FilesPath(database.path), database.path} {
+		entries, err := ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to read %s", path,
+			)
+		}
+
+		for _, entry := range entries {
+			database.entries[entry.Name] = entry
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// Get returns entry of given package or nil.
+func (database *Database) Get(name string) *Entry {
+	return database.entries[name]
+}
+
+// Entries returns all entries sorted by name.
+func (database *Database) Entries() []*Entry {
+	entries := []*Entry{}
+	for _, entry := range database.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries
+}
+
+// Add adds entry into database replacing entry of the same package.
+func (database *Database) Add(entry *Entry) {
+	database.entries[entry.Name] = entry
+}
+
+// Remove removes entry of given package and returns removed entry or nil.
+func (database *Database) Remove(name string) *Entry {
+	entry := database.entries[name]
+
+	delete(database.entries, name)
+
+	return entry
+}
+
+// Save writes .db and .files databases atomically, signs them and creates
+// symlinks without .tar* extension which pacman actually downloads.
+func (database *Database) Save() error {
+	entries := database.Entries()
+
+	for _, path := range []string{database.path, FilesPath(database.path)} {
+		err := write(path, entries, path != database.path)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to write %s", path,
+			)
+		}
+
+		err = symlink(path)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to create symlink to %s", path,
+			)
+		}
+
+		if database.Sign == nil {
+			continue
+		}
+
+		err = database.Sign(path)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to sign %s", path,
+			)
+		}
+
+		err = symlink(path + ".sig")
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to create symlink to signature of %s", path,
+			)
+		}
+	}
+
+	return nil
+}
+
+// Close releases the lock, unsaved changes are discarded.
+func (database *Database) Close() error {
+	return database.lock.Close()
+}
+
+// Path returns path of database of given repository in repository
+// directory.
+func Path(dir string, repository string) string {
+	return filepath.Join(dir, repository+".db.tar")
+}
+
+// ArchPath returns path of database of given repository for given
+// architecture. Databases of the first architecture are stored in repository
+// directory itself, databases of other architectures are stored in
+// subdirectories named after architectures, archives of all architectures
+// are stored in repository directory.
+func ArchPath(
+	dir string,
+	repository string,
+	architectures []string,
+	arch string,
+) string {
+	if len(architectures) == 0 || arch == architectures[0] {
+		return Path(dir, repository)
+	}
+
+	return Path(filepath.Join(dir, arch), repository)
+}
+
+// FilesPath returns path of .files database for given .db database.
+func FilesPath(path string) string {
+	dir, name := filepath.Split(path)
+
+	index := strings.LastIndex(name, ".db")
+	if index == -1 {
+		return path + ".files"
+	}
+
+	return dir + name[:index] + ".files" + name[index+len(".db"):]
+}
+
+func write(path string, entries []*Entry, files bool) error {
+	temp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	compressor, err := compress(temp, path)
+	if err != nil {
+		return err
+	}
+
+	archive := tar.NewWriter(compressor)
+	now := time.Now()
+
+	for _, entry := range entries {
+		err := archive.WriteHeader(&tar.Header{
+			Name:     entry.dir() + "/",
+			Typeflag: tar.TypeDir,
+			Mode:     0o755,
+			ModTime:  now,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = writeFile(archive, entry.dir()+"/desc", formatDesc(entry), now)
+		if err != nil {
+			return err
+		}
+
+		if files {
+			err = writeFile(archive, entry.dir()+"/files", formatFiles(entry), now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return err
+	}
+
+	err = compressor.Close()
+	if err != nil {
+		return err
+	}
+
+	err = temp.Chmod(0o644)
+	if err != nil {
+		return err
+	}
+
+	err = temp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
+
+func writeFile(archive *tar.Writer, name string, content string, now time.Time) error {
+	err := archive.WriteHeader(&tar.Header{
+		Name:     name,
+		Typeflag: tar.TypeReg,
+		Mode:     0o644,
+		Size:     int64(len(content)),
+		ModTime:  now,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(archive, content)
+	return err
+}
+
+func formatDesc(entry *Entry) string {
+	builder := &strings.Builder{}
+	for _, field := range entry.fields() {
+		var values []string
+		switch value := field.value.(type) {
+		case *string:
+			if *value != "" {
+				values = []string{*value}
+			}
+		case *[]string:
+			values = *value
+		}
+
+		if len(values) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(builder, "%%%s%%\n%s\n\n", field.name, strings.Join(values, "\n"))
+	}
+
+	return builder.String()
+}
+
+func formatFiles(entry *Entry) string {
+	if len(entry.Files) == 0 {
+		return ""
+	}
+
+	return "%FILES%\n" + strings.Join(entry.Files, "\n") + "\n"
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// compress returns compressor chosen by extension of the database like
+// repo-add does.
+func compress(writer io.Writer, path string) (io.WriteCloser, error) {
+	switch filepath.Ext(path) {
+	case ".tar":
+		return nopCloser{writer}, nil
+	case ".gz":
+		return gzip.NewWriter(writer), nil
+	case ".zst":
+		return zstd.NewWriter(writer)
+	case ".xz":
+		return xz.NewWriter(writer)
+	}
+
+	return nil, fmt.Errorf("unsupported database extension: %s", path)
+}
+
+// symlink creates symlink like aurora.db pointing to aurora.db.tar or
+// aurora.db.sig pointing to aurora.db.tar.sig.
+func symlink(path string) error {
+	index := strings.LastIndex(path, ".tar")
+	if index == -1 {
+		return nil
+	}
+
+	link := path[:index]
+	if strings.HasSuffix(path, ".sig") {
+		link += ".sig"
+	}
+
+	temp := link + ".tmp"
+
+	os.Remove(temp)
+
+	err := os.Symlink(filepath.Base(path), temp)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp, link)
+}
@@ -0,0 +1,239 @@
+package repodb
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePackage(t *testing.T, path string, files map[string]string) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	compressor, err := zstd.NewWriter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer compressor.Close()
+
+	archive := tar.NewWriter(compressor)
+	defer archive.Close()
+
+	for _, name := range []string{".PKGINFO", ".MTREE", "usr/", "usr/bin/", "usr/bin/foo"} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+
+		header := &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+		}
+		if name[len(name)-1] == '/' {
+			header.Typeflag = tar.TypeDir
+		}
+
+		err := archive.WriteHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = archive.Write([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDatabase(t *testing.T) {
+	test := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "repodb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "foo-1.0-1-x86_64.pkg.tar.zst")
+	writePackage(t, archive, map[string]string{
+		".PKGINFO": `# Generated by makepkg
+pkgname = foo
+pkgbase = foo
+pkgver = 1.0-1
+pkgdesc = Foo = bar
+url = https://example.com
+builddate = 1600000000
+packager = Unknown Packager
+size = 1024
+arch = x86_64
+license = MIT
+provides = libfoo.so=1-64
+depend = glibc
+depend = bash
+makedepend = go
+`,
+		".MTREE":      "",
+		"usr/":        "",
+		"usr/bin/":    "",
+		"usr/bin/foo": "#!/bin/sh\n",
+	})
+
+	test.NoError(ioutil.WriteFile(archive+".sig", []byte("sig"), 0o644))
+
+	entry, err := ReadPackage(archive)
+	test.NoError(err)
+
+	stat, err := os.Stat(archive)
+	test.NoError(err)
+
+	test.Equal("foo-1.0-1-x86_64.pkg.tar.zst", entry.Filename)
+	test.Equal("Foo = bar", entry.Desc)
+	test.Equal([]string{"glibc", "bash"}, entry.Depends)
+	test.Equal([]string{"usr/", "usr/bin/", "usr/bin/foo"}, entry.Files)
+	test.Equal("c2ln", entry.PGPSig)
+	test.Len(entry.SHA256Sum, 64)
+
+	test.Equal(
+		`%FILENAME%
+foo-1.0-1-x86_64.pkg.tar.zst
+
+%NAME%
+foo
+
+%BASE%
+foo
+
+%VERSION%
+1.0-1
+
+%DESC%
+Foo = bar
+
+%CSIZE%
+`+entry.CSize+`
+
+%ISIZE%
+1024
+
+%SHA256SUM%
+`+entry.SHA256Sum+`
+
+%PGPSIG%
+c2ln
+
+%URL%
+https://example.com
+
+%LICENSE%
+MIT
+
+%ARCH%
+x86_64
+
+%BUILDDATE%
+1600000000
+
+%PACKAGER%
+Unknown Packager
+
+%PROVIDES%
+libfoo.so=1-64
+
+%DEPENDS%
+glibc
+bash
+
+%MAKEDEPENDS%
+go
+
+`,
+		formatDesc(entry),
+	)
+	test.Equal(strconv.FormatInt(stat.Size(), 10), entry.CSize)
+
+	path := filepath.Join(dir, "aurora.db.tar")
+
+	signed := []string{}
+	sign := func(path string) error {
+		signed = append(signed, filepath.Base(path))
+		return ioutil.WriteFile(path+".sig", []byte("sig"), 0o644)
+	}
+
+	err = Update(path, sign, func(database *Database) error {
+		database.Add(entry)
+		database.Add(&Entry{Name: "bar", Version: "2-1", Files: []string{"bar"}})
+		return nil
+	})
+	test.NoError(err)
+
+	entries, err := ReadFile(path)
+	test.NoError(err)
+	test.Len(entries, 2)
+	test.Equal("bar", entries[0].Name)
+	test.Nil(entries[0].Files)
+	test.Equal("foo", entries[1].Name)
+
+	entries, err = ReadFile(filepath.Join(dir, "aurora.files.tar"))
+	test.NoError(err)
+	test.Len(entries, 2)
+	test.Equal(entry, entries[1])
+
+	link, err := os.Readlink(filepath.Join(dir, "aurora.db"))
+	test.NoError(err)
+	test.Equal("aurora.db.tar", link)
+
+	link, err = os.Readlink(filepath.Join(dir, "aurora.files"))
+	test.NoError(err)
+	test.Equal("aurora.files.tar", link)
+
+	test.Equal([]string{"aurora.db.tar", "aurora.files.tar"}, signed)
+
+	link, err = os.Readlink(filepath.Join(dir, "aurora.db.sig"))
+	test.NoError(err)
+	test.Equal("aurora.db.tar.sig", link)
+
+	err = Update(path, nil, func(database *Database) error {
+		test.Equal(entry, database.Get("foo"))
+		test.NotNil(database.Remove("bar"))
+		test.Nil(database.Remove("baz"))
+		return nil
+	})
+	test.NoError(err)
+
+	entries, err = ReadFile(path)
+	test.NoError(err)
+	test.Len(entries, 1)
+	test.Equal("foo", entries[0].Name)
+}
+
+func TestArchPath(t *testing.T) {
+	test := assert.New(t)
+
+	architectures := []string{"x86_64", "aarch64"}
+
+	test.Equal("/srv/aurora.db.tar", ArchPath("/srv", "aurora", architectures, "x86_64"))
+	test.Equal("/srv/aarch64/aurora.db.tar", ArchPath("/srv", "aurora", architectures, "aarch64"))
+	test.Equal("/srv/aurora.db.tar", ArchPath("/srv", "aurora", nil, "aarch64"))
+}
+
+func TestFilesPath(t *testing.T) {
+	test := assert.New(t)
+
+	test.Equal("/srv/aurora.files.tar", FilesPath("/srv/aurora.db.tar"))
+	test.Equal("aurora.files.tar.zst", FilesPath("aurora.db.tar.zst"))
+	test.Equal("aurora.files", FilesPath("aurora.db"))
+}
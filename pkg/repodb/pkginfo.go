@@ -0,0 +1,132 @@
+package repodb
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kovetskiy/aurora/pkg/pkginfo"
+	"github.com/kovetskiy/aurora/pkg/pkgtar"
+	"github.com/reconquest/karma-go"
+)
+
+// ReadPackage reads .PKGINFO and list of files of a package archive and
+// returns entry for repository database. Detached signature is included when
+// the archive has .sig file next to it.
+func ReadPackage(path string) (*Entry, error) {
+	archive, err := pkgtar.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer archive.Close()
+
+	var entry *Entry
+	files := []string{}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to read package archive",
+			)
+		}
+
+		name := strings.TrimPrefix(header.Name, "./")
+		if name == ".PKGINFO" {
+			entry, err = readPKGINFO(archive)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"unable to read .PKGINFO",
+				)
+			}
+
+			continue
+		}
+
+		// .PKGINFO, .MTREE, .INSTALL and others are not listed by repo-add
+		if name == "" || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		files = append(files, name)
+	}
+
+	if entry == nil {
+		return nil, errors.New("package archive has no .PKGINFO")
+	}
+
+	sort.Strings(files)
+
+	entry.Files = files
+	entry.Filename = filepath.Base(path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.CSize = strconv.FormatInt(size, 10)
+	entry.SHA256Sum = hex.EncodeToString(hash.Sum(nil))
+
+	signature, err := ioutil.ReadFile(path + ".sig")
+	switch {
+	case err == nil:
+		entry.PGPSig = base64.StdEncoding.EncodeToString(signature)
+	case !os.IsNotExist(err):
+		return nil, karma.Format(
+			err,
+			"unable to read signature of package",
+		)
+	}
+
+	return entry, nil
+}
+
+func readPKGINFO(reader io.Reader) (*Entry, error) {
+	info, err := pkginfo.ParsePKGINFO(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entry{
+		Name:         info.Name,
+		Base:         info.Base,
+		Version:      info.Version,
+		Desc:         info.Desc,
+		URL:          info.URL,
+		BuildDate:    info.BuildDate,
+		Packager:     info.Packager,
+		ISize:        strconv.FormatInt(info.Size, 10),
+		Arch:         info.Arch,
+		Groups:       info.Groups,
+		License:      info.License,
+		Replaces:     info.Replaces,
+		Conflicts:    info.Conflicts,
+		Provides:     info.Provides,
+		Depends:      info.Depends,
+		OptDepends:   info.OptDepends,
+		MakeDepends:  info.MakeDepends,
+		CheckDepends: info.CheckDepends,
+	}, nil
+}
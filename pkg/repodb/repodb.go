@@ -0,0 +1,194 @@
+// Package repodb reads and writes pacman repository databases in the same
+// format as repo-add does.
+package repodb
+
+import (
+	"archive/tar"
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kovetskiy/aurora/pkg/pkgtar"
+	"github.com/reconquest/karma-go"
+)
+
+// Entry is a description of a package in a repository database.
+type Entry struct {
+	Filename     string
+	Name         string
+	Base         string
+	Version      string
+	Desc         string
+	Groups       []string
+	CSize        string
+	ISize        string
+	SHA256Sum    string
+	PGPSig       string
+	URL          string
+	License      []string
+	Arch         string
+	BuildDate    string
+	Packager     string
+	Replaces     []string
+	Conflicts    []string
+	Provides     []string
+	Depends      []string
+	OptDepends   []string
+	MakeDepends  []string
+	CheckDepends []string
+
+	// Files is a list of files of the package, it's stored only in .files
+	// database.
+	Files []string
+}
+
+type field struct {
+	name  string
+	value interface{}
+}
+
+// fields returns fields of desc file in order used by repo-add.
+func (entry *Entry) fields() []field {
+	return []field{
+		{"FILENAME", &entry.Filename},
+		{"NAME", &entry.Name},
+		{"BASE", &entry.Base},
+		{"VERSION", &entry.Version},
+		{"DESC", &entry.Desc},
+		{"GROUPS", &entry.Groups},
+		{"CSIZE", &entry.CSize},
+		{"ISIZE", &entry.ISize},
+		{"SHA256SUM", &entry.SHA256Sum},
+		{"PGPSIG", &entry.PGPSig},
+		{"URL", &entry.URL},
+		{"LICENSE", &entry.License},
+		{"ARCH", &entry.Arch},
+		{"BUILDDATE", &entry.BuildDate},
+		{"PACKAGER", &entry.Packager},
+		{"REPLACES", &entry.Replaces},
+		{"CONFLICTS", &entry.Conflicts},
+		{"PROVIDES", &entry.Provides},
+		{"DEPENDS", &entry.Depends},
+		{"OPTDEPENDS", &entry.OptDepends},
+		{"MAKEDEPENDS", &entry.MakeDepends},
+		{"CHECKDEPENDS", &entry.CheckDepends},
+	}
+}
+
+// dir returns name of directory of the entry in repository database.
+func (entry *Entry) dir() string {
+	return entry.Name + "-" + entry.Version
+}
+
+// StripVersion returns name of a dependency or a provision without version
+// constraint, like foo for foo>=1.0.
+func StripVersion(depend string) string {
+	index := strings.IndexAny(depend, "<>=")
+	if index == -1 {
+		return depend
+	}
+
+	return depend[:index]
+}
+
+// ReadFile reads entries of repository database file.
+func ReadFile(path string) ([]*Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Read reads entries of repository database, compression of the database is
+// detected automatically.
+func Read(reader io.Reader) ([]*Entry, error) {
+	stream, close, err := pkgtar.Decompress(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	defer close()
+
+	archive := tar.NewReader(stream)
+
+	entries := []*Entry{}
+	dirs := map[string]*Entry{}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to read repository database",
+			)
+		}
+
+		dir, name := path.Split(header.Name)
+		if name != "desc" && name != "files" {
+			continue
+		}
+
+		entry, ok := dirs[dir]
+		if !ok {
+			entry = &Entry{}
+			dirs[dir] = entry
+			entries = append(entries, entry)
+		}
+
+		err = readDesc(archive, entry)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to read %s", header.Name,
+			)
+		}
+	}
+
+	return entries, nil
+}
+
+func readDesc(reader io.Reader, entry *Entry) error {
+	fields := map[string]interface{}{"FILES": &entry.Files}
+	for _, field := range entry.fields() {
+		fields[field.name] = field.value
+	}
+
+	var field interface{}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			field = nil
+
+		case field == nil:
+			if strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%") {
+				// unknown fields are skipped
+				field = fields[strings.Trim(line, "%")]
+				if field == nil {
+					field = new(string)
+				}
+			}
+
+		default:
+			switch value := field.(type) {
+			case *string:
+				*value = line
+			case *[]string:
+				*value = append(*value, line)
+			}
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,99 @@
+package repodb
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRead(t *testing.T) {
+	test := assert.New(t)
+
+	buffer := &bytes.Buffer{}
+	compressor := gzip.NewWriter(buffer)
+	archive := tar.NewWriter(compressor)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"foo-1.0-1/", ""},
+		{"foo-1.0-1/desc", `%FILENAME%
+foo-1.0-1-x86_64.pkg.tar.zst
+
+%NAME%
+foo
+
+%BASE%
+foo-base
+
+%VERSION%
+1.0-1
+
+%UNKNOWN%
+whatever
+
+%PROVIDES%
+libfoo.so=1-64
+bar
+
+%DEPENDS%
+glibc>=2.33
+`},
+		{"foo-1.0-1/files", "%FILES%\nusr/bin/foo\n"},
+		{"baz-2:3-4/desc", "%NAME%\nbaz\n\n%VERSION%\n2:3-4\n"},
+	}
+
+	for _, file := range files {
+		header := &tar.Header{
+			Name:     file.name,
+			Typeflag: tar.TypeReg,
+			Size:     int64(len(file.content)),
+			Mode:     0o644,
+		}
+		if file.content == "" {
+			header.Typeflag = tar.TypeDir
+		}
+
+		test.NoError(archive.WriteHeader(header))
+
+		_, err := archive.Write([]byte(file.content))
+		test.NoError(err)
+	}
+
+	test.NoError(archive.Close())
+	test.NoError(compressor.Close())
+
+	entries, err := Read(buffer)
+	test.NoError(err)
+	test.Equal(
+		[]*Entry{
+			{
+				Filename: "foo-1.0-1-x86_64.pkg.tar.zst",
+				Name:     "foo",
+				Base:     "foo-base",
+				Version:  "1.0-1",
+				Provides: []string{"libfoo.so=1-64", "bar"},
+				Depends:  []string{"glibc>=2.33"},
+				Files:    []string{"usr/bin/foo"},
+			},
+			{
+				Name:    "baz",
+				Version: "2:3-4",
+			},
+		},
+		entries,
+	)
+}
+
+func TestStripVersion(t *testing.T) {
+	test := assert.New(t)
+
+	test.Equal("foo", StripVersion("foo"))
+	test.Equal("foo", StripVersion("foo=1.0"))
+	test.Equal("foo", StripVersion("foo>=1.0"))
+	test.Equal("libfoo.so", StripVersion("libfoo.so=1-64"))
+}
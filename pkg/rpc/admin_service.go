@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"net/http"
+
+	"github.com/globalsign/mgo"
+	"github.com/kovetskiy/aurora/pkg/fsck"
+	"github.com/kovetskiy/aurora/pkg/proto"
+)
+
+// AdminService performs maintenance of the repository directory.
+type AdminService struct {
+	auth         *AuthService
+	collection   *mgo.Collection
+	repoDir      string
+	repositories []string
+	// architectures are names of configured architectures, the first one
+	// is the default one.
+	architectures []string
+	sign          func(string) error
+}
+
+func NewAdminService(
+	auth *AuthService,
+	collection *mgo.Collection,
+	repoDir string,
+	repositories []string,
+	architectures []string,
+	sign func(string) error,
+) *AdminService {
+	return &AdminService{
+		auth:          auth,
+		collection:    collection,
+		repoDir:       repoDir,
+		repositories:  repositories,
+		architectures: architectures,
+		sign:          sign,
+	}
+}
+
+func (service *AdminService) Fsck(
+	source *http.Request,
+	request *proto.RequestFsck,
+	response *proto.ResponseFsck,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	issues, err := fsck.Run(
+		service.collection,
+		service.repoDir,
+		service.repositories,
+		service.architectures,
+		service.sign,
+		request.Repair,
+	)
+	if err != nil {
+		return err
+	}
+
+	response.Issues = issues
+
+	return nil
+}
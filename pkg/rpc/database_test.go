@@ -0,0 +1,34 @@
+package rpc
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo"
+)
+
+// newTestDatabase returns an empty database in mongodb specified by
+// AURORA_TEST_DATABASE, the database is dropped after the test. Tests which
+// need a database are skipped if it's not specified.
+func newTestDatabase(t *testing.T) *mgo.Database {
+	dsn := os.Getenv("AURORA_TEST_DATABASE")
+	if dsn == "" {
+		t.Skip("AURORA_TEST_DATABASE is not specified")
+	}
+
+	session, err := mgo.DialWithTimeout(dsn, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	database := session.DB(fmt.Sprintf("aurora_test_%d", time.Now().UnixNano()))
+
+	t.Cleanup(func() {
+		database.DropDatabase()
+		session.Close()
+	})
+
+	return database
+}
@@ -0,0 +1,228 @@
+package rpc
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/pkginfo"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// ArchiveFilesChunk is the max number of files stored in one document of
+// files of an archive.
+const ArchiveFilesChunk = 1000
+
+// PutArchive reads metadata of a published archive of the package and
+// stores it, metadata of the same archive is replaced.
+func PutArchive(
+	archives *mgo.Collection,
+	files *mgo.Collection,
+	pkg string,
+	path string,
+) (*proto.ArchiveMetadata, error) {
+	archive, err := pkginfo.Read(path)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to read metadata of archive %s", path,
+		)
+	}
+
+	metadata := &proto.ArchiveMetadata{
+		Filename:      filepath.Base(path),
+		Package:       pkg,
+		Name:          archive.Info.Name,
+		Version:       archive.Info.Version,
+		Arch:          archive.Info.Arch,
+		Size:          archive.Size,
+		InstalledSize: archive.Info.Size,
+		SHA256:        archive.SHA256,
+		Depends:       archive.Info.Depends,
+		Provides:      archive.Info.Provides,
+		Conflicts:     archive.Info.Conflicts,
+		Published:     time.Now(),
+	}
+
+	if archive.BuildInfo != nil {
+		metadata.PkgbuildSHA256 = archive.BuildInfo.PkgbuildSHA256
+		metadata.Installed = archive.BuildInfo.Installed
+	}
+
+	for _, file := range archive.Files {
+		metadata.Files = append(metadata.Files, proto.ArchiveFile{
+			Path:   file.Path,
+			Type:   file.Type,
+			Mode:   file.Mode,
+			Size:   file.Size,
+			Link:   file.Link,
+			SHA256: file.SHA256,
+		})
+	}
+
+	// files go first, so stored metadata always has its files
+	_, err = files.RemoveAll(bson.M{"archive": metadata.Filename})
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to remove files of archive %s", metadata.Filename,
+		)
+	}
+
+	for chunk := 0; chunk*ArchiveFilesChunk < len(metadata.Files); chunk++ {
+		end := (chunk + 1) * ArchiveFilesChunk
+		if end > len(metadata.Files) {
+			end = len(metadata.Files)
+		}
+
+		err = files.Insert(proto.ArchiveFiles{
+			Archive: metadata.Filename,
+			Package: pkg,
+			Chunk:   chunk,
+			Files:   metadata.Files[chunk*ArchiveFilesChunk : end],
+		})
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to store files of archive %s", metadata.Filename,
+			)
+		}
+	}
+
+	_, err = archives.UpsertId(metadata.Filename, metadata)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to store metadata of archive %s", metadata.Filename,
+		)
+	}
+
+	return metadata, nil
+}
+
+// RemoveArchive removes stored metadata and files of an archive.
+func RemoveArchive(
+	archives *mgo.Collection,
+	files *mgo.Collection,
+	filename string,
+) error {
+	err := archives.RemoveId(filename)
+	if err != nil && err != mgo.ErrNotFound {
+		return karma.Format(
+			err,
+			"unable to remove metadata of archive %s", filename,
+		)
+	}
+
+	_, err = files.RemoveAll(bson.M{"archive": filename})
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to remove files of archive %s", filename,
+		)
+	}
+
+	return nil
+}
+
+// findArchiveFiles fills files of given archives.
+func findArchiveFiles(
+	collection *mgo.Collection,
+	archives []proto.ArchiveMetadata,
+) error {
+	byFilename := map[string]*proto.ArchiveMetadata{}
+	filenames := []string{}
+	for i := range archives {
+		byFilename[archives[i].Filename] = &archives[i]
+		filenames = append(filenames, archives[i].Filename)
+	}
+
+	iter := collection.Find(
+		bson.M{"archive": bson.M{"$in": filenames}},
+	).Sort("archive", "chunk").Iter()
+
+	var chunk proto.ArchiveFiles
+	for iter.Next(&chunk) {
+		archive := byFilename[chunk.Archive]
+		archive.Files = append(archive.Files, chunk.Files...)
+
+		chunk = proto.ArchiveFiles{}
+	}
+
+	err := iter.Close()
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to read files of archives from database",
+		)
+	}
+
+	return nil
+}
+
+// findPublishedArchives returns metadata of archives of the package
+// published to repositories and the newest archives of other architectures,
+// the newest archives go first.
+func findPublishedArchives(
+	collection *mgo.Collection,
+	files *mgo.Collection,
+	pkg *proto.Package,
+) ([]proto.ArchiveMetadata, error) {
+	var archives []proto.ArchiveMetadata
+	err := collection.Find(
+		bson.M{"package": pkg.Name},
+	).Sort("-published").All(&archives)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find archives in database",
+		)
+	}
+
+	archives = selectPublished(pkg, archives)
+
+	err = findArchiveFiles(files, archives)
+	if err != nil {
+		return nil, err
+	}
+
+	return archives, nil
+}
+
+// selectPublished returns archives published to repositories of the
+// package and the newest archives of other architectures, archives should
+// be sorted from the newest to the oldest.
+func selectPublished(
+	pkg *proto.Package,
+	archives []proto.ArchiveMetadata,
+) []proto.ArchiveMetadata {
+	published := map[string]bool{}
+	for _, repository := range pkg.Repositories {
+		published[repository.Archive] = true
+	}
+
+	// archives of other architectures are not recorded in the package
+	recorded := map[string]bool{}
+	for _, archive := range archives {
+		if published[archive.Filename] {
+			recorded[archive.Arch] = true
+		}
+	}
+
+	found := []proto.ArchiveMetadata{}
+	architectures := map[string]bool{}
+	for _, archive := range archives {
+		switch {
+		case published[archive.Filename]:
+		case recorded[archive.Arch], architectures[archive.Arch]:
+			continue
+		}
+
+		found = append(found, archive)
+		architectures[archive.Arch] = true
+	}
+
+	return found
+}
@@ -0,0 +1,44 @@
+package rpc
+
+import (
+	"net/http"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// DefaultBuildsLimit is used when limit of builds is not specified.
+const DefaultBuildsLimit = 20
+
+// ListBuilds returns latest build records of specified package, newest
+// first.
+func (service *PackageService) ListBuilds(
+	source *http.Request,
+	request *proto.RequestListBuilds,
+	response *proto.ResponseListBuilds,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = DefaultBuildsLimit
+	}
+
+	response.Builds = []*proto.Build{}
+
+	err := service.builds.Find(
+		bson.M{"package": request.Name},
+	).Sort("-started").Limit(limit).All(&response.Builds)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find builds in database",
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package rpc
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/pkginfo"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// SearchFiles finds published archives which contain files matching given
+// glob pattern or regular expression, like pacman -F does.
+func (service *PackageService) SearchFiles(
+	source *http.Request,
+	request *proto.RequestSearchFiles,
+	response *proto.ResponseSearchFiles,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	match, err := pkginfo.NewMatcher(request.Pattern, request.Regex)
+	if err != nil {
+		return err
+	}
+
+	var packages []proto.Package
+	err = service.collection.Find(bson.M{}).Select(
+		bson.M{"name": 1, "repositories": 1},
+	).All(&packages)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find packages in database",
+		)
+	}
+
+	var archives []proto.ArchiveMetadata
+	err = service.archives.Find(bson.M{}).Select(
+		bson.M{"package": 1, "name": 1, "version": 1, "arch": 1, "published": 1},
+	).Sort("-published").All(&archives)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find archives in database",
+		)
+	}
+
+	byPackage := map[string][]proto.ArchiveMetadata{}
+	for _, archive := range archives {
+		byPackage[archive.Package] = append(byPackage[archive.Package], archive)
+	}
+
+	repositories := map[string][]string{}
+	published := map[string]proto.ArchiveMetadata{}
+	filenames := []string{}
+	for i := range packages {
+		pkg := &packages[i]
+
+		for _, archive := range selectPublished(pkg, byPackage[pkg.Name]) {
+			published[archive.Filename] = archive
+			filenames = append(filenames, archive.Filename)
+		}
+
+		for _, repository := range service.repositories {
+			archive, ok := pkg.Repositories[repository]
+			if ok {
+				repositories[archive.Archive] = append(
+					repositories[archive.Archive],
+					repository,
+				)
+			}
+		}
+	}
+
+	// files of an archive are stored in several chunks
+	matches := map[string]*proto.FileMatch{}
+
+	iter := service.files.Find(
+		bson.M{"archive": bson.M{"$in": filenames}},
+	).Sort("archive", "chunk").Iter()
+	for {
+		var chunk proto.ArchiveFiles
+		if !iter.Next(&chunk) {
+			break
+		}
+
+		for _, file := range chunk.Files {
+			if !match(file.Path) {
+				continue
+			}
+
+			found, ok := matches[chunk.Archive]
+			if !ok {
+				archive := published[chunk.Archive]
+				found = &proto.FileMatch{
+					Package:      archive.Package,
+					Name:         archive.Name,
+					Version:      archive.Version,
+					Arch:         archive.Arch,
+					Repositories: repositories[archive.Filename],
+				}
+
+				matches[chunk.Archive] = found
+			}
+
+			found.Files = append(found.Files, file.Path)
+		}
+	}
+
+	err = iter.Close()
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to read files of archives from database",
+		)
+	}
+
+	response.Matches = []proto.FileMatch{}
+	for _, found := range matches {
+		response.Matches = append(response.Matches, *found)
+	}
+
+	sort.Slice(response.Matches, func(i, j int) bool {
+		a, b := response.Matches[i], response.Matches[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+
+		return a.Arch < b.Arch
+	})
+
+	return nil
+}
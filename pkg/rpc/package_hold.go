@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// AckPackage acknowledges reason of hold of a package, the package is built
+// again according to its status.
+func (service *PackageService) AckPackage(
+	source *http.Request,
+	request *proto.RequestAckPackage,
+	response *proto.ResponseAckPackage,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	var pkg proto.Package
+	err := service.collection.Find(bson.M{"name": request.Name}).One(&pkg)
+	if err == mgo.ErrNotFound {
+		return errors.New("no such package")
+	}
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find package in database",
+		)
+	}
+
+	if pkg.Hold == "" {
+		return errors.New("package is not on hold")
+	}
+
+	err = service.collection.Update(
+		bson.M{"name": request.Name},
+		bson.M{"$unset": bson.M{"hold": ""}},
+	)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to update package in database",
+		)
+	}
+
+	pkg.Hold = ""
+
+	response.Package = &pkg
+
+	return nil
+}
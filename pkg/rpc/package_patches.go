@@ -0,0 +1,210 @@
+package rpc
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// MaxPatchSize limits size of a single uploaded patch.
+const MaxPatchSize = 1024 * 1024
+
+// FindPatches returns latest versions of patches of specified package sorted
+// by name, removed patches are not returned.
+func FindPatches(collection *mgo.Collection, name string) ([]*proto.Patch, error) {
+	var versions []*proto.Patch
+	err := collection.Find(
+		bson.M{"package": name},
+	).Sort("name", "-version").All(&versions)
+	if err != nil {
+		return nil, err
+	}
+
+	return latestPatches(versions), nil
+}
+
+// latestPatches returns latest versions of patches, versions should be
+// sorted by name and from the newest to the oldest.
+func latestPatches(versions []*proto.Patch) []*proto.Patch {
+	patches := []*proto.Patch{}
+	for i, patch := range versions {
+		if i > 0 && versions[i-1].Name == patch.Name {
+			continue
+		}
+
+		if patch.Removed {
+			continue
+		}
+
+		patches = append(patches, patch)
+	}
+
+	return patches
+}
+
+func (service *PackageService) UploadPatch(
+	source *http.Request,
+	request *proto.RequestUploadPatch,
+	response *proto.ResponseUploadPatch,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	if !proto.IsValidPatchName(request.Patch) {
+		return errors.New("invalid patch name")
+	}
+
+	if len(request.Content) == 0 {
+		return errors.New("patch is empty")
+	}
+
+	if len(request.Content) > MaxPatchSize {
+		return errors.New("patch is too big")
+	}
+
+	patch, err := service.putPatch(request.Name, proto.Patch{
+		Name:    request.Patch,
+		Content: request.Content,
+		Author:  signer.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	response.Patch = patch
+
+	return nil
+}
+
+func (service *PackageService) ListPatches(
+	source *http.Request,
+	request *proto.RequestListPatches,
+	response *proto.ResponseListPatches,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	patches, err := FindPatches(service.patches, request.Name)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find patches in database",
+		)
+	}
+
+	response.Patches = patches
+
+	return nil
+}
+
+func (service *PackageService) RemovePatch(
+	source *http.Request,
+	request *proto.RequestRemovePatch,
+	response *proto.ResponseRemovePatch,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	patches, err := FindPatches(service.patches, request.Name)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find patches in database",
+		)
+	}
+
+	found := false
+	for _, patch := range patches {
+		if patch.Name == request.Patch {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return errors.New("no such patch")
+	}
+
+	patch, err := service.putPatch(request.Name, proto.Patch{
+		Name:    request.Patch,
+		Removed: true,
+		Author:  signer.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	response.Patch = patch
+
+	return nil
+}
+
+// putPatch stores a new version of given patch and requests rebuild of the
+// package.
+func (service *PackageService) putPatch(
+	name string,
+	patch proto.Patch,
+) (*proto.Patch, error) {
+	count, err := service.collection.Find(bson.M{"name": name}).Count()
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to find package in database",
+		)
+	}
+
+	if count == 0 {
+		return nil, errors.New("no such package")
+	}
+
+	var latest proto.Patch
+	err = service.patches.Find(
+		bson.M{"package": name, "name": patch.Name},
+	).Sort("-version").One(&latest)
+	if err != nil && err != mgo.ErrNotFound {
+		return nil, karma.Format(
+			err,
+			"unable to find patch in database",
+		)
+	}
+
+	patch.Package = name
+	patch.Version = latest.Version + 1
+	patch.Date = time.Now()
+
+	err = service.patches.Insert(patch)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to insert patch into database",
+		)
+	}
+
+	// patches usually don't change pkgver, status is not changed since the
+	// package could be being built right now
+	err = service.collection.Update(
+		bson.M{"name": name},
+		bson.M{"$set": bson.M{"rebuild": true}},
+	)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to update package in database",
+		)
+	}
+
+	patch.Content = ""
+
+	return &patch, nil
+}
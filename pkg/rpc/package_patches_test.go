@@ -0,0 +1,117 @@
+package rpc
+
+import (
+	"testing"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestPatches(t *testing.T) {
+	test := assert.New(t)
+
+	test.Equal([]*proto.Patch{}, latestPatches(nil))
+
+	patches := latestPatches([]*proto.Patch{
+		{Name: "a.patch", Version: 2},
+		{Name: "a.patch", Version: 1},
+		{Name: "b.patch", Version: 3, Removed: true},
+		{Name: "b.patch", Version: 2},
+		{Name: "c.patch", Version: 1},
+	})
+
+	test.Equal(
+		[]*proto.Patch{
+			{Name: "a.patch", Version: 2},
+			{Name: "c.patch", Version: 1},
+		},
+		patches,
+	)
+}
+
+func TestFindPatches(t *testing.T) {
+	test := assert.New(t)
+
+	database := newTestDatabase(t)
+	collection := database.C("patches")
+
+	for _, patch := range []proto.Patch{
+		{Package: "foo", Name: "b.patch", Version: 1, Content: "b1"},
+		{Package: "foo", Name: "a.patch", Version: 1, Content: "a1"},
+		{Package: "foo", Name: "a.patch", Version: 2, Content: "a2"},
+		{Package: "foo", Name: "c.patch", Version: 1, Content: "c1"},
+		{Package: "foo", Name: "c.patch", Version: 2, Removed: true},
+		{Package: "bar", Name: "a.patch", Version: 3, Content: "bar"},
+	} {
+		err := collection.Insert(patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	patches, err := FindPatches(collection, "foo")
+	test.NoError(err)
+
+	found := []string{}
+	for _, patch := range patches {
+		found = append(found, patch.String()+" "+patch.Content)
+	}
+
+	test.Equal([]string{"a.patch@2 a2", "b.patch@1 b1"}, found)
+
+	patches, err = FindPatches(collection, "baz")
+	test.NoError(err)
+	test.Empty(patches)
+}
+
+func TestPutPatch(t *testing.T) {
+	test := assert.New(t)
+
+	database := newTestDatabase(t)
+
+	service := &PackageService{
+		collection: database.C("packages"),
+		patches:    database.C("patches"),
+	}
+
+	_, err := service.putPatch("foo", proto.Patch{Name: "a.patch", Content: "a"})
+	test.EqualError(err, "no such package")
+
+	err = service.collection.Insert(proto.Package{
+		Name:    "foo",
+		Version: "1.0-1",
+		Status:  proto.BuildStatusSuccess.String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patch, err := service.putPatch("foo", proto.Patch{Name: "a.patch", Content: "a"})
+	test.NoError(err)
+	test.Equal("foo", patch.Package)
+	test.Equal(1, patch.Version)
+	test.Empty(patch.Content)
+
+	patch, err = service.putPatch("foo", proto.Patch{Name: "a.patch", Removed: true})
+	test.NoError(err)
+	test.Equal(2, patch.Version)
+
+	patch, err = service.putPatch("foo", proto.Patch{Name: "b.patch", Content: "b"})
+	test.NoError(err)
+	test.Equal(1, patch.Version)
+
+	patches, err := FindPatches(service.patches, "foo")
+	test.NoError(err)
+	test.Len(patches, 1)
+	test.Equal("b.patch@1", patches[0].String())
+	test.Equal("b", patches[0].Content)
+
+	// pkgver isn't changed by patches, so the package is rebuilt anyway,
+	// status is kept since the package could be being built
+	var pkg proto.Package
+	err = service.collection.Find(bson.M{"name": "foo"}).One(&pkg)
+	test.NoError(err)
+	test.Equal(proto.BuildStatusSuccess.String(), pkg.Status)
+	test.True(pkg.Rebuild)
+}
@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// FindPromotion returns repositories which a package should be promoted from
+// and to. Packages are promoted from a repository to the next one, if target
+// is not specified the first repository holding a version different from the
+// previous repository is chosen.
+func FindPromotion(
+	repositories []string,
+	pkg proto.Package,
+	target string,
+) (string, string, error) {
+	if len(repositories) < 2 {
+		return "", "", errors.New("only one repository is configured")
+	}
+
+	canPromote := func(from, to string) bool {
+		published, ok := pkg.Repositories[from]
+		return ok && published.Archive != pkg.Repositories[to].Archive
+	}
+
+	if target == "" {
+		for i := 1; i < len(repositories); i++ {
+			from, to := repositories[i-1], repositories[i]
+			if canPromote(from, to) {
+				return from, to, nil
+			}
+		}
+
+		return "", "", errors.New("package has nothing to promote")
+	}
+
+	for i, name := range repositories {
+		if name != target {
+			continue
+		}
+
+		if i == 0 {
+			return "", "", fmt.Errorf(
+				"packages can't be promoted to the first repository %s", target,
+			)
+		}
+
+		from := repositories[i-1]
+		if !canPromote(from, target) {
+			return "", "", fmt.Errorf(
+				"package has nothing to promote from %s to %s", from, target,
+			)
+		}
+
+		return from, target, nil
+	}
+
+	return "", "", fmt.Errorf("no such repository: %s", target)
+}
+
+// PromotePackage requests promotion of a package, promotion itself is
+// performed by the processor.
+func (service *PackageService) PromotePackage(
+	source *http.Request,
+	request *proto.RequestPromotePackage,
+	response *proto.ResponsePromotePackage,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	var pkg proto.Package
+	err := service.collection.Find(bson.M{"name": request.Name}).One(&pkg)
+	if err == mgo.ErrNotFound {
+		return errors.New("no such package")
+	}
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find package in database",
+		)
+	}
+
+	from, to, err := FindPromotion(service.repositories, pkg, request.Repository)
+	if err != nil {
+		return err
+	}
+
+	err = service.collection.Update(
+		bson.M{"name": request.Name},
+		bson.M{"$set": bson.M{"promote": to}},
+	)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to update package in database",
+		)
+	}
+
+	pkg.Promote = to
+
+	response.Package = &pkg
+	response.From = from
+	response.To = to
+
+	return nil
+}
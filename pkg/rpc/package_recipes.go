@@ -0,0 +1,178 @@
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/udiff"
+	"github.com/reconquest/karma-go"
+)
+
+// PutRecipe stores contents of a recipe file, contents which are already
+// stored are not duplicated.
+func PutRecipe(
+	collection *mgo.Collection,
+	name string,
+	content string,
+) (proto.RecipeFile, error) {
+	hash := sha256.Sum256([]byte(content))
+	digest := hex.EncodeToString(hash[:])
+
+	_, err := collection.UpsertId(
+		digest,
+		proto.Recipe{Digest: digest, Content: content},
+	)
+	if err != nil {
+		return proto.RecipeFile{}, karma.Format(
+			err,
+			"unable to store recipe file %s", name,
+		)
+	}
+
+	return proto.RecipeFile{Name: name, Digest: digest}, nil
+}
+
+// RecipeDiff returns unified diff between two recipes, files are compared by
+// their names.
+func RecipeDiff(collection *mgo.Collection, a, b []proto.RecipeFile) (string, error) {
+	contentsA, err := findRecipeContents(collection, a)
+	if err != nil {
+		return "", err
+	}
+
+	contentsB, err := findRecipeContents(collection, b)
+	if err != nil {
+		return "", err
+	}
+
+	names := []string{}
+	for name := range contentsA {
+		names = append(names, name)
+	}
+
+	for name := range contentsB {
+		if _, ok := contentsA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	diff := &strings.Builder{}
+	for _, name := range names {
+		nameA, nameB := "a/"+name, "b/"+name
+
+		if _, ok := contentsA[name]; !ok {
+			nameA = "/dev/null"
+		}
+
+		if _, ok := contentsB[name]; !ok {
+			nameB = "/dev/null"
+		}
+
+		diff.WriteString(
+			udiff.Unified(
+				nameA, nameB,
+				contentsA[name], contentsB[name],
+				udiff.DefaultContext,
+			),
+		)
+	}
+
+	return diff.String(), nil
+}
+
+func findRecipeContents(
+	collection *mgo.Collection,
+	files []proto.RecipeFile,
+) (map[string]string, error) {
+	contents := map[string]string{}
+
+	for _, file := range files {
+		var recipe proto.Recipe
+		err := collection.FindId(file.Digest).One(&recipe)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to find recipe file %s in database", file.Name,
+			)
+		}
+
+		contents[file.Name] = recipe.Content
+	}
+
+	return contents, nil
+}
+
+func (service *PackageService) GetRecipeDiff(
+	source *http.Request,
+	request *proto.RequestGetRecipeDiff,
+	response *proto.ResponseGetRecipeDiff,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	query := bson.M{
+		"package":  request.Name,
+		"recipe.0": bson.M{"$exists": true},
+	}
+
+	var buildB proto.Build
+	var err error
+	if request.BuildB == "" {
+		err = service.builds.Find(query).Sort("-started").One(&buildB)
+	} else {
+		err = service.builds.Find(
+			bson.M{"_id": request.BuildB, "package": request.Name},
+		).One(&buildB)
+	}
+	if err == mgo.ErrNotFound {
+		return errors.New("no such build with recipe")
+	}
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find build in database",
+		)
+	}
+
+	var buildA proto.Build
+	if request.BuildA == "" {
+		query["status"] = proto.BuildStatusSuccess.String()
+		query["started"] = bson.M{"$lt": buildB.Started}
+
+		err = service.builds.Find(query).Sort("-started").One(&buildA)
+	} else {
+		err = service.builds.Find(
+			bson.M{"_id": request.BuildA, "package": request.Name},
+		).One(&buildA)
+	}
+	if err == mgo.ErrNotFound {
+		return errors.New("no build to compare with")
+	}
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find build in database",
+		)
+	}
+
+	response.BuildA = buildA.ID
+	response.BuildB = buildB.ID
+
+	response.Diff, err = RecipeDiff(service.recipes, buildA.Recipe, buildB.Recipe)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,345 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/kovetskiy/aurora/pkg/signature"
+	"github.com/reconquest/karma-go"
+)
+
+// RemovedDir is a directory in repository directory where archives of
+// removed packages are moved to when files are kept.
+const RemovedDir = "removed"
+
+// RemovePackage removes a package from the queue, removes the package and
+// packages split from it from all repositories and removes its archives, so
+// the package isn't installable anymore right after removal.
+func (service *PackageService) RemovePackage(
+	source *http.Request,
+	request *proto.RequestRemovePackage,
+	response *proto.ResponseRemovePackage,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	var pkg proto.Package
+	err := service.collection.Find(bson.M{"name": request.Name}).One(&pkg)
+	if err != nil && err != mgo.ErrNotFound {
+		return karma.Format(
+			err,
+			"unable to find package in database",
+		)
+	}
+
+	// package could have been removed from the queue by previous versions
+	// which kept it in repositories
+	found := err == nil
+
+	// the running build would publish its archive after the removal
+	if found && pkg.Status == proto.BuildStatusProcessing.String() {
+		return errors.New("package is being built, try again later")
+	}
+
+	var archives []proto.ArchiveMetadata
+	err = service.archives.Find(
+		bson.M{"package": request.Name},
+	).Select(bson.M{"_id": 1, "name": 1}).All(&archives)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find archives in database",
+		)
+	}
+
+	names, err := service.getSplitNames(request.Name, archives)
+	if err != nil {
+		return err
+	}
+
+	response.Entries, err = service.removeEntries(names)
+	if err != nil {
+		return err
+	}
+
+	if !found && len(response.Entries) == 0 {
+		return errors.New("no such package")
+	}
+
+	response.Files, err = service.findRemovedFiles(
+		names, response.Entries, archives,
+	)
+	if err != nil {
+		return err
+	}
+
+	if request.KeepFiles {
+		response.Dir = filepath.Join(service.repoDir, RemovedDir)
+	}
+
+	for _, filename := range response.Files {
+		err := service.removeFile(filename, response.Dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = service.archives.RemoveAll(bson.M{"package": request.Name})
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to remove metadata of archives",
+		)
+	}
+
+	_, err = service.files.RemoveAll(bson.M{"package": request.Name})
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to remove files of archives",
+		)
+	}
+
+	if found {
+		err = service.collection.Remove(bson.M{"name": request.Name})
+		if err != nil && err != mgo.ErrNotFound {
+			return karma.Format(
+				err,
+				"unable to remove package from database",
+			)
+		}
+	}
+
+	service.closeBus(pkg.Instance, request.Name, request.Signature)
+
+	return nil
+}
+
+// getSplitNames returns names of packages built from the package, names
+// are taken from metadata of its archives and from repository databases, so
+// packages published before metadata was stored are found too.
+func (service *PackageService) getSplitNames(
+	name string,
+	archives []proto.ArchiveMetadata,
+) ([]string, error) {
+	names := map[string]bool{name: true}
+
+	for _, archive := range archives {
+		names[archive.Name] = true
+	}
+
+	for _, repository := range service.repositories {
+		for _, arch := range service.architectures {
+			path := repodb.ArchPath(
+				service.repoDir, repository, service.architectures, arch,
+			)
+
+			_, err := os.Stat(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			entries, err := repodb.ReadFile(path)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"unable to read repository database %s", path,
+				)
+			}
+
+			for _, entry := range entries {
+				if entry.Base == name {
+					names[entry.Name] = true
+				}
+			}
+		}
+	}
+
+	result := []string{}
+	for split := range names {
+		result = append(result, split)
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// removeEntries removes packages with given names from all repositories of
+// all architectures.
+func (service *PackageService) removeEntries(
+	names []string,
+) ([]proto.RemovedEntry, error) {
+	removed := []proto.RemovedEntry{}
+
+	for _, repository := range service.repositories {
+		for _, arch := range service.architectures {
+			path := repodb.ArchPath(
+				service.repoDir, repository, service.architectures, arch,
+			)
+
+			_, err := os.Stat(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			err = repodb.Update(
+				path,
+				service.sign,
+				func(database *repodb.Database) error {
+					for _, name := range names {
+						entry := database.Remove(name)
+						if entry == nil {
+							continue
+						}
+
+						removed = append(removed, proto.RemovedEntry{
+							Repository: repository,
+							Arch:       arch,
+							Name:       entry.Name,
+							Version:    entry.Version,
+							Filename:   entry.Filename,
+						})
+					}
+
+					return nil
+				},
+			)
+			if err != nil {
+				return nil, karma.Format(
+					err,
+					"unable to remove packages from repository %s (%s)",
+					repository, arch,
+				)
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// findRemovedFiles returns existing archives of removed packages:
+// published archives, archives with stored metadata and retained archives
+// of the same names.
+func (service *PackageService) findRemovedFiles(
+	names []string,
+	entries []proto.RemovedEntry,
+	archives []proto.ArchiveMetadata,
+) ([]string, error) {
+	files := map[string]bool{}
+	for _, entry := range entries {
+		files[entry.Filename] = true
+	}
+
+	for _, archive := range archives {
+		files[archive.Filename] = true
+	}
+
+	for _, split := range names {
+		retained, err := FindArchives(service.repoDir, split)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, archive := range retained {
+			files[archive.Filename] = true
+		}
+	}
+
+	result := []string{}
+	for filename := range files {
+		_, err := os.Stat(filepath.Join(service.repoDir, filename))
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		result = append(result, filename)
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// removeFile removes an archive and its signature from repository
+// directory, the archive is moved to given directory if it's specified.
+func (service *PackageService) removeFile(filename string, dir string) error {
+	if dir != "" {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to create directory %s", dir,
+			)
+		}
+	}
+
+	for _, name := range []string{filename, filename + ".sig"} {
+		path := filepath.Join(service.repoDir, name)
+
+		var err error
+		if dir != "" {
+			err = os.Rename(path, filepath.Join(dir, name))
+		} else {
+			err = os.Remove(path)
+		}
+
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to remove archive %s", name,
+			)
+		}
+	}
+
+	return nil
+}
+
+// closeBus closes the bus topic of the package, so watchers of the package
+// are disconnected, the request is authorized by the signature of the
+// client. The bus lives in memory of the processor, so if the processor
+// isn't reachable there is no topic to close.
+func (service *PackageService) closeBus(
+	instance string,
+	name string,
+	sign *signature.Signature,
+) {
+	body, err := json.Marshal(sign)
+	if err != nil {
+		return
+	}
+
+	request, err := http.NewRequest(
+		http.MethodDelete,
+		service.getBusAddress("http", instance, name),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return
+	}
+
+	response.Body.Close()
+}
@@ -0,0 +1,220 @@
+package rpc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRemoveService(t *testing.T) *PackageService {
+	dir, err := ioutil.TempDir("", "aurora-remove")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	return &PackageService{
+		repoDir:       dir,
+		repositories:  []string{"aurora", "testing"},
+		architectures: []string{"x86_64", "aarch64"},
+	}
+}
+
+func addTestEntries(
+	t *testing.T,
+	service *PackageService,
+	repository string,
+	arch string,
+	entries ...*repodb.Entry,
+) {
+	path := repodb.ArchPath(
+		service.repoDir, repository, service.architectures, arch,
+	)
+
+	err := repodb.Update(path, nil, func(database *repodb.Database) error {
+		for _, entry := range entries {
+			database.Add(entry)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestFiles(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetSplitNames(t *testing.T) {
+	test := assert.New(t)
+
+	service := newTestRemoveService(t)
+
+	names, err := service.getSplitNames("foo", nil)
+	test.NoError(err)
+	test.Equal([]string{"foo"}, names)
+
+	addTestEntries(
+		t, service, "aurora", "x86_64",
+		&repodb.Entry{Name: "foo", Base: "foo", Version: "1-1"},
+		&repodb.Entry{Name: "foo-docs", Base: "foo", Version: "1-1"},
+		&repodb.Entry{Name: "bar", Base: "bar", Version: "1-1"},
+	)
+
+	addTestEntries(
+		t, service, "aurora", "aarch64",
+		&repodb.Entry{Name: "foo-arm", Base: "foo", Version: "1-1"},
+	)
+
+	names, err = service.getSplitNames(
+		"foo",
+		[]proto.ArchiveMetadata{{Name: "foo"}, {Name: "foo-extra"}},
+	)
+	test.NoError(err)
+	test.Equal([]string{"foo", "foo-arm", "foo-docs", "foo-extra"}, names)
+}
+
+func TestRemoveEntries(t *testing.T) {
+	test := assert.New(t)
+
+	service := newTestRemoveService(t)
+
+	addTestEntries(
+		t, service, "aurora", "x86_64",
+		&repodb.Entry{Name: "foo", Version: "1-1", Filename: "1.foo-1-1-x86_64.pkg.tar.zst"},
+		&repodb.Entry{Name: "bar", Version: "1-1", Filename: "1.bar-1-1-x86_64.pkg.tar.zst"},
+	)
+
+	addTestEntries(
+		t, service, "testing", "aarch64",
+		&repodb.Entry{Name: "foo-docs", Version: "1-1", Filename: "1.foo-docs-1-1-any.pkg.tar.zst"},
+	)
+
+	removed, err := service.removeEntries([]string{"foo", "foo-docs"})
+	test.NoError(err)
+	test.Equal(
+		[]proto.RemovedEntry{
+			{
+				Repository: "aurora",
+				Arch:       "x86_64",
+				Name:       "foo",
+				Version:    "1-1",
+				Filename:   "1.foo-1-1-x86_64.pkg.tar.zst",
+			},
+			{
+				Repository: "testing",
+				Arch:       "aarch64",
+				Name:       "foo-docs",
+				Version:    "1-1",
+				Filename:   "1.foo-docs-1-1-any.pkg.tar.zst",
+			},
+		},
+		removed,
+	)
+
+	entries, err := repodb.ReadFile(repodb.Path(service.repoDir, "aurora"))
+	test.NoError(err)
+	test.Len(entries, 1)
+	test.Equal("bar", entries[0].Name)
+
+	// databases which don't exist aren't created
+	_, err = os.Stat(repodb.Path(service.repoDir, "testing"))
+	test.True(os.IsNotExist(err))
+}
+
+func TestFindRemovedFiles(t *testing.T) {
+	test := assert.New(t)
+
+	service := newTestRemoveService(t)
+
+	writeTestFiles(
+		t, service.repoDir,
+		"3.foo-1-2-x86_64.pkg.tar.zst",
+		"2.foo-1-1-x86_64.pkg.tar.zst",
+		"2.foo-1-1-x86_64.pkg.tar.zst.sig",
+		"3.foo-docs-1-2-any.pkg.tar.zst",
+		"1.foo-extra-1-1-x86_64.pkg.tar.zst",
+		"3.foobar-1-1-x86_64.pkg.tar.zst",
+		"3.bar-1-1-x86_64.pkg.tar.zst",
+	)
+
+	files, err := service.findRemovedFiles(
+		[]string{"foo", "foo-docs"},
+		[]proto.RemovedEntry{
+			{Filename: "3.foo-1-2-x86_64.pkg.tar.zst"},
+			{Filename: "4.foo-1-3-x86_64.pkg.tar.zst"},
+		},
+		[]proto.ArchiveMetadata{
+			{Filename: "1.foo-extra-1-1-x86_64.pkg.tar.zst"},
+		},
+	)
+	test.NoError(err)
+	test.Equal(
+		[]string{
+			"1.foo-extra-1-1-x86_64.pkg.tar.zst",
+			"2.foo-1-1-x86_64.pkg.tar.zst",
+			"3.foo-1-2-x86_64.pkg.tar.zst",
+			"3.foo-docs-1-2-any.pkg.tar.zst",
+		},
+		files,
+	)
+}
+
+func TestRemoveFile(t *testing.T) {
+	test := assert.New(t)
+
+	service := newTestRemoveService(t)
+
+	writeTestFiles(
+		t, service.repoDir,
+		"1.foo-1-1-x86_64.pkg.tar.zst",
+		"1.foo-1-1-x86_64.pkg.tar.zst.sig",
+		"2.foo-1-2-x86_64.pkg.tar.zst",
+		"2.foo-1-2-x86_64.pkg.tar.zst.sig",
+		"3.foo-1-3-x86_64.pkg.tar.zst",
+	)
+
+	test.NoError(service.removeFile("1.foo-1-1-x86_64.pkg.tar.zst", ""))
+
+	dir := filepath.Join(service.repoDir, RemovedDir)
+
+	test.NoError(service.removeFile("2.foo-1-2-x86_64.pkg.tar.zst", dir))
+	test.NoError(service.removeFile("3.foo-1-3-x86_64.pkg.tar.zst", dir))
+
+	infos, err := ioutil.ReadDir(service.repoDir)
+	test.NoError(err)
+	test.Len(infos, 1)
+	test.Equal(RemovedDir, infos[0].Name())
+
+	infos, err = ioutil.ReadDir(dir)
+	test.NoError(err)
+
+	kept := []string{}
+	for _, info := range infos {
+		kept = append(kept, info.Name())
+	}
+
+	test.Equal(
+		[]string{
+			"2.foo-1-2-x86_64.pkg.tar.zst",
+			"2.foo-1-2-x86_64.pkg.tar.zst.sig",
+			"3.foo-1-3-x86_64.pkg.tar.zst",
+		},
+		kept,
+	)
+}
@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// ApprovePackage trusts pending recipe of a package in awaiting-review status
+// and queues the package for building.
+func (service *PackageService) ApprovePackage(
+	source *http.Request,
+	request *proto.RequestReviewPackage,
+	response *proto.ResponseReviewPackage,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	return service.review(
+		request.Name,
+		func(pkg *proto.Package) bson.M {
+			return bson.M{
+				"$set": bson.M{
+					"reviewed_recipe": pkg.PendingRecipe,
+					"reviewed_by":     signer.Name,
+					"status":          proto.BuildStatusQueued.String(),
+					// approved change could keep the same pkgver
+					"rebuild": true,
+				},
+				"$unset": bson.M{
+					"pending_recipe":  "",
+					"pending_build":   "",
+					"rejected_recipe": "",
+				},
+			}
+		},
+		response,
+	)
+}
+
+// RejectPackage marks pending recipe of a package in awaiting-review status
+// as rejected, builds with exactly the same recipe fail until a new change
+// is approved.
+func (service *PackageService) RejectPackage(
+	source *http.Request,
+	request *proto.RequestReviewPackage,
+	response *proto.ResponseReviewPackage,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	return service.review(
+		request.Name,
+		func(pkg *proto.Package) bson.M {
+			return bson.M{
+				"$set": bson.M{
+					"rejected_recipe": pkg.PendingRecipe,
+					"reviewed_by":     signer.Name,
+					"status":          proto.BuildStatusFailure.String(),
+					"failure_reason":  proto.FailureReasonRejected,
+					"date":            time.Now(),
+				},
+				"$unset": bson.M{
+					"pending_recipe": "",
+					"pending_build":  "",
+				},
+			}
+		},
+		response,
+	)
+}
+
+func (service *PackageService) review(
+	name string,
+	update func(*proto.Package) bson.M,
+	response *proto.ResponseReviewPackage,
+) error {
+	var pkg proto.Package
+	err := service.collection.Find(bson.M{"name": name}).One(&pkg)
+	if err == mgo.ErrNotFound {
+		return errors.New("no such package")
+	}
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find package in database",
+		)
+	}
+
+	if pkg.Status != proto.BuildStatusAwaitingReview.String() ||
+		len(pkg.PendingRecipe) == 0 {
+		return errors.New("package is not awaiting review")
+	}
+
+	// pending build is a part of the query, so a change which appeared
+	// after the reviewer has seen the diff is not reviewed accidentally
+	err = service.collection.Update(
+		bson.M{
+			"name":          name,
+			"status":        proto.BuildStatusAwaitingReview.String(),
+			"pending_build": pkg.PendingBuild,
+		},
+		update(&pkg),
+	)
+	if err == mgo.ErrNotFound {
+		return errors.New("pending change has been replaced, review it again")
+	}
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to update package in database",
+		)
+	}
+
+	err = service.collection.Find(bson.M{"name": name}).One(&response.Package)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find package in database",
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,276 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/kovetskiy/aurora/pkg/repodb"
+	"github.com/kovetskiy/aurora/pkg/vercmp"
+	"github.com/reconquest/karma-go"
+)
+
+// FindArchives returns archives of given pkgname retained in repository
+// directory, newest first.
+func FindArchives(repoDir string, name string) ([]proto.Archive, error) {
+	paths, err := filepath.Glob(filepath.Join(repoDir, "*."+name+"-*.pkg.*"))
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to glob for archives",
+		)
+	}
+
+	archives := []proto.Archive{}
+	for _, path := range paths {
+		archive, ok := proto.ParseArchive(filepath.Base(path))
+		if !ok || archive.Name != name {
+			continue
+		}
+
+		archives = append(archives, archive)
+	}
+
+	// the newest version first, builds of the same version from the latest
+	sort.Slice(archives, func(i, j int) bool {
+		result := vercmp.Compare(archives[i].Version, archives[j].Version)
+		if result != 0 {
+			return result > 0
+		}
+
+		return archives[i].Built.After(archives[j].Built)
+	})
+
+	return archives, nil
+}
+
+// Rollback lists retained archives of a package and republishes an archive
+// of requested version. The package is put on hold, so the next build doesn't
+// replace the archive until the hold is acknowledged.
+func (service *PackageService) Rollback(
+	source *http.Request,
+	request *proto.RequestRollback,
+	response *proto.ResponseRollback,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	var pkg proto.Package
+	err := service.collection.Find(bson.M{"name": request.Name}).One(&pkg)
+	if err == mgo.ErrNotFound {
+		return errors.New("no such package")
+	}
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find package in database",
+		)
+	}
+
+	response.Package = &pkg
+
+	response.Archives, err = FindArchives(service.repoDir, pkg.ArchiveName())
+	if err != nil {
+		return err
+	}
+
+	if request.Version == "" {
+		return nil
+	}
+
+	architectures := service.getArchitectures(pkg)
+
+	var archive *proto.Archive
+	for i := range response.Archives {
+		candidate := &response.Archives[i]
+		if candidate.Arch != architectures[0] &&
+			candidate.Arch != proto.ArchitectureAny {
+			continue
+		}
+
+		// the newest archive of the version is chosen, pkgrel may be
+		// omitted
+		if vercmp.Compare(candidate.Version, request.Version) == 0 ||
+			candidate.Filename == request.Version {
+			archive = candidate
+			break
+		}
+	}
+
+	if archive == nil {
+		return fmt.Errorf("no retained archive of version %s", request.Version)
+	}
+
+	entries, err := service.readArchives(response.Archives, *archive, architectures)
+	if err != nil {
+		return err
+	}
+
+	published := proto.PackageRepository{
+		Version:   archive.Version,
+		Archive:   archive.Filename,
+		Published: time.Now(),
+	}
+
+	hold := "rolled back to " + archive.Version
+	set := bson.M{
+		"version": archive.Version,
+		"hold":    hold,
+	}
+
+	// the archive replaces the package in all repositories holding it
+	targets := []string{}
+	for _, repository := range service.repositories {
+		if _, ok := pkg.Repositories[repository]; ok {
+			targets = append(targets, repository)
+		}
+	}
+
+	if len(targets) == 0 {
+		targets = service.repositories[:1]
+	}
+
+	for _, repository := range targets {
+		for _, arch := range architectures {
+			entry, ok := entries[arch]
+			if !ok {
+				continue
+			}
+
+			err := repodb.Update(
+				repodb.ArchPath(
+					service.repoDir, repository, service.architectures, arch,
+				),
+				service.sign,
+				func(database *repodb.Database) error {
+					database.Add(entry)
+					return nil
+				},
+			)
+			if err != nil {
+				return karma.Format(
+					err,
+					"unable to update repository %s for %s", repository, arch,
+				)
+			}
+		}
+
+		set["repositories."+repository] = published
+
+		if pkg.Repositories == nil {
+			pkg.Repositories = map[string]proto.PackageRepository{}
+		}
+
+		pkg.Repositories[repository] = published
+	}
+
+	// archives are published again, so they become the current ones
+	stored := map[string]bool{}
+	for _, entry := range entries {
+		if stored[entry.Filename] {
+			continue
+		}
+
+		_, err := PutArchive(
+			service.archives,
+			service.files,
+			pkg.Name,
+			filepath.Join(service.repoDir, entry.Filename),
+		)
+		if err != nil {
+			return err
+		}
+
+		stored[entry.Filename] = true
+	}
+
+	err = service.collection.Update(bson.M{"name": pkg.Name}, bson.M{"$set": set})
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to update package in database",
+		)
+	}
+
+	pkg.Version = archive.Version
+	pkg.Hold = hold
+
+	response.Archive = archive
+
+	return nil
+}
+
+// getArchitectures returns configured architectures which the package is
+// built for, the first one is the architecture of published archives
+// recorded in the package.
+func (service *PackageService) getArchitectures(pkg proto.Package) []string {
+	configured := map[string]bool{}
+	for _, arch := range service.architectures {
+		configured[arch] = true
+	}
+
+	architectures := []string{}
+	for _, arch := range pkg.Architectures {
+		if configured[arch] {
+			architectures = append(architectures, arch)
+		}
+	}
+
+	if len(architectures) == 0 {
+		architectures = service.architectures[:1]
+	}
+
+	return architectures
+}
+
+// readArchives reads entries of archives of the same version as the given
+// archive for given architectures. Architectures without such archive are
+// skipped, the archive which can be installed on any architecture is used
+// for all architectures.
+func (service *PackageService) readArchives(
+	archives []proto.Archive,
+	archive proto.Archive,
+	architectures []string,
+) (map[string]*repodb.Entry, error) {
+	chosen := map[string]proto.Archive{}
+	for _, arch := range architectures {
+		if archive.Arch == proto.ArchitectureAny || arch == archive.Arch {
+			chosen[arch] = archive
+			continue
+		}
+
+		// archives are sorted, so the newest archive of the version goes
+		// first
+		for _, candidate := range archives {
+			if candidate.Arch == arch && candidate.Version == archive.Version {
+				chosen[arch] = candidate
+				break
+			}
+		}
+	}
+
+	entries := map[string]*repodb.Entry{}
+	for arch, archive := range chosen {
+		entry, err := repodb.ReadPackage(
+			filepath.Join(service.repoDir, archive.Filename),
+		)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to read archive %s", archive.Filename,
+			)
+		}
+
+		entries[arch] = entry
+	}
+
+	return entries, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"time"
@@ -17,34 +18,80 @@ import (
 
 var ErrorUnauthorized = errors.New("you are not authorized to perform this action")
 
-// PackageService handles all interactions with packages, including:
-//
-// - adding/removing a package to the queue
-// - retrieving list of packages
-// - retrieving info about a package
-// - retrieving logs after build
-// - watching logs from bus
+// PackageService handles packages: the queue and build settings, patches and
+// pushed sources, builds and reviews, published versions and archives.
 //
 // Should be splitted into several services in order to decrease
 // responsibilities.
 type PackageService struct {
 	collection *mgo.Collection
+	patches    *mgo.Collection
+	builds     *mgo.Collection
+	sources    *mgo.Collection
+	recipes    *mgo.Collection
+	archives   *mgo.Collection
+	files      *mgo.Collection
 	auth       *AuthService
 	logsDir    string
 	instance   string
+
+	// repositories are names of repositories in order of promotion, they
+	// are stored in repoDir and signed using sign if it's not nil
+	repositories []string
+	repoDir      string
+	sign         func(path string) error
+
+	// architectures are names of configured architectures, the first one
+	// is the default one
+	architectures []string
+}
+
+// PackageServiceConfig is a set of collections and settings of aurorad used
+// by PackageService.
+type PackageServiceConfig struct {
+	Packages     *mgo.Collection
+	Patches      *mgo.Collection
+	Builds       *mgo.Collection
+	Sources      *mgo.Collection
+	Recipes      *mgo.Collection
+	Archives     *mgo.Collection
+	ArchiveFiles *mgo.Collection
+
+	LogsDir  string
+	Instance string
+
+	// Repositories are names of repositories in order of promotion, they
+	// are stored in RepoDir and signed using Sign if it's not nil.
+	Repositories []string
+	RepoDir      string
+	Sign         func(path string) error
+
+	// Architectures are names of configured architectures, the first one
+	// is the default one.
+	Architectures []string
 }
 
 func NewPackageService(
-	collection *mgo.Collection,
 	auth *AuthService,
-	logsDir string,
-	instance string,
+	config PackageServiceConfig,
 ) *PackageService {
 	return &PackageService{
-		collection: collection,
-		logsDir:    logsDir,
+		collection: config.Packages,
+		patches:    config.Patches,
+		builds:     config.Builds,
+		sources:    config.Sources,
+		recipes:    config.Recipes,
+		archives:   config.Archives,
+		files:      config.ArchiveFiles,
+		logsDir:    config.LogsDir,
 		auth:       auth,
-		instance:   instance,
+		instance:   config.Instance,
+
+		repositories: config.Repositories,
+		repoDir:      config.RepoDir,
+		sign:         config.Sign,
+
+		architectures: config.Architectures,
 	}
 }
 
@@ -93,6 +140,17 @@ func (service *PackageService) GetPackage(
 		)
 	}
 
+	if request.Details {
+		response.Archives, err = findPublishedArchives(
+			service.archives,
+			service.files,
+			response.Package,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -155,22 +213,30 @@ func (service *PackageService) GetBus(
 		return errors.New("no such package")
 	}
 
-	instance := pkg.Instance
+	response.Stream = service.getBusAddress("ws", pkg.Instance, request.Name)
+
+	return nil
+}
+
+// getBusAddress returns address of bus server of the processor instance
+// which processes the package.
+func (service *PackageService) getBusAddress(
+	scheme string,
+	instance string,
+	name string,
+) string {
 	if instance == "" {
 		instance = service.instance
 	}
 
 	// here can be complex logic with retrieving address of processor
-	address := fmt.Sprintf(
-		"ws://%s:%d/?package=%s",
+	return fmt.Sprintf(
+		"%s://%s:%d/?package=%s",
+		scheme,
 		instance,
 		proto.DefaultBusServerPort,
-		request.Name,
+		url.QueryEscape(name),
 	)
-
-	response.Stream = address
-
-	return nil
 }
 
 func (service *PackageService) AddPackage(
@@ -187,14 +253,40 @@ func (service *PackageService) AddPackage(
 		return errors.New("invalid package name")
 	}
 
-	err := service.collection.Insert(
+	if request.Ref != "" && !proto.IsValidRef(request.Ref) {
+		return errors.New("invalid git ref")
+	}
+
+	err := proto.ValidateBuildSettings(
+		request.Env,
+		request.MakepkgFlags,
+		request.MakepkgConf,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = service.validateArchitectures(request.Architectures)
+	if err != nil {
+		return err
+	}
+
+	err = service.collection.Insert(
 		proto.Package{
-			Name:     request.Name,
-			Status:   proto.BuildStatusQueued.String(),
-			Date:     time.Now(),
-			CloneURL: request.CloneURL,
-			Subdir:   request.Subdir,
-			Priority: request.Priority,
+			Name:         request.Name,
+			Status:       proto.BuildStatusQueued.String(),
+			Date:         time.Now(),
+			CloneURL:     request.CloneURL,
+			Subdir:       request.Subdir,
+			Ref:          request.Ref,
+			Priority:     request.Priority,
+			Env:          request.Env,
+			MakepkgFlags: request.MakepkgFlags,
+			MakepkgConf:  request.MakepkgConf,
+
+			Architectures:      request.Architectures,
+			VerifyReproducible: request.VerifyReproducible,
+			Review:             request.Review,
 		},
 	)
 
@@ -207,19 +299,128 @@ func (service *PackageService) AddPackage(
 	}
 }
 
-func (service *PackageService) RemovePackage(
+// validateArchitectures checks that packages can be built for given
+// architectures, no architectures means the default one.
+func (service *PackageService) validateArchitectures(architectures []string) error {
+	configured := map[string]bool{}
+	for _, arch := range service.architectures {
+		configured[arch] = true
+	}
+
+	seen := map[string]bool{}
+	for _, arch := range architectures {
+		if !proto.IsValidArchitecture(arch) {
+			return fmt.Errorf("invalid architecture: %q", arch)
+		}
+
+		if !configured[arch] {
+			return fmt.Errorf("architecture is not configured: %s", arch)
+		}
+
+		if seen[arch] {
+			return fmt.Errorf("duplicate architecture: %s", arch)
+		}
+
+		seen[arch] = true
+	}
+
+	return nil
+}
+
+func (service *PackageService) SetPackage(
 	source *http.Request,
-	request *proto.RequestRemovePackage,
-	response *proto.ResponseRemovePackage,
+	request *proto.RequestSetPackage,
+	response *proto.ResponseSetPackage,
 ) error {
 	signer := service.auth.Verify(request.Signature)
 	if signer == nil {
 		return ErrorUnauthorized
 	}
 
-	err := service.collection.Remove(
+	var (
+		set   = bson.M{}
+		env   []string
+		flags []string
+		conf  []string
+	)
+
+	if request.Ref != nil {
+		if *request.Ref != "" && !proto.IsValidRef(*request.Ref) {
+			return errors.New("invalid git ref")
+		}
+
+		set["ref"] = *request.Ref
+	}
+
+	if request.Env != nil {
+		env = *request.Env
+		set["env"] = env
+	}
+
+	if request.MakepkgFlags != nil {
+		flags = *request.MakepkgFlags
+		set["makepkg_flags"] = flags
+	}
+
+	if request.MakepkgConf != nil {
+		conf = *request.MakepkgConf
+		set["makepkg_conf"] = conf
+	}
+
+	if request.VerifyReproducible != nil {
+		set["verify_reproducible"] = *request.VerifyReproducible
+	}
+
+	if request.Review != nil {
+		set["review"] = *request.Review
+	}
+
+	if request.Architectures != nil {
+		err := service.validateArchitectures(*request.Architectures)
+		if err != nil {
+			return err
+		}
+
+		set["architectures"] = *request.Architectures
+	}
+
+	err := proto.ValidateBuildSettings(env, flags, conf)
+	if err != nil {
+		return err
+	}
+
+	if len(set) == 0 {
+		return errors.New("nothing to set")
+	}
+
+	// settings are changed, so package should be rebuilt as soon as possible
+	// even if pkgver is not changed, status is not changed since the package
+	// could be being built right now
+	set["rebuild"] = true
+
+	err = service.collection.Update(
 		bson.M{"name": request.Name},
+		bson.M{"$set": set},
 	)
+	if err == mgo.ErrNotFound {
+		return errors.New("no such package")
+	}
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to update package in database",
+		)
+	}
 
-	return err
+	err = service.collection.Find(
+		bson.M{"name": request.Name},
+	).One(&response.Package)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to find package in database",
+		)
+	}
+
+	return nil
 }
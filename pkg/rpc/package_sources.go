@@ -0,0 +1,129 @@
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// FindSource returns specified version of pushed source of the package.
+func FindSource(
+	collection *mgo.Collection,
+	name string,
+	version int,
+) (*proto.Source, error) {
+	var source proto.Source
+	err := collection.Find(
+		bson.M{"package": name, "version": version},
+	).One(&source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &source, nil
+}
+
+// PushSource stores a new version of PKGBUILD directory and queues the
+// package for building, the package is created if it doesn't exist. Pushed
+// source takes precedence over clone URL of the package.
+func (service *PackageService) PushSource(
+	source *http.Request,
+	request *proto.RequestPushSource,
+	response *proto.ResponsePushSource,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	if !proto.IsValidPackageName(request.Name) {
+		return errors.New("invalid package name")
+	}
+
+	if len(request.Content) == 0 {
+		return errors.New("source is empty")
+	}
+
+	if len(request.Content) > proto.MaxSourceSize {
+		return errors.New("source is too big")
+	}
+
+	err := proto.ValidateSource(request.Content)
+	if err != nil {
+		return karma.Format(
+			err,
+			"invalid source",
+		)
+	}
+
+	hash := sha256.Sum256(request.Content)
+	digest := hex.EncodeToString(hash[:])
+
+	var latest proto.Source
+	err = service.sources.Find(
+		bson.M{"package": request.Name},
+	).Sort("-version").One(&latest)
+	if err != nil && err != mgo.ErrNotFound {
+		return karma.Format(
+			err,
+			"unable to find source in database",
+		)
+	}
+
+	pushed := latest
+	if latest.Digest != digest {
+		pushed = proto.Source{
+			Package: request.Name,
+			Version: latest.Version + 1,
+			Content: request.Content,
+			Size:    len(request.Content),
+			Digest:  digest,
+			Author:  signer.Name,
+			Date:    time.Now(),
+		}
+
+		err = service.sources.Insert(pushed)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to insert source into database",
+			)
+		}
+	}
+
+	_, err = service.collection.Upsert(
+		bson.M{"name": request.Name},
+		bson.M{
+			"$set": bson.M{
+				"source": pushed.Version,
+				// fixed PKGBUILD is often pushed with the same pkgver
+				"rebuild": true,
+			},
+			// status of existing package is not changed since it could be
+			// being built right now
+			"$setOnInsert": bson.M{
+				"status": proto.BuildStatusQueued.String(),
+				"date":   time.Now(),
+			},
+		},
+	)
+	if err != nil {
+		return karma.Format(
+			err,
+			"unable to update package in database",
+		)
+	}
+
+	pushed.Content = nil
+
+	response.Source = &pushed
+
+	return nil
+}
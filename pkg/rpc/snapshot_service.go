@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kovetskiy/aurora/pkg/proto"
+	"github.com/reconquest/karma-go"
+)
+
+// SnapshotService lists dated snapshots of repositories, snapshots
+// themselves are created by the processor and served as static files.
+type SnapshotService struct {
+	auth *AuthService
+	dir  string
+}
+
+func NewSnapshotService(auth *AuthService, dir string) *SnapshotService {
+	return &SnapshotService{
+		auth: auth,
+		dir:  dir,
+	}
+}
+
+// FindSnapshots returns snapshots stored in given directory, newest first.
+func FindSnapshots(dir string) ([]proto.Snapshot, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []proto.Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"unable to read snapshots directory",
+		)
+	}
+
+	snapshots := []proto.Snapshot{}
+	for _, info := range infos {
+		// snapshots being created are hidden
+		if !info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+
+		date, err := time.Parse(proto.SnapshotDateFormat, info.Name())
+		if err != nil {
+			continue
+		}
+
+		files, err := ioutil.ReadDir(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to read snapshot %s", info.Name(),
+			)
+		}
+
+		archives := 0
+		for _, file := range files {
+			if strings.Contains(file.Name(), ".pkg.tar") &&
+				!strings.HasSuffix(file.Name(), ".sig") {
+				archives++
+			}
+		}
+
+		snapshots = append(snapshots, proto.Snapshot{
+			Name:     info.Name(),
+			Date:     date,
+			Archives: archives,
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Date.After(snapshots[j].Date)
+	})
+
+	return snapshots, nil
+}
+
+func (service *SnapshotService) ListSnapshots(
+	source *http.Request,
+	request *proto.RequestListSnapshots,
+	response *proto.ResponseListSnapshots,
+) error {
+	signer := service.auth.Verify(request.Signature)
+	if signer == nil {
+		return ErrorUnauthorized
+	}
+
+	snapshots, err := FindSnapshots(service.dir)
+	if err != nil {
+		return err
+	}
+
+	response.Snapshots = snapshots
+
+	return nil
+}
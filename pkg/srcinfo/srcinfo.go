@@ -0,0 +1,202 @@
+// Package srcinfo implements parser of .SRCINFO files generated by
+// makepkg --printsrcinfo.
+package srcinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Package describes a single pkgname section of .SRCINFO, values which are
+// not overridden in the section are inherited from pkgbase.
+type Package struct {
+	Name      string
+	Arch      []string
+	Depends   []string
+	Provides  []string
+	Conflicts []string
+	Replaces  []string
+}
+
+type SrcInfo struct {
+	Pkgbase      string
+	Pkgver       string
+	Pkgrel       string
+	Epoch        string
+	Arch         []string
+	Depends      []string
+	MakeDepends  []string
+	CheckDepends []string
+	Provides     []string
+	Conflicts    []string
+	Replaces     []string
+	Source       []string
+	ValidPGPKeys []string
+	Install      string
+	Packages     []*Package
+}
+
+// Version returns full version of the package in [epoch:]pkgver-pkgrel form.
+func (info *SrcInfo) Version() string {
+	if info.Pkgver == "" {
+		return ""
+	}
+
+	version := info.Pkgver + "-" + info.Pkgrel
+	if info.Epoch != "" && info.Epoch != "0" {
+		version = info.Epoch + ":" + version
+	}
+
+	return version
+}
+
+// Names returns names of all packages produced by the package base.
+func (info *SrcInfo) Names() []string {
+	names := []string{}
+	for _, pkg := range info.Packages {
+		names = append(names, pkg.Name)
+	}
+
+	return names
+}
+
+// Parse reads .SRCINFO from given reader.
+func Parse(reader io.Reader) (*SrcInfo, error) {
+	info := &SrcInfo{}
+
+	var (
+		pkg *Package
+		// overridden keeps list of keys which were specified in current
+		// pkgname section, so inherited values are replaced instead of
+		// being appended to
+		overridden map[string]bool
+	)
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		chunks := strings.SplitN(text, "=", 2)
+		if len(chunks) != 2 {
+			return nil, fmt.Errorf(
+				"line %d: expected key = value, got: %q", line, text,
+			)
+		}
+
+		key := strings.TrimSpace(chunks[0])
+		value := strings.TrimSpace(chunks[1])
+
+		switch key {
+		case "pkgbase":
+			info.Pkgbase = value
+			continue
+
+		case "pkgname":
+			pkg = &Package{
+				Name:      value,
+				Arch:      info.Arch,
+				Depends:   info.Depends,
+				Provides:  info.Provides,
+				Conflicts: info.Conflicts,
+				Replaces:  info.Replaces,
+			}
+			overridden = map[string]bool{}
+
+			info.Packages = append(info.Packages, pkg)
+			continue
+		}
+
+		// architecture specific values like depends_x86_64 are treated the
+		// same way as generic ones
+		key = trimArch(key)
+
+		if pkg != nil {
+			field := pkg.field(key)
+			if field != nil {
+				if !overridden[key] {
+					*field = nil
+					overridden[key] = true
+				}
+
+				*field = append(*field, value)
+			}
+
+			continue
+		}
+
+		switch key {
+		case "pkgver":
+			info.Pkgver = value
+		case "pkgrel":
+			info.Pkgrel = value
+		case "epoch":
+			info.Epoch = value
+		case "install":
+			info.Install = value
+		case "arch":
+			info.Arch = append(info.Arch, value)
+		case "depends":
+			info.Depends = append(info.Depends, value)
+		case "makedepends":
+			info.MakeDepends = append(info.MakeDepends, value)
+		case "checkdepends":
+			info.CheckDepends = append(info.CheckDepends, value)
+		case "provides":
+			info.Provides = append(info.Provides, value)
+		case "conflicts":
+			info.Conflicts = append(info.Conflicts, value)
+		case "replaces":
+			info.Replaces = append(info.Replaces, value)
+		case "source":
+			info.Source = append(info.Source, value)
+		case "validpgpkeys":
+			info.ValidPGPKeys = append(info.ValidPGPKeys, value)
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+func (pkg *Package) field(key string) *[]string {
+	switch key {
+	case "arch":
+		return &pkg.Arch
+	case "depends":
+		return &pkg.Depends
+	case "provides":
+		return &pkg.Provides
+	case "conflicts":
+		return &pkg.Conflicts
+	case "replaces":
+		return &pkg.Replaces
+	}
+
+	return nil
+}
+
+var knownArches = []string{
+	"x86_64", "i686", "aarch64", "armv7h", "armv6h", "arm", "pentium4",
+	"riscv64",
+}
+
+func trimArch(key string) string {
+	for _, arch := range knownArches {
+		if strings.HasSuffix(key, "_"+arch) {
+			return strings.TrimSuffix(key, "_"+arch)
+		}
+	}
+
+	return key
+}
@@ -0,0 +1,79 @@
+package srcinfo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSrcInfo = `pkgbase = foo
+	pkgdesc = Foo = bar
+	pkgver = 1.2.3
+	pkgrel = 2
+	epoch = 1
+	url = https://example.com
+	install = foo.install
+	arch = x86_64
+	arch = aarch64
+	license = MIT
+	makedepends = go
+	depends = glibc
+	depends_x86_64 = lib32-glibc
+	provides = foo-bin
+	source = https://example.com/foo.tar.gz
+	source = https://example.com/foo.tar.gz.sig
+	validpgpkeys = 0123456789ABCDEF0123456789ABCDEF01234567
+
+pkgname = foo
+
+pkgname = foo-docs
+	arch = any
+	depends = foo
+`
+
+func TestParse(t *testing.T) {
+	test := assert.New(t)
+
+	info, err := Parse(strings.NewReader(testSrcInfo))
+	test.NoError(err)
+
+	test.Equal("foo", info.Pkgbase)
+	test.Equal("1:1.2.3-2", info.Version())
+	test.Equal("foo.install", info.Install)
+	test.Equal([]string{"x86_64", "aarch64"}, info.Arch)
+	test.Equal([]string{"go"}, info.MakeDepends)
+	test.Equal([]string{"glibc", "lib32-glibc"}, info.Depends)
+	test.Equal([]string{"foo-bin"}, info.Provides)
+	test.Len(info.Source, 2)
+	test.Equal(
+		[]string{"0123456789ABCDEF0123456789ABCDEF01234567"},
+		info.ValidPGPKeys,
+	)
+
+	test.Equal([]string{"foo", "foo-docs"}, info.Names())
+
+	test.Equal([]string{"x86_64", "aarch64"}, info.Packages[0].Arch)
+	test.Equal([]string{"glibc", "lib32-glibc"}, info.Packages[0].Depends)
+	test.Equal([]string{"foo-bin"}, info.Packages[0].Provides)
+
+	test.Equal([]string{"any"}, info.Packages[1].Arch)
+	test.Equal([]string{"foo"}, info.Packages[1].Depends)
+	test.Equal([]string{"foo-bin"}, info.Packages[1].Provides)
+}
+
+func TestParse_Empty(t *testing.T) {
+	test := assert.New(t)
+
+	info, err := Parse(strings.NewReader(""))
+	test.NoError(err)
+	test.Equal("", info.Version())
+	test.Empty(info.Names())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	test := assert.New(t)
+
+	_, err := Parse(strings.NewReader("pkgbase foo"))
+	test.Error(err)
+}
@@ -0,0 +1,168 @@
+// Package udiff produces line-based diffs in unified format.
+package udiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is a number of unchanged lines shown around changes.
+const DefaultContext = 3
+
+type operation struct {
+	kind byte
+	line string
+}
+
+// Unified returns unified diff between two texts, the diff is empty when
+// texts are equal.
+func Unified(nameA, nameB, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+
+	operations := compare(splitLines(a), splitLines(b))
+
+	// positions of operations in both texts, posA[i] is number of lines of
+	// first text consumed before i-th operation
+	posA := make([]int, len(operations)+1)
+	posB := make([]int, len(operations)+1)
+	changes := []int{}
+
+	for i, operation := range operations {
+		posA[i+1] = posA[i]
+		posB[i+1] = posB[i]
+
+		if operation.kind != '+' {
+			posA[i+1]++
+		}
+
+		if operation.kind != '-' {
+			posB[i+1]++
+		}
+
+		if operation.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	buffer := &strings.Builder{}
+	fmt.Fprintf(buffer, "--- %s\n+++ %s\n", nameA, nameB)
+
+	for i := 0; i < len(changes); {
+		first := changes[i]
+		last := first
+
+		for i++; i < len(changes); i++ {
+			if changes[i]-last > context*2 {
+				break
+			}
+
+			last = changes[i]
+		}
+
+		start := max(first-context, 0)
+		end := min(last+context+1, len(operations))
+
+		fmt.Fprintf(
+			buffer,
+			"@@ -%s +%s @@\n",
+			hunkRange(posA[start], posA[end]-posA[start]),
+			hunkRange(posB[start], posB[end]-posB[start]),
+		)
+
+		for _, operation := range operations[start:end] {
+			buffer.WriteByte(operation.kind)
+			buffer.WriteString(operation.line)
+
+			if !strings.HasSuffix(operation.line, "\n") {
+				buffer.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+
+	return buffer.String()
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// compare builds edit script using longest common subsequence of lines.
+func compare(a, b []string) []operation {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	operations := []operation{}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			operations = append(operations, operation{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			operations = append(operations, operation{'-', a[i]})
+			i++
+		default:
+			operations = append(operations, operation{'+', b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		operations = append(operations, operation{'-', a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		operations = append(operations, operation{'+', b[j]})
+	}
+
+	return operations
+}
+
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,99 @@
+package udiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnified(t *testing.T) {
+	test := assert.New(t)
+
+	testcases := []struct {
+		Name string
+		A    string
+		B    string
+		Diff string
+	}{
+		{
+			Name: "equal",
+			A:    "a\nb\n",
+			B:    "a\nb\n",
+			Diff: "",
+		},
+		{
+			Name: "changed line",
+			A:    "pkgname=foo\npkgver=1\npkgrel=1\n",
+			B:    "pkgname=foo\npkgver=2\npkgrel=1\n",
+			Diff: `--- a
++++ b
+@@ -1,3 +1,3 @@
+ pkgname=foo
+-pkgver=1
++pkgver=2
+ pkgrel=1
+`,
+		},
+		{
+			Name: "new file",
+			A:    "",
+			B:    "a\nb\n",
+			Diff: `--- a
++++ b
+@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+		{
+			Name: "removed file",
+			A:    "a\n",
+			B:    "",
+			Diff: `--- a
++++ b
+@@ -1 +0,0 @@
+-a
+`,
+		},
+		{
+			Name: "no newline",
+			A:    "a\nb",
+			B:    "a\nc",
+			Diff: `--- a
++++ b
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`,
+		},
+		{
+			Name: "separate hunks",
+			A:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			B:    "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			Diff: `--- a
++++ b
+@@ -1,3 +1,4 @@
++0
+ 1
+ 2
+ 3
+@@ -9,4 +10,3 @@
+ 9
+ 10
+ 11
+-12
+`,
+		},
+	}
+
+	for _, testcase := range testcases {
+		test.Equal(
+			testcase.Diff,
+			Unified("a", "b", testcase.A, testcase.B, 3),
+			testcase.Name,
+		)
+	}
+}
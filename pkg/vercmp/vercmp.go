@@ -0,0 +1,179 @@
+// Package vercmp compares versions of packages the same way pacman and
+// vercmp(8) do, so 1.10 is newer than 1.9, 1:1.0 is newer than 2.0 and
+// 1.0rc is older than 1.0.
+package vercmp
+
+import (
+	"sort"
+	"strings"
+)
+
+// Compare returns -1 if version a is older than b, 0 if they are equal and 1
+// if a is newer than b. Versions are [epoch:]pkgver[-pkgrel], pkgrel is
+// compared only if both versions have it.
+func Compare(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	epochA, versionA, releaseA := parse(a)
+	epochB, versionB, releaseB := parse(b)
+
+	result := compareSegments(epochA, epochB)
+	if result != 0 {
+		return result
+	}
+
+	result = compareSegments(versionA, versionB)
+	if result != 0 {
+		return result
+	}
+
+	if releaseA == "" || releaseB == "" {
+		return 0
+	}
+
+	return compareSegments(releaseA, releaseB)
+}
+
+// Sort sorts versions from the newest to the oldest.
+func Sort(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return Compare(versions[i], versions[j]) > 0
+	})
+}
+
+// parse splits version into epoch, pkgver and pkgrel, epoch defaults to 0.
+func parse(version string) (string, string, string) {
+	epoch := "0"
+
+	digits := 0
+	for digits < len(version) && isDigit(version[digits]) {
+		digits++
+	}
+
+	if digits < len(version) && version[digits] == ':' {
+		if digits > 0 {
+			epoch = version[:digits]
+		}
+
+		version = version[digits+1:]
+	}
+
+	release := ""
+	if index := strings.LastIndexByte(version, '-'); index != -1 {
+		release = version[index+1:]
+		version = version[:index]
+	}
+
+	return epoch, version, release
+}
+
+// compareSegments is rpmvercmp: versions are split into alternating numeric
+// and alphabetic segments by non-alphanumeric separators, numeric segments
+// are newer than alphabetic ones.
+func compareSegments(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	one, two := 0, 0
+	for one < len(a) && two < len(b) {
+		startA, startB := one, two
+
+		for one < len(a) && !isAlnum(a[one]) {
+			one++
+		}
+
+		for two < len(b) && !isAlnum(b[two]) {
+			two++
+		}
+
+		if one == len(a) || two == len(b) {
+			break
+		}
+
+		// the version with longer separator is newer, so 2___a is newer
+		// than 2_a
+		if one-startA != two-startB {
+			if one-startA < two-startB {
+				return -1
+			}
+
+			return 1
+		}
+
+		startA, startB = one, two
+
+		numeric := isDigit(a[one])
+		if numeric {
+			for one < len(a) && isDigit(a[one]) {
+				one++
+			}
+
+			for two < len(b) && isDigit(b[two]) {
+				two++
+			}
+		} else {
+			for one < len(a) && isAlpha(a[one]) {
+				one++
+			}
+
+			for two < len(b) && isAlpha(b[two]) {
+				two++
+			}
+		}
+
+		// segments of different types, numeric one is newer
+		if startB == two {
+			if numeric {
+				return 1
+			}
+
+			return -1
+		}
+
+		segmentA, segmentB := a[startA:one], b[startB:two]
+
+		if numeric {
+			segmentA = strings.TrimLeft(segmentA, "0")
+			segmentB = strings.TrimLeft(segmentB, "0")
+
+			if len(segmentA) != len(segmentB) {
+				if len(segmentA) < len(segmentB) {
+					return -1
+				}
+
+				return 1
+			}
+		}
+
+		if result := strings.Compare(segmentA, segmentB); result != 0 {
+			return result
+		}
+	}
+
+	if one == len(a) && two == len(b) {
+		return 0
+	}
+
+	// the version with remaining segment is newer unless the segment is
+	// alphabetic, so 1.0 is newer than 1.0rc and 1.0.1 is newer than 1.0
+	if (one == len(a) && !isAlpha(b[two])) || (one < len(a) && isAlpha(a[one])) {
+		return -1
+	}
+
+	return 1
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+func isAlpha(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+func isAlnum(char byte) bool {
+	return isDigit(char) || isAlpha(char)
+}
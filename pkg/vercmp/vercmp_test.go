@@ -0,0 +1,126 @@
+package vercmp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	test := assert.New(t)
+
+	// cases are taken from pacman's vercmp test suite
+	testcases := []struct {
+		a, b     string
+		expected int
+	}{
+		// all similar length, no pkgrel
+		{"1.5.0", "1.5.0", 0},
+		{"1.5.1", "1.5.0", 1},
+
+		// mixed length
+		{"1.5.1", "1.5", 1},
+		{"1.10", "1.9", 1},
+		{"1.010", "1.9", 1},
+		{"1.0", "1.00", 0},
+
+		// with pkgrel, simple
+		{"1.5.0-1", "1.5.0-1", 0},
+		{"1.5.0-1", "1.5.0-2", -1},
+		{"1.5.0-1", "1.5.1-1", -1},
+		{"1.5.0-2", "1.5.1-1", -1},
+		{"1.5.0-9", "1.5.0-10", -1},
+
+		// with pkgrel, mixed lengths
+		{"1.5-1", "1.5.1-1", -1},
+		{"1.5-2", "1.5.1-1", -1},
+		{"1.5-2", "1.5.1-2", -1},
+
+		// mixed pkgrel inclusion
+		{"1.5", "1.5-1", 0},
+		{"1.5-1", "1.5", 0},
+		{"1.1-1", "1.1", 0},
+		{"1.0-1", "1.1", -1},
+		{"1.1-1", "1.0", 1},
+
+		// alphanumeric versions
+		{"1.5b-1", "1.5-1", -1},
+		{"1.5b", "1.5", -1},
+		{"1.5b-1", "1.5", -1},
+		{"1.5b", "1.5.1", -1},
+
+		// from the manpage
+		{"1.0a", "1.0alpha", -1},
+		{"1.0alpha", "1.0b", -1},
+		{"1.0b", "1.0beta", -1},
+		{"1.0beta", "1.0rc", -1},
+		{"1.0rc", "1.0", -1},
+
+		// alpha-dotted versions
+		{"1.5.a", "1.5", 1},
+		{"1.5.b", "1.5.a", 1},
+		{"1.5.1", "1.5.b", 1},
+
+		// alpha dots and dashes
+		{"1.5.b-1", "1.5.b", 0},
+		{"1.5-1", "1.5.b", -1},
+
+		// same or similar content, differing separators
+		{"2.0", "2_0", 0},
+		{"2.0_a", "2_0.a", 0},
+		{"2.0a", "2.0.a", -1},
+		{"2___a", "2_a", 1},
+
+		// epoch included version comparisons
+		{"0:1.0", "0:1.0", 0},
+		{"0:1.0", "0:1.1", -1},
+		{"1:1.0", "0:1.0", 1},
+		{"1:1.0", "0:1.1", 1},
+		{"1:1.0", "2:1.1", -1},
+		{"1:2.0", "10.0", 1},
+
+		// epoch and sometimes present pkgrel
+		{"1:1.0", "0:1.0-1", 1},
+		{"1:1.0-1", "0:1.1-1", 1},
+
+		// epoch included on one version
+		{"0:1.0", "1.0", 0},
+		{"0:1.0", "1.1", -1},
+		{"0:1.1", "1.0", 1},
+		{"1:1.0", "1.0", 1},
+		{"1:1.0", "1.1", 1},
+		{"1:1.1", "1.1", 1},
+		{":1.0", "1.0", 0},
+
+		// git versions
+		{"r123.abcdef-1", "r99.fedcba-1", 1},
+		{"1.0.r5.g1234567", "1.0.r12.g7654321", -1},
+	}
+
+	for _, testcase := range testcases {
+		test.Equal(
+			testcase.expected,
+			Compare(testcase.a, testcase.b),
+			"%s <=> %s", testcase.a, testcase.b,
+		)
+
+		test.Equal(
+			-testcase.expected,
+			Compare(testcase.b, testcase.a),
+			"%s <=> %s", testcase.b, testcase.a,
+		)
+	}
+}
+
+func TestSort(t *testing.T) {
+	test := assert.New(t)
+
+	versions := []string{"1.9-1", "1:0.1-1", "1.10-1", "1.10-2", "1.10rc1-1"}
+
+	Sort(versions)
+
+	test.Equal(
+		[]string{"1:0.1-1", "1.10-2", "1.10-1", "1.10rc1-1", "1.9-1"},
+		versions,
+	)
+}